@@ -0,0 +1,105 @@
+package behaviour
+
+import (
+	"log"
+	"rtkcsm/component/structure"
+)
+
+const ALERT_NOTIFICATION_BUFFER_SIZE = 1024
+
+// SetAlertRules replaces the rules and checks all graphs against them, graphs that matched a rule of
+// the same name before are not notified again
+func (c *RTKCSMImplementation[T, K]) SetAlertRules(rules []structure.AlertRule) {
+	c.graphsMutex.Lock()
+	defer c.graphsMutex.Unlock()
+
+	c.alertRules = rules
+	c.alertRuleStates = map[structure.GraphID]*structure.AlertRuleState[T]{}
+
+	for id, graph := range c.graphs {
+		c.evaluateAlertRules(id, graph, structure.DirectedRelation[T]{}, false)
+	}
+}
+
+// SetNotifier starts to deliver notifications of matched rules in the background, so that slow
+// notifiers do not block the correlation
+func (c *RTKCSMImplementation[T, K]) SetNotifier(notifier structure.Notifier) {
+	c.graphsMutex.Lock()
+	defer c.graphsMutex.Unlock()
+
+	if c.notifications != nil {
+		close(c.notifications)
+	}
+
+	notifications := make(chan structure.AlertNotification, ALERT_NOTIFICATION_BUFFER_SIZE)
+	go func() {
+		for notification := range notifications {
+			if err := notifier.Notify(notification); err != nil {
+				log.Printf("error notifying alert rule %s of graph %d: %s", notification.Rule, notification.GraphID, err)
+			}
+		}
+	}()
+
+	c.notifications = notifications
+	// states are not updated without a notifier
+	c.alertRuleStates = map[structure.GraphID]*structure.AlertRuleState[T]{}
+}
+
+// evaluateAlertRules expects the graphs mutex to be locked. The state of a graph is updated with the
+// relation if it is new, graphs without a state (e.g. merged graphs) or with a late relation evaluate
+// all relations once.
+func (c *RTKCSMImplementation[T, K]) evaluateAlertRules(id structure.GraphID, graph *structure.Graph[T, K], relation structure.DirectedRelation[T], isNewRelation bool) {
+	if len(c.alertRules) == 0 || c.notifications == nil {
+		return
+	}
+
+	state, ok := c.alertRuleStates[id]
+	if !ok {
+		state = structure.NewAlertRuleState(graph, c.alertRules)
+		c.alertRuleStates[id] = state
+	} else if isNewRelation && !state.Add(relation, c.alertRules) {
+		state = structure.NewAlertRuleState(graph, c.alertRules)
+		c.alertRuleStates[id] = state
+	}
+
+	for _, rule := range c.alertRules {
+		if c.firedAlertRules[id][rule.Name] || !graph.MatchesRule(rule, state) {
+			continue
+		}
+
+		if c.firedAlertRules[id] == nil {
+			c.firedAlertRules[id] = map[string]bool{}
+		}
+		c.firedAlertRules[id][rule.Name] = true
+
+		notification := structure.AlertNotification{
+			Rule:        rule.Name,
+			Description: rule.Description,
+			GraphID:     id,
+			Relevance:   graph.Relevance(),
+			Relations:   graph.Len(),
+			Timestamp:   graph.LastSeen(),
+		}
+
+		select {
+		case c.notifications <- notification:
+		default:
+			log.Printf("dropping notification of alert rule %s for graph %d", rule.Name, id)
+		}
+	}
+}
+
+// mergeFiredAlertRules expects the graphs mutex to be locked. The merged graph continues the attack,
+// so rules that matched one of the graphs are not notified again. The state of the merged graph is
+// evaluated again, as the stages of both graphs are interleaved.
+func (c *RTKCSMImplementation[T, K]) mergeFiredAlertRules(from structure.GraphID, into structure.GraphID) {
+	for name := range c.firedAlertRules[from] {
+		if c.firedAlertRules[into] == nil {
+			c.firedAlertRules[into] = map[string]bool{}
+		}
+		c.firedAlertRules[into][name] = true
+	}
+	delete(c.firedAlertRules, from)
+	delete(c.alertRuleStates, from)
+	delete(c.alertRuleStates, into)
+}
@@ -0,0 +1,179 @@
+package behaviour
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"rtkcsm/component/structure"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	mutex         sync.Mutex
+	notifications []structure.AlertNotification
+}
+
+func (n *recordingNotifier) Notify(notification structure.AlertNotification) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+// relations returns the relation counts of the graphs when the rules matched
+func (n *recordingNotifier) relations() map[string]int {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	relations := map[string]int{}
+	for _, notification := range n.notifications {
+		relations[notification.Rule] = notification.Relations
+	}
+	return relations
+}
+
+func (n *recordingNotifier) count() int {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	return len(n.notifications)
+}
+
+func waitFor(t *testing.T, description string, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAlertRules(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "rules.json")
+	writeRules := func(content string, modified time.Time) {
+		if err := os.WriteFile(rulesFile, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		// the modification time has to change even if the file is written twice within its resolution
+		if err := os.Chtimes(rulesFile, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeRules(`{"rules": [
+		{"name": "incoming-then-outgoing", "sequence": ["incoming", "outgoing"]},
+		{"name": "outgoing-then-incoming", "sequence": ["outgoing", "incoming"]},
+		{"name": "delivery-then-pivot", "sequence": ["D1", "Pivot"]},
+		{"name": "relevant", "min_relevance": 0.8},
+		{"name": "high-risk-host", "min_host_risk": 1.5},
+		{"name": "two-zones", "min_lateral_zones": 2},
+		{"name": "three-zones", "min_lateral_zones": 3}
+	]}`, time.Now().Add(-time.Minute))
+
+	watcher := structure.NewAlertRuleWatcher(rulesFile)
+	watcher.PollInterval = 10 * time.Millisecond
+	rules, changed, err := watcher.Load()
+	if err != nil || !changed || len(rules) != 7 {
+		t.Fatalf("could not load rules: %v (changed=%t, %d rules)", err, changed, len(rules))
+	}
+
+	rtkcsm := newTestRTKCSM()
+	notifier := &recordingNotifier{}
+	rtkcsm.SetNotifier(notifier)
+	rtkcsm.SetAlertRules(rules)
+
+	highRiskHost := structure.ParseIPAddress("172.16.42.1")
+	rtkcsm.AddHostRisk(highRiskHost, structure.HighRisk)
+	defer rtkcsm.DeleteHostRisk(highRiskHost)
+
+	// an attack from the internet that moves laterally into another subnet
+	seconds := time.Now().Unix()
+	alerts := structure.Alerts{
+		newTestAlert(time.Unix(seconds-5, 0), "94.141.120.36", "172.16.42.42", 1),
+		newTestAlert(time.Unix(seconds-4, 0), "172.16.42.42", "218.92.0.27", 1),
+		newTestAlert(time.Unix(seconds-3, 0), "172.16.42.42", "172.16.42.1", 1),
+		newTestAlert(time.Unix(seconds-2, 0), "172.16.42.1", "10.12.2.93", 1),
+	}
+	sort.Sort(alerts)
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+
+	expected := map[string]int{
+		"incoming-then-outgoing": 2,
+		"delivery-then-pivot":    4,
+		"relevant":               3,
+		"high-risk-host":         3,
+		"two-zones":              4,
+	}
+	waitFor(t, "notifications", func() bool { return notifier.count() >= len(expected) })
+
+	// rules are notified once per graph
+	rtkcsm.AddAlert(alerts[len(alerts)-1])
+	time.Sleep(20 * time.Millisecond)
+
+	if relations := notifier.relations(); !reflect.DeepEqual(relations, expected) || notifier.count() != len(expected) {
+		t.Errorf("expected rules matching at %v instead of %v (%d notifications)", expected, relations, notifier.count())
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go watcher.Watch(rtkcsm.SetAlertRules, stop)
+
+	// invalid files do not replace the rules
+	writeRules(`{"rules": [{"name": "broken"`, time.Now().Add(-30*time.Second))
+	time.Sleep(50 * time.Millisecond)
+
+	writeRules(`{"rules": [{"name": "four-relations", "min_relations": 4}, {"name": "five-relations", "min_relations": 5}, {"name": "two-zones", "min_lateral_zones": 2}]}`, time.Now())
+
+	// existing graphs are checked against the reloaded rules
+	waitFor(t, "the reloaded rule of an existing graph", func() bool { return notifier.count() > len(expected) })
+	expected["four-relations"] = 4
+
+	rtkcsm.AddAlert(newTestAlert(alerts[len(alerts)-1].Timestamp.Add(time.Second), "10.12.2.93", "10.12.2.94", 1))
+	waitFor(t, "the reloaded rule", func() bool { return notifier.count() > len(expected) })
+
+	expected["five-relations"] = 5
+	time.Sleep(20 * time.Millisecond)
+	if relations := notifier.relations(); !reflect.DeepEqual(relations, expected) || notifier.count() != len(expected) {
+		t.Errorf("expected rules matching at %v after the reload instead of %v (%d notifications)", expected, relations, notifier.count())
+	}
+}
+
+func TestAlertRuleSequenceOrder(t *testing.T) {
+	rules, err := structure.ParseAlertRules(strings.NewReader(`{"rules": [
+		{"name": "incoming-then-outgoing", "sequence": ["incoming", "outgoing"]},
+		{"name": "outgoing-then-incoming", "sequence": ["outgoing", "incoming"]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rtkcsm := newTestRTKCSM()
+	rtkcsm.SetRelinkLateAlerts(true)
+	notifier := &recordingNotifier{}
+	rtkcsm.SetNotifier(notifier)
+	rtkcsm.SetAlertRules(rules)
+
+	// the incoming alert happened first but arrives late
+	seconds := time.Now().Unix()
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-4, 0), "172.16.42.42", "218.92.0.27", 1))
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-5, 0), "94.141.120.36", "172.16.42.42", 1))
+
+	if count := rtkcsm.GetGraphList(-1).Count; count != 1 {
+		t.Fatalf("late alert created %d graphs instead of 1", count)
+	}
+
+	// sequences follow the timestamps like for graphs whose relations are evaluated again
+	waitFor(t, "notifications", func() bool { return notifier.count() >= 1 })
+	time.Sleep(20 * time.Millisecond)
+	if relations := notifier.relations(); !reflect.DeepEqual(relations, map[string]int{"incoming-then-outgoing": 2}) {
+		t.Errorf("unexpected rules matching at %v", relations)
+	}
+}
@@ -0,0 +1,16 @@
+package behaviour
+
+import "rtkcsm/component/structure"
+
+// GetAttackPaths returns the most relevant attack paths of a graph or nil if the graph does not exist
+func (c *RTKCSMImplementation[T, K]) GetAttackPaths(id structure.GraphID, limit int) []structure.AttackPath[T] {
+	c.graphsMutex.RLock()
+	defer c.graphsMutex.RUnlock()
+
+	graph, ok := c.graphs[id]
+	if !ok {
+		return nil
+	}
+
+	return graph.GetAttackPaths(c.stateMachine, limit)
+}
@@ -0,0 +1,23 @@
+package behaviour
+
+import "rtkcsm/component/structure"
+
+// SubscribeGraphEvents returns a subscription for changes of the graphs, which has to be closed
+func (c *RTKCSMImplementation[T, K]) SubscribeGraphEvents() *structure.GraphEventSubscription {
+	return c.events.Subscribe()
+}
+
+// publishGraphEvent expects the graphs mutex to be locked
+func (c *RTKCSMImplementation[T, K]) publishGraphEvent(eventType structure.GraphEventType, id structure.GraphID, graph *structure.Graph[T, K], mergedInto structure.GraphID) {
+	if !c.events.HasSubscribers() {
+		return
+	}
+
+	c.events.Publish(structure.GraphEvent{
+		Type:       eventType,
+		ID:         id,
+		MergedInto: mergedInto,
+		Relevance:  graph.Relevance(),
+		Relations:  graph.Len(),
+	})
+}
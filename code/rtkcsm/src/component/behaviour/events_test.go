@@ -0,0 +1,51 @@
+package behaviour
+
+import (
+	"reflect"
+	"rtkcsm/component/structure"
+	"testing"
+	"time"
+)
+
+func TestGraphEvents(t *testing.T) {
+	rtkcsm := newTestRTKCSM()
+	subscription := rtkcsm.SubscribeGraphEvents()
+
+	seconds := time.Now().Unix()
+	alerts := structure.Alerts{
+		newTestAlert(time.Unix(seconds-3, 0), "94.141.120.36", "172.16.42.42", 0.5),
+		newTestAlert(time.Unix(seconds-2, 0), "94.141.120.37", "172.16.42.42", 0.5),
+		// links both graphs
+		newTestAlert(time.Unix(seconds-1, 0), "172.16.42.42", "172.16.42.1", 1),
+	}
+
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+	subscription.Close()
+
+	events := []structure.GraphEventType{}
+	var mergedEvent structure.GraphEvent
+	for event := range subscription.Events {
+		events = append(events, event.Type)
+		if event.Type == structure.GraphEventMerged {
+			mergedEvent = event
+		}
+	}
+
+	expected := []structure.GraphEventType{
+		structure.GraphEventCreated,
+		structure.GraphEventCreated,
+		structure.GraphEventMerged,
+		structure.GraphEventGrown,
+		structure.GraphEventRanking,
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("got events %v, expected %v", events, expected)
+	}
+
+	graphList := rtkcsm.GetGraphList(-1)
+	if graphList.Count != 1 || mergedEvent.MergedInto != graphList.Graphs[0].ID || mergedEvent.ID == mergedEvent.MergedInto {
+		t.Errorf("merge event %+v does not refer to the remaining graph %v", mergedEvent, graphList.Graphs)
+	}
+}
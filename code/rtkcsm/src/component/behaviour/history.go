@@ -0,0 +1,117 @@
+package behaviour
+
+import (
+	"encoding/base64"
+	"log"
+	"rtkcsm/component/structure"
+	"sort"
+)
+
+// recordMerge expects the graphs mutex to be locked. The merges of the old graph are kept by the new graph.
+func (c *RTKCSMImplementation[T, K]) recordMerge(oldGraphID structure.GraphID, newGraphID structure.GraphID, oldGraph *structure.Graph[T, K], alert structure.EnrichedAlert[T]) {
+	relationId := structure.NewOptimizedDirectedRelationID(alert.SourceIP, alert.DestinationIP, alert.Severity, alert.Confidence, alert.SignatureId)
+
+	merge := structure.GraphMerge{
+		From:      oldGraphID,
+		Into:      newGraphID,
+		Timestamp: alert.Timestamp,
+		Relation:  base64.StdEncoding.EncodeToString(relationId[:]),
+		Relations: oldGraph.Len(),
+	}
+
+	c.restoreMerge(merge)
+}
+
+// restoreMerge expects the graphs mutex to be locked and accepts merges in any order
+func (c *RTKCSMImplementation[T, K]) restoreMerge(merge structure.GraphMerge) {
+	into := c.resolveGraphID(merge.Into)
+	if into == merge.From {
+		log.Printf("ignoring merge of graph %d into itself", merge.From)
+		return
+	}
+
+	c.mergedGraphs[merge.From] = merge
+	c.merges[into] = append(append(c.merges[into], c.merges[merge.From]...), merge)
+	delete(c.merges, merge.From)
+}
+
+// forgetMerges expects the graphs mutex to be locked and is called when a graph is removed
+func (c *RTKCSMImplementation[T, K]) forgetMerges(id structure.GraphID) {
+	for _, merge := range c.merges[id] {
+		delete(c.mergedGraphs, merge.From)
+	}
+	delete(c.merges, id)
+}
+
+// getMerges expects the graphs mutex to be locked
+func (c *RTKCSMImplementation[T, K]) getMerges() []structure.GraphMerge {
+	merges := []structure.GraphMerge{}
+	for _, graphMerges := range c.merges {
+		merges = append(merges, graphMerges...)
+	}
+
+	sort.Slice(merges, func(i, j int) bool {
+		return merges[i].Timestamp.Before(merges[j].Timestamp)
+	})
+
+	return merges
+}
+
+// ResolveGraphID returns the id of the graph that a graph was (transitively) merged into or the id itself
+func (c *RTKCSMImplementation[T, K]) ResolveGraphID(id structure.GraphID) structure.GraphID {
+	c.graphsMutex.RLock()
+	defer c.graphsMutex.RUnlock()
+	return c.resolveGraphID(id)
+}
+
+// resolveGraphID expects the graphs mutex to be locked
+func (c *RTKCSMImplementation[T, K]) resolveGraphID(id structure.GraphID) structure.GraphID {
+	for {
+		merge, ok := c.mergedGraphs[id]
+		if !ok {
+			return id
+		}
+		id = merge.Into
+	}
+}
+
+// GetGraphHistory lists the relations and merges of a graph in the order of their timestamps
+func (c *RTKCSMImplementation[T, K]) GetGraphHistory(id structure.GraphID) *structure.GraphHistory {
+	c.graphsMutex.RLock()
+	defer c.graphsMutex.RUnlock()
+
+	graph, ok := c.graphs[id]
+	if !ok {
+		return nil
+	}
+
+	history := structure.GraphHistory{
+		ID:      id,
+		Entries: []structure.GraphHistoryEntry{},
+	}
+
+	for _, relation := range graph.GetPreComputed().PreComputedDirectedRelations {
+		history.Entries = append(history.Entries, structure.GraphHistoryEntry{
+			Type:      structure.GraphHistoryRelation,
+			Timestamp: relation.Timestamp,
+			Relation:  relation.ID,
+		})
+	}
+
+	for _, merge := range c.merges[id] {
+		history.Entries = append(history.Entries, structure.GraphHistoryEntry{
+			Type:        structure.GraphHistoryMerge,
+			Timestamp:   merge.Timestamp.UnixMilli(),
+			Relation:    merge.Relation,
+			MergedGraph: merge.From,
+			Relations:   merge.Relations,
+		})
+	}
+
+	// relations that cause a merge are listed before the merge
+	sort.SliceStable(history.Entries, func(i, j int) bool {
+		return history.Entries[i].Timestamp < history.Entries[j].Timestamp
+	})
+
+	return &history
+}
@@ -0,0 +1,86 @@
+package behaviour
+
+import (
+	"bytes"
+	"reflect"
+	"rtkcsm/component/structure"
+	"testing"
+	"time"
+)
+
+func TestGraphMergeHistory(t *testing.T) {
+	rtkcsm := newTestRTKCSM()
+
+	seconds := time.Now().Unix()
+	alerts := structure.Alerts{
+		newTestAlert(time.Unix(seconds-3, 0), "94.141.120.36", "172.16.42.42", 1),
+		newTestAlert(time.Unix(seconds-2, 0), "94.141.120.37", "172.16.42.42", 1),
+	}
+
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+
+	graphs := rtkcsm.GetGraphList(-1).Graphs
+	survivingId := min(graphs[0].ID, graphs[1].ID)
+	mergedId := max(graphs[0].ID, graphs[1].ID)
+
+	// links both graphs
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-1, 0), "172.16.42.42", "172.16.42.1", 1))
+
+	if id := rtkcsm.ResolveGraphID(mergedId); id != survivingId {
+		t.Fatalf("merged graph %d resolves to %d instead of %d", mergedId, id, survivingId)
+	}
+
+	if id := rtkcsm.ResolveGraphID(survivingId); id != survivingId {
+		t.Errorf("surviving graph %d resolves to %d", survivingId, id)
+	}
+
+	if history := rtkcsm.GetGraphHistory(mergedId); history != nil {
+		t.Errorf("merged graph %d has a history", mergedId)
+	}
+
+	expectHistory := func(rtkcsm *RTKCSMImplementation[structure.SimplifiedUKCStage, structure.UKCStage]) {
+		history := rtkcsm.GetGraphHistory(survivingId)
+		if history == nil {
+			t.Fatalf("graph %d has no history", survivingId)
+		}
+
+		types := []structure.GraphHistoryEntryType{}
+		for _, entry := range history.Entries {
+			types = append(types, entry.Type)
+		}
+
+		expected := []structure.GraphHistoryEntryType{
+			structure.GraphHistoryRelation,
+			structure.GraphHistoryRelation,
+			structure.GraphHistoryRelation,
+			structure.GraphHistoryMerge,
+		}
+		if !reflect.DeepEqual(types, expected) {
+			t.Fatalf("got history %v, expected %v", types, expected)
+		}
+
+		merge := history.Entries[3]
+		if merge.MergedGraph != mergedId || merge.Relations != 1 || merge.Relation != history.Entries[2].Relation {
+			t.Errorf("unexpected merge entry %+v", merge)
+		}
+	}
+	expectHistory(rtkcsm)
+
+	// merges are part of the export
+	export := bytes.Buffer{}
+	if _, err := rtkcsm.ExportGraphs(&export, structure.ExportFormatBinary); err != nil {
+		t.Fatal(err)
+	}
+
+	importedRTKCSM := newTestRTKCSM()
+	if err := importedRTKCSM.ImportGraphs(&export); err != nil {
+		t.Fatal(err)
+	}
+
+	if id := importedRTKCSM.ResolveGraphID(mergedId); id != survivingId {
+		t.Errorf("imported merged graph %d resolves to %d instead of %d", mergedId, id, survivingId)
+	}
+	expectHistory(importedRTKCSM)
+}
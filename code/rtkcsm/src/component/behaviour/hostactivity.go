@@ -0,0 +1,44 @@
+package behaviour
+
+import (
+	"rtkcsm/component/structure"
+	"sort"
+)
+
+// GetHostActivity lists all graphs a host is part of ordered by relevance
+func (c *RTKCSMImplementation[T, K]) GetHostActivity(address structure.IPAddress) structure.HostActivity {
+	c.graphsMutex.RLock()
+	defer c.graphsMutex.RUnlock()
+
+	hostActivity := structure.NewHostActivity(address)
+
+	// only internal hosts are part of the lookup table
+	var graphIds []structure.GraphID
+	if address.IsInternal() {
+		graphIds = c.lookup.SearchHost(address).ToSlice()
+	} else {
+		for id := range c.graphs {
+			graphIds = append(graphIds, id)
+		}
+	}
+
+	for _, id := range graphIds {
+		graph, ok := c.graphs[id]
+		if !ok {
+			continue
+		}
+
+		if activity, ok := graph.GetHostActivity(id, address); ok {
+			hostActivity.Add(activity)
+		}
+	}
+
+	sort.Slice(hostActivity.Graphs, func(i, j int) bool {
+		if hostActivity.Graphs[i].Relevance == hostActivity.Graphs[j].Relevance {
+			return hostActivity.Graphs[i].ID < hostActivity.Graphs[j].ID
+		}
+		return hostActivity.Graphs[i].Relevance > hostActivity.Graphs[j].Relevance
+	})
+
+	return hostActivity
+}
@@ -0,0 +1,71 @@
+package behaviour
+
+import (
+	"reflect"
+	"rtkcsm/component/structure"
+	"testing"
+	"time"
+)
+
+func TestHostActivity(t *testing.T) {
+	rtkcsm := newTestRTKCSM()
+
+	seconds := time.Now().Unix()
+	alerts := structure.Alerts{
+		newTestAlert(time.Unix(seconds-5, 0), "94.141.120.36", "172.16.42.42", 1),
+		newTestAlert(time.Unix(seconds-4, 0), "172.16.42.42", "218.92.0.27", 1),
+		newTestAlert(time.Unix(seconds-3, 0), "172.16.42.42", "172.16.42.1", 1),
+		newTestAlert(time.Unix(seconds-2, 0), "94.141.120.36", "172.16.42.50", 0.5),
+	}
+
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+
+	activity := rtkcsm.GetHostActivity(structure.ParseIPAddress("172.16.42.42"))
+	if len(activity.Graphs) != 1 || activity.Graphs[0].Relations != 3 || !activity.IsInternal {
+		t.Fatalf("unexpected activity of an internal host %+v", activity)
+	}
+
+	// exfiltration is not confirmed because of the lateral movement of the host
+	expectedStages := []structure.UKCStage{structure.R, structure.D1, structure.D2, structure.C2, structure.L, structure.S, structure.O}
+	if !reflect.DeepEqual(activity.Stages, expectedStages) {
+		t.Errorf("got stages %v, expected %v", activity.Stages, expectedStages)
+	}
+
+	if !reflect.DeepEqual(activity.Roles, []structure.HostRole{structure.HostRoleAttacker, structure.HostRoleVictim}) {
+		t.Errorf("unexpected roles %v", activity.Roles)
+	}
+
+	if activity.FirstSeen != alerts[0].Timestamp.UnixMilli() || activity.LastSeen != alerts[2].Timestamp.UnixMilli() {
+		t.Errorf("unexpected first seen %d or last seen %d", activity.FirstSeen, activity.LastSeen)
+	}
+
+	activity = rtkcsm.GetHostActivity(structure.ParseIPAddress("94.141.120.36"))
+	if len(activity.Graphs) != 2 || activity.Graphs[0].Relevance < activity.Graphs[1].Relevance {
+		t.Fatalf("unexpected activity of an external host %+v", activity)
+	}
+
+	if !reflect.DeepEqual(activity.Roles, []structure.HostRole{structure.HostRoleAttacker}) || !reflect.DeepEqual(activity.Stages, []structure.UKCStage{structure.R, structure.D1}) {
+		t.Errorf("unexpected roles %v or stages %v of an external host", activity.Roles, activity.Stages)
+	}
+
+	activity = rtkcsm.GetHostActivity(structure.ParseIPAddress("172.16.42.1"))
+	if len(activity.Graphs) != 1 || !reflect.DeepEqual(activity.Roles, []structure.HostRole{structure.HostRoleVictim}) {
+		t.Errorf("unexpected activity of a victim %+v", activity)
+	}
+
+	if activity := rtkcsm.GetHostActivity(structure.ParseIPAddress("172.16.42.99")); len(activity.Graphs) != 0 || activity.FirstSeen != 0 {
+		t.Errorf("unknown host has activity %+v", activity)
+	}
+
+	// evicted graphs are no longer found by their hosts
+	retentionPolicy := structure.NewRetentionPolicy()
+	retentionPolicy.MaxGraphs = 1
+	rtkcsm.SetRetentionPolicy(retentionPolicy)
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-1, 0), "94.141.120.37", "172.16.42.60", 1))
+
+	if activity := rtkcsm.GetHostActivity(structure.ParseIPAddress("172.16.42.42")); len(activity.Graphs) != 0 {
+		t.Errorf("host of an evicted graph has activity %+v", activity)
+	}
+}
@@ -0,0 +1,65 @@
+package behaviour
+
+import (
+	"errors"
+	"rtkcsm/component/structure"
+	"time"
+)
+
+var ErrGraphNotFound = errors.New("graph not found")
+
+// GetIncident returns the incident state of a graph or nil if the graph does not exist
+func (c *RTKCSMImplementation[T, K]) GetIncident(id structure.GraphID) *structure.Incident {
+	c.graphsMutex.RLock()
+	defer c.graphsMutex.RUnlock()
+
+	graph, ok := c.graphs[id]
+	if !ok {
+		return nil
+	}
+
+	incident := graph.GetIncident()
+	return &incident
+}
+
+// UpdateIncident changes the status, assignee or tags of the incident of a graph
+func (c *RTKCSMImplementation[T, K]) UpdateIncident(id structure.GraphID, update structure.IncidentUpdate) (*structure.Incident, error) {
+	return c.changeIncident(id, func(incident *structure.Incident, now time.Time) error {
+		return incident.Update(update, now)
+	})
+}
+
+// AddIncidentNote appends a note to the incident of a graph
+func (c *RTKCSMImplementation[T, K]) AddIncidentNote(id structure.GraphID, note structure.IncidentNote) (*structure.Incident, error) {
+	return c.changeIncident(id, func(incident *structure.Incident, now time.Time) error {
+		return incident.AddNote(note, now)
+	})
+}
+
+func (c *RTKCSMImplementation[T, K]) changeIncident(id structure.GraphID, change func(incident *structure.Incident, now time.Time) error) (*structure.Incident, error) {
+	c.graphsMutex.Lock()
+	defer c.graphsMutex.Unlock()
+
+	graph, ok := c.graphs[id]
+	if !ok {
+		return nil, ErrGraphNotFound
+	}
+
+	incident := graph.GetIncident()
+	if err := change(&incident, time.Now()); err != nil {
+		return nil, err
+	}
+
+	graph.SetIncident(incident)
+	c.logIncident(id, incident)
+	c.publishGraphEvent(structure.GraphEventIncident, id, graph, 0)
+
+	return &incident, nil
+}
+
+// reopenIncident expects the graphs mutex to be locked and is called when a graph receives new relations
+func (c *RTKCSMImplementation[T, K]) reopenIncident(id structure.GraphID, graph *structure.Graph[T, K], now time.Time) {
+	if graph.ReopenIncident(now) {
+		c.publishGraphEvent(structure.GraphEventIncident, id, graph, 0)
+	}
+}
@@ -0,0 +1,147 @@
+package behaviour
+
+import (
+	"bytes"
+	"reflect"
+	"rtkcsm/component/structure"
+	"testing"
+	"time"
+)
+
+func TestGraphIncidents(t *testing.T) {
+	folder := t.TempDir()
+	rtkcsm := newTestRTKCSM()
+	if err := rtkcsm.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	seconds := time.Now().Unix()
+	alerts := structure.Alerts{
+		newTestAlert(time.Unix(seconds-5, 0), "94.141.120.36", "172.16.42.42", 1),
+		newTestAlert(time.Unix(seconds-4, 0), "94.141.120.37", "172.16.42.42", 1),
+	}
+
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+
+	graphs := rtkcsm.GetGraphList(-1).Graphs
+	survivingId := min(graphs[0].ID, graphs[1].ID)
+	mergedId := max(graphs[0].ID, graphs[1].ID)
+
+	status := func(status structure.IncidentStatus) *string {
+		value := string(status)
+		return &value
+	}
+	assignee := "alice"
+
+	if _, err := rtkcsm.UpdateIncident(survivingId, structure.IncidentUpdate{
+		Status:   status(structure.IncidentStatusClosed),
+		Assignee: &assignee,
+		Tags:     &[]string{"phishing"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rtkcsm.UpdateIncident(mergedId, structure.IncidentUpdate{
+		Status: status(structure.IncidentStatusAcknowledged),
+		Tags:   &[]string{"lateral-movement", "phishing"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rtkcsm.AddIncidentNote(mergedId, structure.IncidentNote{Author: "bob", Text: "same campaign"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rtkcsm.UpdateIncident(mergedId, structure.IncidentUpdate{Status: status("resolved")}); err == nil {
+		t.Error("unknown status was accepted")
+	}
+
+	if _, err := rtkcsm.AddIncidentNote(0, structure.IncidentNote{Text: "missing"}); err != ErrGraphNotFound {
+		t.Errorf("note of a missing graph returned %v", err)
+	}
+
+	// links both graphs, the acknowledged state of the merged graph wins over the closed one
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-3, 0), "172.16.42.42", "172.16.42.1", 1))
+
+	incident := rtkcsm.GetIncident(survivingId)
+	if incident == nil {
+		t.Fatalf("graph %d has no incident", survivingId)
+	}
+
+	if incident.Status != structure.IncidentStatusAcknowledged || incident.Assignee != assignee || incident.ClosedAt != nil || incident.Reopened != 0 {
+		t.Errorf("unexpected merged incident %+v", incident)
+	}
+
+	if !reflect.DeepEqual(incident.Tags, []string{"lateral-movement", "phishing"}) || len(incident.Notes) != 1 {
+		t.Errorf("unexpected tags %v or notes %v of the merged incident", incident.Tags, incident.Notes)
+	}
+
+	closeIncident := func() {
+		if _, err := rtkcsm.UpdateIncident(survivingId, structure.IncidentUpdate{Status: status(structure.IncidentStatusClosed)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a repeated relation does not reopen the graph
+	closeIncident()
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-2, 0), "172.16.42.42", "172.16.42.1", 1))
+
+	if incident := rtkcsm.GetIncident(survivingId); incident.Status != structure.IncidentStatusClosed {
+		t.Errorf("repeated relation changed the incident to %s", incident.Status)
+	}
+
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-1, 0), "172.16.42.1", "218.92.0.27", 1))
+
+	incident = rtkcsm.GetIncident(survivingId)
+	if incident.Status != structure.IncidentStatusOpen || incident.Reopened != 1 || incident.ClosedAt != nil {
+		t.Errorf("new relation did not reopen the incident: %+v", incident)
+	}
+
+	closeIncident()
+	expected := rtkcsm.GetIncident(survivingId)
+
+	expectIncident := func(name string, rtkcsm *RTKCSMImplementation[structure.SimplifiedUKCStage, structure.UKCStage]) {
+		incident := rtkcsm.GetIncident(survivingId)
+		if incident == nil {
+			t.Fatalf("%s: graph %d has no incident", name, survivingId)
+		}
+
+		if incident.Status != expected.Status || incident.Assignee != expected.Assignee || incident.Reopened != expected.Reopened ||
+			!reflect.DeepEqual(incident.Tags, expected.Tags) || len(incident.Notes) != len(expected.Notes) ||
+			!incident.CreatedAt.Equal(expected.CreatedAt) || incident.ClosedAt == nil || !incident.ClosedAt.Equal(*expected.ClosedAt) {
+			t.Errorf("%s: got incident %+v, expected %+v", name, incident, expected)
+		}
+
+		if graphs := rtkcsm.GetGraphList(-1).Graphs; len(graphs) != 1 || graphs[0].Status != expected.Status {
+			t.Errorf("%s: unexpected graph list %v", name, graphs)
+		}
+	}
+
+	for _, format := range []structure.ExportFormat{structure.ExportFormatJson, structure.ExportFormatBinary} {
+		export := bytes.Buffer{}
+		if _, err := rtkcsm.ExportGraphs(&export, format); err != nil {
+			t.Fatal(err)
+		}
+
+		importedRTKCSM := newTestRTKCSM()
+		if err := importedRTKCSM.ImportGraphs(&export); err != nil {
+			t.Fatal(err)
+		}
+		expectIncident(string(format), importedRTKCSM)
+	}
+
+	// graph ids of replayed alerts depend on the id counter of this process, so the state is restored from the final snapshot
+	if err := rtkcsm.ClosePersistence(); err != nil {
+		t.Fatal(err)
+	}
+
+	restoredRTKCSM := newTestRTKCSM()
+	if err := restoredRTKCSM.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer restoredRTKCSM.ClosePersistence()
+
+	expectIncident("snapshot", restoredRTKCSM)
+}
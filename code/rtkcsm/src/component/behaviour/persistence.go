@@ -0,0 +1,299 @@
+package behaviour
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"rtkcsm/component/structure"
+	"sync"
+	"time"
+)
+
+const SNAPSHOT_FILE_NAME = "snapshot"
+const WRITE_AHEAD_LOG_FILE_NAME = "wal"
+
+// snapshotState is stored in the first line of a snapshot followed by the exported graphs. Alerts of
+// the reorder buffer are not part of the graphs yet and are buffered again after a restore.
+type snapshotState[T structure.Stage] struct {
+	Sequence      uint64                       `json:"sequence"`
+	Time          time.Time                    `json:"time"`
+	ReorderBuffer []structure.EnrichedAlert[T] `json:"reorder_buffer,omitempty"`
+}
+
+// EnablePersistence restores the state from the snapshot and write-ahead log of a folder,
+// logs all further changes and takes a snapshot in the given interval.
+func (c *RTKCSMImplementation[T, K]) EnablePersistence(folder string, snapshotInterval time.Duration) error {
+	if err := os.MkdirAll(filepath.Clean(folder), 0700); err != nil {
+		return err
+	}
+
+	sequence, err := c.restoreSnapshot(filepath.Join(folder, SNAPSHOT_FILE_NAME))
+	if err != nil {
+		return fmt.Errorf("error restoring snapshot: %s", err)
+	}
+
+	writeAheadLog, err := structure.OpenWriteAheadLog[T](filepath.Join(folder, WRITE_AHEAD_LOG_FILE_NAME))
+	if err != nil {
+		return err
+	}
+
+	replayed := 0
+	hostRisksChanged := false
+	c.graphsMutex.Lock()
+	c.replaying = true
+	err = writeAheadLog.Replay(sequence, func(entry structure.WriteAheadLogEntry[T]) error {
+		replayed += 1
+		hostRisksChanged = hostRisksChanged || entry.Type == structure.WriteAheadLogAddHostRisk || entry.Type == structure.WriteAheadLogDeleteHostRisk
+		return c.applyWriteAheadLogEntry(entry)
+	})
+	c.replaying = false
+	if err == nil && hostRisksChanged {
+		c.recomputeGraphRelevances()
+	}
+	c.graphsMutex.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("error replaying write-ahead log: %s", err)
+	}
+	log.Printf("restored %d graphs from %s (%d log entries)", c.GetGraphList(-1).Count, folder, replayed)
+
+	c.graphsMutex.Lock()
+	c.writeAheadLog = writeAheadLog
+	c.persistenceFolder = folder
+	if c.reorderBuffer != nil && c.reorderBuffer.Len() > 0 {
+		// restored alerts are released like alerts that just arrived
+		c.reorderTimer.Reset(c.reorderBuffer.Metrics().Window)
+	}
+	c.graphsMutex.Unlock()
+
+	if snapshotInterval > 0 {
+		c.snapshotStop = make(chan struct{})
+		c.snapshotWait = &sync.WaitGroup{}
+		c.snapshotWait.Add(1)
+
+		go func() {
+			defer c.snapshotWait.Done()
+			ticker := time.NewTicker(snapshotInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					if err := c.Snapshot(); err != nil {
+						log.Printf("error taking snapshot: %s", err)
+					}
+				case <-c.snapshotStop:
+					return
+				}
+			}
+		}()
+	}
+
+	return nil
+}
+
+// applyWriteAheadLogEntry expects the graphs mutex to be locked
+func (c *RTKCSMImplementation[T, K]) applyWriteAheadLogEntry(entry structure.WriteAheadLogEntry[T]) error {
+	switch entry.Type {
+	case structure.WriteAheadLogAlert:
+		if entry.Alert == nil {
+			return fmt.Errorf("alert entry %d without alert", entry.Sequence)
+		}
+		for _, alert := range c.bufferRelation(*entry.Alert) {
+			c.correlateRelation(alert, entry.Time)
+		}
+	case structure.WriteAheadLogFlushReorderBuffer:
+		if c.reorderBuffer != nil {
+			for _, alert := range c.reorderBuffer.Flush() {
+				c.correlateRelation(alert, entry.Time)
+			}
+		}
+	case structure.WriteAheadLogAddHostRisk:
+		if entry.HostRisk == nil {
+			return fmt.Errorf("host risk entry %d without host risk", entry.Sequence)
+		}
+		structure.HostManager.AddHostRiskLevel(structure.ParseIPAddress(entry.HostRisk.IpAddress), structure.RiskLevel(entry.HostRisk.RiskLevel))
+	case structure.WriteAheadLogDeleteHostRisk:
+		if entry.HostRisk == nil {
+			return fmt.Errorf("host risk entry %d without host risk", entry.Sequence)
+		}
+		structure.HostManager.DeleteHostRiskLevel(structure.ParseIPAddress(entry.HostRisk.IpAddress))
+	case structure.WriteAheadLogIncident:
+		if entry.Incident == nil {
+			return fmt.Errorf("incident entry %d without incident", entry.Sequence)
+		}
+		// the graph may have been merged or removed after the change
+		if graph, ok := c.graphs[c.resolveGraphID(entry.GraphID)]; ok {
+			graph.SetIncident(*entry.Incident)
+		}
+	default:
+		return fmt.Errorf("unknown write-ahead log entry type: %s", entry.Type)
+	}
+
+	return nil
+}
+
+// logHostRisk expects the graphs mutex to be locked
+func (c *RTKCSMImplementation[T, K]) logHostRisk(entryType structure.WriteAheadLogEntryType, address structure.IPAddress, riskLevel structure.RiskLevel) {
+	if c.writeAheadLog != nil {
+		err := c.writeAheadLog.Append(structure.WriteAheadLogEntry[T]{
+			Type: entryType,
+			Time: time.Now(),
+			HostRisk: &structure.HostRisk{
+				IpAddress: address.String(),
+				RiskLevel: float32(riskLevel),
+			},
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// logIncident expects the graphs mutex to be locked
+func (c *RTKCSMImplementation[T, K]) logIncident(id structure.GraphID, incident structure.Incident) {
+	if c.writeAheadLog != nil {
+		err := c.writeAheadLog.Append(structure.WriteAheadLogEntry[T]{
+			Type:     structure.WriteAheadLogIncident,
+			Time:     incident.UpdatedAt,
+			GraphID:  id,
+			Incident: &incident,
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (c *RTKCSMImplementation[T, K]) restoreSnapshot(path string) (uint64, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return 0, err
+	}
+
+	var state snapshotState[T]
+	if err := json.Unmarshal(line, &state); err != nil {
+		return 0, err
+	}
+
+	c.graphsMutex.Lock()
+	defer c.graphsMutex.Unlock()
+
+	// the export header restores graph ids and host risks
+	if err := c.importGraphs(reader); err != nil {
+		return 0, err
+	}
+
+	for _, bufferedAlert := range state.ReorderBuffer {
+		for _, alert := range c.bufferRelation(bufferedAlert) {
+			c.correlateRelation(alert, state.Time)
+		}
+	}
+
+	return state.Sequence, nil
+}
+
+// Snapshot exports all graphs into the persistence folder and truncates the write-ahead log
+func (c *RTKCSMImplementation[T, K]) Snapshot() error {
+	c.graphsMutex.RLock()
+	defer c.graphsMutex.RUnlock()
+
+	if c.writeAheadLog == nil {
+		return nil
+	}
+
+	path := filepath.Join(c.persistenceFolder, SNAPSHOT_FILE_NAME)
+	temporaryPath := path + ".tmp"
+
+	file, err := os.Create(filepath.Clean(temporaryPath))
+	if err != nil {
+		return err
+	}
+
+	err = c.writeSnapshot(file)
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(temporaryPath, path); err != nil {
+		return err
+	}
+
+	// entries before the snapshot are skipped during a replay even if truncating fails
+	return c.writeAheadLog.Truncate()
+}
+
+func (c *RTKCSMImplementation[T, K]) writeSnapshot(writer io.Writer) error {
+	state := snapshotState[T]{
+		Sequence: c.writeAheadLog.Sequence(),
+		Time:     time.Now(),
+	}
+	if c.reorderBuffer != nil {
+		state.ReorderBuffer = c.reorderBuffer.Alerts()
+	}
+
+	line, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	bufferedWriter := bufio.NewWriter(writer)
+	if _, err := bufferedWriter.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	if _, err := c.exportGraphs(bufferedWriter); err != nil {
+		return err
+	}
+
+	return bufferedWriter.Flush()
+}
+
+// ClosePersistence stops periodic snapshots, correlates buffered alerts and takes a final snapshot
+func (c *RTKCSMImplementation[T, K]) ClosePersistence() error {
+	c.FlushReorderBuffer()
+
+	if c.snapshotStop != nil {
+		close(c.snapshotStop)
+		c.snapshotWait.Wait()
+		c.snapshotStop = nil
+	}
+
+	if err := c.Snapshot(); err != nil {
+		return err
+	}
+
+	c.graphsMutex.Lock()
+	defer c.graphsMutex.Unlock()
+
+	if c.writeAheadLog == nil {
+		return nil
+	}
+
+	err := c.writeAheadLog.Close()
+	c.writeAheadLog = nil
+
+	return err
+}
@@ -0,0 +1,158 @@
+package behaviour
+
+import (
+	"fmt"
+	"rtkcsm/component/structure"
+	"testing"
+	"time"
+)
+
+func TestGraphPersistence(t *testing.T) {
+	folder := t.TempDir()
+	seconds := time.Now().Unix()
+
+	alerts := structure.Alerts{
+		newTestAlert(time.Unix(seconds-5, 0), "94.141.120.36", "172.16.42.42", 1),
+		newTestAlert(time.Unix(seconds-4, 0), "172.16.42.42", "218.92.0.27", 1),
+		newTestAlert(time.Unix(seconds-3, 0), "94.141.120.37", "172.16.42.43", 0.5),
+		newTestAlert(time.Unix(seconds-2, 0), "172.16.42.42", "172.16.42.1", 1),
+	}
+
+	rtkcsm := newTestRTKCSM()
+	if err := rtkcsm.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, alert := range alerts[:2] {
+		rtkcsm.AddAlert(alert)
+	}
+
+	if err := rtkcsm.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	// not part of the snapshot
+	for _, alert := range alerts[2:] {
+		rtkcsm.AddAlert(alert)
+	}
+
+	restoredRTKCSM := newTestRTKCSM()
+	if err := restoredRTKCSM.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer restoredRTKCSM.ClosePersistence()
+
+	graphList := rtkcsm.GetGraphList(-1)
+	restoredGraphList := restoredRTKCSM.GetGraphList(-1)
+
+	if graphList.Count != restoredGraphList.Count {
+		t.Fatalf("restored %d graphs instead of %d", restoredGraphList.Count, graphList.Count)
+	}
+
+	// graph ids depend on the global id counter of this process, so graphs are compared by their content
+	relations := map[string]int{}
+	for _, graph := range graphList.Graphs {
+		relations[fmt.Sprintf("%.4f", graph.Relevance)] += rtkcsm.GetGraph(graph.ID).Len()
+	}
+
+	for _, graph := range restoredGraphList.Graphs {
+		relations[fmt.Sprintf("%.4f", graph.Relevance)] -= restoredRTKCSM.GetGraph(graph.ID).Len()
+	}
+
+	for relevance, difference := range relations {
+		if difference != 0 {
+			t.Errorf("restored graphs with relevance %s differ by %d relations", relevance, difference)
+		}
+	}
+}
+
+func TestReorderBufferPersistence(t *testing.T) {
+	folder := t.TempDir()
+	seconds := time.Now().Unix()
+
+	alerts := structure.Alerts{
+		newTestAlert(time.Unix(seconds-4, 0), "172.16.42.42", "218.92.0.27", 1),
+		newTestAlert(time.Unix(seconds-5, 0), "94.141.120.36", "172.16.42.42", 1),
+		newTestAlert(time.Unix(seconds-3, 0), "172.16.42.42", "172.16.42.1", 1),
+	}
+
+	rtkcsm := newTestRTKCSM()
+	rtkcsm.SetReorderWindow(time.Hour)
+	defer rtkcsm.SetReorderWindow(0)
+	if err := rtkcsm.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, alert := range alerts[:2] {
+		rtkcsm.AddAlert(alert)
+	}
+
+	if err := rtkcsm.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	// only in the write-ahead log
+	rtkcsm.AddAlert(alerts[2])
+
+	// the alerts are still buffered when the process crashes
+	restoredRTKCSM := newTestRTKCSM()
+	restoredRTKCSM.SetReorderWindow(time.Hour)
+	defer restoredRTKCSM.SetReorderWindow(0)
+	if err := restoredRTKCSM.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer restoredRTKCSM.ClosePersistence()
+
+	if buffered := restoredRTKCSM.GetReorderMetrics().Buffered; buffered != len(alerts) {
+		t.Fatalf("restored %d buffered alerts instead of %d", buffered, len(alerts))
+	}
+
+	restoredRTKCSM.FlushReorderBuffer()
+
+	graphList := restoredRTKCSM.GetGraphList(-1)
+	if graphList.Count != 1 || restoredRTKCSM.GetGraph(graphList.Graphs[0].ID).Len() != len(alerts) {
+		t.Errorf("restored buffered alerts were not correlated in timestamp order: %+v", graphList)
+	}
+}
+
+func TestReplayedIncidentTimestamps(t *testing.T) {
+	folder := t.TempDir()
+	seconds := time.Now().Unix()
+
+	rtkcsm := newTestRTKCSM()
+	if err := rtkcsm.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-2, 0), "94.141.120.36", "172.16.42.42", 1))
+
+	// graph ids of replayed alerts depend on the id counter of this process, so the graph is restored from a snapshot
+	if err := rtkcsm.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	id := rtkcsm.GetGraphList(-1).Graphs[0].ID
+	status := string(structure.IncidentStatusClosed)
+	if _, err := rtkcsm.UpdateIncident(id, structure.IncidentUpdate{Status: &status}); err != nil {
+		t.Fatal(err)
+	}
+
+	// reopens the incident
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-1, 0), "172.16.42.42", "218.92.0.27", 1))
+	expected := rtkcsm.GetIncident(id)
+
+	// the incident changes are only restored from the write-ahead log
+	restoredRTKCSM := newTestRTKCSM()
+	if err := restoredRTKCSM.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer restoredRTKCSM.ClosePersistence()
+
+	incident := restoredRTKCSM.GetIncident(id)
+	if incident == nil {
+		t.Fatalf("graph %d was not restored", id)
+	}
+	if incident.Reopened != 1 || !incident.CreatedAt.Equal(expected.CreatedAt) || !incident.UpdatedAt.Equal(expected.UpdatedAt) {
+		t.Errorf("replayed incident %+v instead of %+v", incident, expected)
+	}
+}
@@ -0,0 +1,81 @@
+package behaviour
+
+import (
+	"log"
+	"rtkcsm/component/structure"
+	"time"
+)
+
+// SetReorderWindow buffers alerts for the window of alert time before they are correlated, so that alerts
+// from multiple sensors can link to alerts that arrived earlier but happened later. Buffered alerts are
+// also released if no alert arrives within the window of wall-clock time. A window <= 0 disables the buffer.
+func (c *RTKCSMImplementation[T, K]) SetReorderWindow(window time.Duration) {
+	c.FlushReorderBuffer()
+
+	c.graphsMutex.Lock()
+	defer c.graphsMutex.Unlock()
+
+	if c.reorderTimer != nil {
+		c.reorderTimer.Stop()
+		c.reorderTimer = nil
+	}
+
+	c.reorderBuffer = nil
+	if window > 0 {
+		c.reorderBuffer = structure.NewReorderBuffer[T](window)
+		c.reorderTimer = time.AfterFunc(window, c.FlushReorderBuffer)
+	}
+}
+
+// FlushReorderBuffer correlates all buffered alerts, e.g. at the end of the input
+func (c *RTKCSMImplementation[T, K]) FlushReorderBuffer() {
+	c.graphsMutex.Lock()
+	defer c.graphsMutex.Unlock()
+
+	if c.reorderBuffer == nil || c.reorderBuffer.Len() == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	// flushes do not depend on the alerts, so they are logged to release the same alerts during a replay
+	if c.writeAheadLog != nil {
+		err := c.writeAheadLog.Append(structure.WriteAheadLogEntry[T]{
+			Type: structure.WriteAheadLogFlushReorderBuffer,
+			Time: now,
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	for _, alert := range c.reorderBuffer.Flush() {
+		if err := c.releaseRelation(alert, now); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (c *RTKCSMImplementation[T, K]) GetReorderMetrics() structure.ReorderMetrics {
+	c.graphsMutex.RLock()
+	defer c.graphsMutex.RUnlock()
+
+	if c.reorderBuffer == nil {
+		return structure.ReorderMetrics{}
+	}
+
+	return c.reorderBuffer.Metrics()
+}
+
+// SetRelinkLateAlerts merges the graph of an alert that is older than already correlated alerts with
+// the graphs it precedes, so that the graphs match an in-order correlation. This tracks an additional
+// index of the relations.
+func (c *RTKCSMImplementation[T, K]) SetRelinkLateAlerts(enabled bool) {
+	c.graphsMutex.Lock()
+	defer c.graphsMutex.Unlock()
+
+	c.relinkLateAlerts = enabled
+	if enabled {
+		c.lookup.TrackFollowingRelations(c.graphs)
+	}
+}
@@ -0,0 +1,106 @@
+package behaviour
+
+import (
+	"fmt"
+	"reflect"
+	"rtkcsm/component/structure"
+	"testing"
+	"time"
+)
+
+func TestReorderWindow(t *testing.T) {
+	seconds := time.Now().Unix()
+
+	// the lateral movement arrives before the incoming alert that caused it
+	alerts := structure.Alerts{
+		newTestAlert(time.Unix(seconds-2, 0), "172.16.42.42", "172.16.42.1", 1),
+		newTestAlert(time.Unix(seconds-5, 0), "94.141.120.36", "172.16.42.42", 1),
+	}
+
+	unorderedRTKCSM := newTestRTKCSM()
+	for _, alert := range alerts {
+		unorderedRTKCSM.AddAlert(alert)
+	}
+
+	if count := unorderedRTKCSM.GetGraphList(-1).Count; count != 2 {
+		t.Fatalf("unordered alerts created %d graphs instead of 2", count)
+	}
+
+	rtkcsm := newTestRTKCSM()
+	rtkcsm.SetReorderWindow(10 * time.Second)
+	defer rtkcsm.SetReorderWindow(0)
+
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+
+	if count := rtkcsm.GetGraphList(-1).Count; count != 0 {
+		t.Errorf("%d graphs were created before the watermark passed the alerts", count)
+	}
+
+	rtkcsm.FlushReorderBuffer()
+
+	if count := rtkcsm.GetGraphList(-1).Count; count != 1 {
+		t.Errorf("reordered alerts created %d graphs instead of 1", count)
+	}
+
+	// alerts before the last released alert are outside of the window
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-10, 0), "94.141.120.37", "172.16.42.43", 1))
+
+	metrics := rtkcsm.GetReorderMetrics()
+	if metrics.Reordered != 1 || metrics.LateAlerts != 1 || metrics.MaxLateness != 8*time.Second || metrics.Buffered != 0 {
+		t.Errorf("unexpected reorder metrics: %+v", metrics)
+	}
+}
+
+func TestRelinkLateAlerts(t *testing.T) {
+	seconds := time.Now().Unix()
+
+	alerts := structure.Alerts{}
+	addresses := [][2]string{
+		{"94.141.120.36", "172.16.42.42"},
+		{"172.16.42.42", "218.92.0.27"},
+		{"172.16.42.42", "172.16.42.1"},
+		{"172.16.42.1", "10.12.2.93"},
+		{"10.12.2.93", "218.92.0.28"},
+		{"94.141.120.37", "172.16.42.43"},
+		{"172.16.42.43", "172.16.42.1"},
+		{"94.141.120.38", "10.12.2.94"},
+	}
+	for i, pair := range addresses {
+		alerts = append(alerts, newTestAlert(time.Unix(seconds-int64(len(addresses)-i), 0), pair[0], pair[1], float32(i+1)/float32(len(addresses))))
+	}
+
+	graphContent := func(rtkcsm *RTKCSMImplementation[structure.SimplifiedUKCStage, structure.UKCStage]) map[string]int {
+		relations := map[string]int{}
+		for _, graph := range rtkcsm.GetGraphList(-1).Graphs {
+			relations[fmt.Sprintf("%.4f", graph.Relevance)] += rtkcsm.GetGraph(graph.ID).Len()
+		}
+		return relations
+	}
+
+	inOrderRTKCSM := newTestRTKCSM()
+	for _, alert := range alerts {
+		inOrderRTKCSM.AddAlert(alert)
+	}
+	expected := graphContent(inOrderRTKCSM)
+
+	permutations := [][]int{
+		{7, 6, 5, 4, 3, 2, 1, 0},
+		{3, 1, 6, 0, 7, 2, 5, 4},
+		{2, 4, 0, 3, 1, 7, 6, 5},
+	}
+
+	for _, permutation := range permutations {
+		rtkcsm := newTestRTKCSM()
+		rtkcsm.SetRelinkLateAlerts(true)
+
+		for _, i := range permutation {
+			rtkcsm.AddAlert(alerts[i])
+		}
+
+		if relations := graphContent(rtkcsm); !reflect.DeepEqual(relations, expected) {
+			t.Errorf("graphs of order %v differ from in-order graphs: %v != %v", permutation, relations, expected)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package behaviour
+
+import (
+	"log"
+	"rtkcsm/component/structure"
+	"sort"
+	"time"
+)
+
+type graphActivity struct {
+	id       structure.GraphID
+	lastSeen time.Time
+}
+
+func (c *RTKCSMImplementation[T, K]) SetRetentionPolicy(policy structure.RetentionPolicy) {
+	c.graphsMutex.Lock()
+	defer c.graphsMutex.Unlock()
+
+	if policy.CheckInterval <= 0 {
+		policy.CheckInterval = structure.DEFAULT_RETENTION_CHECK_INTERVAL
+	}
+
+	c.retentionPolicy = policy
+}
+
+// enforceRetentionPolicy expects the graphs mutex to be locked
+func (c *RTKCSMImplementation[T, K]) enforceRetentionPolicy() {
+	policy := &c.retentionPolicy
+	if !policy.IsEnabled() {
+		return
+	}
+
+	if policy.MaxIdleTime > 0 && c.watermark.Sub(c.lastRetentionCheck) >= policy.CheckInterval {
+		c.lastRetentionCheck = c.watermark
+		deadline := c.watermark.Add(-policy.MaxIdleTime)
+
+		for id, graph := range c.graphs {
+			if graph.LastSeen().Before(deadline) {
+				c.removeGraph(id)
+			}
+		}
+	}
+
+	if policy.ExceedsGraphs(len(c.graphs)) || policy.ExceedsRelations(c.relationCount) {
+		activities := make([]graphActivity, 0, len(c.graphs))
+		for id, graph := range c.graphs {
+			activities = append(activities, graphActivity{
+				id:       id,
+				lastSeen: graph.LastSeen(),
+			})
+		}
+
+		// least recently active graphs are evicted first
+		sort.Slice(activities, func(i, j int) bool {
+			if activities[i].lastSeen.Equal(activities[j].lastSeen) {
+				return activities[i].id < activities[j].id
+			}
+			return activities[i].lastSeen.Before(activities[j].lastSeen)
+		})
+
+		for _, activity := range activities {
+			graphsExceeded := policy.MaxGraphs > 0 && len(c.graphs) > policy.GraphsLowWaterMark()
+			relationsExceeded := policy.MaxRelations > 0 && c.relationCount > policy.RelationsLowWaterMark()
+			if !graphsExceeded && !relationsExceeded {
+				break
+			}
+
+			c.removeGraph(activity.id)
+		}
+	}
+}
+
+// removeGraph archives a graph and deletes it from all indices, it expects the graphs mutex to be locked.
+// Graphs are not archived again while the write-ahead log is replayed.
+func (c *RTKCSMImplementation[T, K]) removeGraph(id structure.GraphID) {
+	graph, ok := c.graphs[id]
+	if !ok {
+		return
+	}
+
+	// a failing archive must not stop the eviction, otherwise the memory is not bounded anymore
+	if c.retentionPolicy.Archive != nil && !c.replaying {
+		if _, err := writeGraph(c.retentionPolicy.Archive, id, graph); err != nil {
+			log.Printf("error archiving graph %d: %s", id, err)
+		}
+	}
+
+	c.lookup.RemoveGraph(id, graph)
+	c.publishGraphEvent(structure.GraphEventRemoved, id, graph, 0)
+	c.forgetMerges(id)
+	delete(c.firedAlertRules, id)
+	delete(c.alertRuleStates, id)
+	c.sortedGraphs.Delete(id)
+	c.relationCount -= graph.Len()
+	delete(c.graphs, id)
+}
@@ -0,0 +1,91 @@
+package behaviour
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"rtkcsm/component/structure"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func TestGraphRetention(t *testing.T) {
+	rtkcsm := newTestRTKCSM()
+
+	archive := bytes.Buffer{}
+	retentionPolicy := structure.NewRetentionPolicy()
+	retentionPolicy.MaxIdleTime = time.Hour
+	retentionPolicy.Archive = &archive
+	rtkcsm.SetRetentionPolicy(retentionPolicy)
+
+	seconds := time.Now().Unix()
+
+	alerts := structure.Alerts{
+		newTestAlert(time.Unix(seconds-3*60*60, 0), "1.1.13.37", "172.31.64.67", 1),
+		newTestAlert(time.Unix(seconds-3*60*60+1, 0), "172.31.64.67", "1.1.14.47", 1),
+		newTestAlert(time.Unix(seconds, 0), "1.1.15.57", "172.31.69.20", 1),
+		// Would be linked to the expired graph without retention
+		newTestAlert(time.Unix(seconds+1, 0), "172.31.64.67", "1.1.16.67", 1),
+	}
+
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+
+	sortedGraphList := rtkcsm.GetGraphList(-1)
+	if sortedGraphList.Count != 2 {
+		t.Errorf("graph list too short or too long: %d graphs", sortedGraphList.Count)
+	}
+
+	if lines := strings.Count(archive.String(), "\n"); lines != 1 {
+		t.Errorf("expected one archived graph, got %d", lines)
+	}
+
+	// evictions of a replayed write-ahead log were archived before the restart
+	folder := t.TempDir()
+	persistentRTKCSM := newTestRTKCSM()
+	persistentRTKCSM.SetRetentionPolicy(retentionPolicy)
+	if err := persistentRTKCSM.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, alert := range alerts {
+		persistentRTKCSM.AddAlert(alert)
+	}
+
+	archive.Reset()
+	restoredRTKCSM := newTestRTKCSM()
+	restoredRTKCSM.SetRetentionPolicy(retentionPolicy)
+	if err := restoredRTKCSM.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer restoredRTKCSM.ClosePersistence()
+
+	if count := restoredRTKCSM.GetGraphList(-1).Count; count != 2 {
+		t.Errorf("restored %d graphs instead of 2", count)
+	}
+	if archive.Len() != 0 {
+		t.Errorf("replay archived evicted graphs again: %s", archive.String())
+	}
+
+	rtkcsm.Reset()
+	retentionPolicy = structure.NewRetentionPolicy()
+	retentionPolicy.MaxGraphs = 2
+	// graphs are evicted even if they cannot be archived
+	retentionPolicy.Archive = failingWriter{}
+	rtkcsm.SetRetentionPolicy(retentionPolicy)
+
+	for i := range 5 {
+		rtkcsm.AddAlert(newTestAlert(time.Unix(seconds+int64(i), 0), "1.1.13.37", fmt.Sprintf("172.31.64.%d", i+1), 1))
+	}
+
+	if count := rtkcsm.GetGraphList(-1).Count; count > 2 {
+		t.Errorf("graph cap exceeded: %d graphs", count)
+	}
+}
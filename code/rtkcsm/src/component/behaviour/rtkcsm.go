@@ -7,12 +7,26 @@ import (
 
 type RTKCSM[T structure.Stage, K structure.Stage] interface {
 	AddAlert(alert structure.Alert) error
+	AddAlerts(alerts <-chan structure.Alert, handleError func(alert structure.Alert, err error))
+	WorkerCount() int
+	GetQueuedAlerts() int
+	FlushReorderBuffer()
+	GetReorderMetrics() structure.ReorderMetrics
+	SubscribeGraphEvents() *structure.GraphEventSubscription
 	GetGraphList(limit int) structure.GraphInformationList
+	SearchGraphs(query structure.GraphQuery) structure.GraphInformationList
 	GetGraph(id structure.GraphID) *structure.Graph[T, K]
+	ResolveGraphID(id structure.GraphID) structure.GraphID
+	GetGraphHistory(id structure.GraphID) *structure.GraphHistory
+	GetAttackPaths(id structure.GraphID, limit int) []structure.AttackPath[T]
+	GetIncident(id structure.GraphID) *structure.Incident
+	UpdateIncident(id structure.GraphID, update structure.IncidentUpdate) (*structure.Incident, error)
+	AddIncidentNote(id structure.GraphID, note structure.IncidentNote) (*structure.Incident, error)
+	GetHostActivity(address structure.IPAddress) structure.HostActivity
 	GetHostRisks() []structure.HostRisk
 	AddHostRisk(address structure.IPAddress, riskLevel structure.RiskLevel)
 	DeleteHostRisk(address structure.IPAddress)
 	ImportGraphs(reader io.Reader) error
-	ExportGraphs(writer io.Writer) (int, error)
+	ExportGraphs(writer io.Writer, format structure.ExportFormat) (int, error)
 	Reset()
 }
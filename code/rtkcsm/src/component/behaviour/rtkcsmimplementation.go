@@ -5,10 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"rtkcsm/component/structure"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type RTKCSMImplementation[T structure.Stage, K structure.Stage] struct {
@@ -19,9 +24,40 @@ type RTKCSMImplementation[T structure.Stage, K structure.Stage] struct {
 	profilerOptions *structure.ProfilerOptions
 	stageMapper     structure.StageMapper[T]
 	stateMachine    structure.StateMachine[T, K]
+	workerCount     int
+	queuedAlerts    atomic.Int64 // alerts of AddAlerts that are not correlated yet
+
+	retentionPolicy    structure.RetentionPolicy
+	relationCount      int
+	watermark          time.Time
+	lastRetentionCheck time.Time
+
+	reorderBuffer    *structure.ReorderBuffer[T]
+	reorderTimer     *time.Timer
+	relinkLateAlerts bool
+
+	events       *structure.GraphEventBroker
+	mergedGraphs map[structure.GraphID]structure.GraphMerge
+	merges       map[structure.GraphID][]structure.GraphMerge
+
+	alertRules      []structure.AlertRule
+	firedAlertRules map[structure.GraphID]map[string]bool
+	alertRuleStates map[structure.GraphID]*structure.AlertRuleState[T]
+	notifications   chan structure.AlertNotification
+
+	writeAheadLog     *structure.WriteAheadLog[T]
+	persistenceFolder string
+	replaying         bool // evictions of a replay were archived before the restart
+	snapshotStop      chan struct{}
+	snapshotWait      *sync.WaitGroup
 }
 
+// NewIncrementalRTKCSM creates an RTKCSM that decodes alerts and maps stages with workerCount workers (number of CPUs if < 1)
 func NewIncrementalRTKCSM[T structure.Stage, K structure.Stage](workerCount int, stageMapper structure.StageMapper[T], stateMachine structure.StateMachine[T, K], profilerOptions *structure.ProfilerOptions) *RTKCSMImplementation[T, K] {
+	if workerCount < 1 {
+		workerCount = runtime.NumCPU()
+	}
+
 	var sortedGraphs structure.SortedMap[structure.GraphID, float32]
 	sortedGraphs = structure.NewWriteEfficientSortedMap[structure.GraphID, float32](true)
 	if profilerOptions.Has(structure.GraphRankingProfilerOptionFlag) {
@@ -35,42 +71,85 @@ func NewIncrementalRTKCSM[T structure.Stage, K structure.Stage](workerCount int,
 		sortedGraphs:    sortedGraphs,
 		profilerOptions: profilerOptions,
 		stageMapper:     stageMapper,
+		stateMachine:    stateMachine,
+		workerCount:     workerCount,
+		retentionPolicy: structure.NewRetentionPolicy(),
+		events:          structure.NewGraphEventBroker(),
+		mergedGraphs:    map[structure.GraphID]structure.GraphMerge{},
+		merges:          map[structure.GraphID][]structure.GraphMerge{},
+		firedAlertRules: map[structure.GraphID]map[string]bool{},
+		alertRuleStates: map[structure.GraphID]*structure.AlertRuleState[T]{},
 	}
 
 	return &rtkcsm
 }
 
 func (c *RTKCSMImplementation[T, K]) GetGraphList(page int) structure.GraphInformationList {
-	graphs := []structure.GraphInformation{}
+	return c.SearchGraphs(structure.NewGraphQuery(page))
+}
+
+// SearchGraphs returns a page of the graphs matching the query and the number of all matching graphs
+func (c *RTKCSMImplementation[T, K]) SearchGraphs(query structure.GraphQuery) structure.GraphInformationList {
 	c.graphsMutex.RLock()
 	defer c.graphsMutex.RUnlock()
 
-	length := c.sortedGraphs.Len()
-
-	if page < 0 {
-		page = 0
+	page := max(query.Page, 0)
+	pageSize := query.PageSize
+	if pageSize < 1 {
+		pageSize = structure.DEFAULT_GRAPH_PAGE_SIZE
 	}
 
-	itemsPerPage := 100
-	offset := page * itemsPerPage
-	limit := offset + itemsPerPage
+	// graphs are already sorted by relevance
+	if !query.HasFilters() && (query.Sort == structure.GraphSortRelevance || query.Sort == "") {
+		length := c.sortedGraphs.Len()
+		graphs := []structure.GraphInformation{}
+
+		for i := min(page*pageSize, length); i < min((page+1)*pageSize, length); i++ {
+			graphs = append(graphs, c.getGraphInformation(c.sortedGraphs.Get(i)))
+		}
 
-	if limit >= length {
-		limit = length
+		return structure.GraphInformationList{
+			Graphs: graphs,
+			Count:  length,
+		}
 	}
 
-	for i := offset; i < limit; i++ {
+	matches := []structure.GraphInformation{}
+	for i := range c.sortedGraphs.Len() {
 		id, relevance := c.sortedGraphs.Get(i)
+		if c.graphs[id].MatchesQuery(query) {
+			matches = append(matches, c.getGraphInformation(id, relevance))
+		}
+	}
 
-		graphs = append(graphs, structure.GraphInformation{
-			ID:        id,
-			Relevance: relevance,
+	// the relevance order is kept for equal values
+	switch query.Sort {
+	case structure.GraphSortLastSeen:
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].LastSeen > matches[j].LastSeen
+		})
+	case structure.GraphSortSize:
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].Relations > matches[j].Relations
 		})
 	}
 
 	return structure.GraphInformationList{
-		Graphs: graphs,
-		Count:  length,
+		Graphs: matches[min(page*pageSize, len(matches)):min((page+1)*pageSize, len(matches))],
+		Count:  len(matches),
+	}
+}
+
+// getGraphInformation expects the graphs mutex to be locked
+func (c *RTKCSMImplementation[T, K]) getGraphInformation(id structure.GraphID, relevance float32) structure.GraphInformation {
+	graph := c.graphs[id]
+
+	return structure.GraphInformation{
+		ID:        id,
+		Relevance: relevance,
+		Status:    graph.IncidentStatus(),
+		Relations: graph.Len(),
+		LastSeen:  graph.LastSeen().UnixMilli(),
 	}
 }
 
@@ -87,56 +166,189 @@ func (c *RTKCSMImplementation[T, K]) GetGraph(id structure.GraphID) *structure.G
 }
 
 func (c *RTKCSMImplementation[T, K]) Reset() {
+	c.reset()
+
+	// an empty snapshot prevents that the previous state is restored
+	if err := c.Snapshot(); err != nil {
+		log.Printf("error taking snapshot: %s", err)
+	}
+}
+
+func (c *RTKCSMImplementation[T, K]) reset() {
 	c.graphsMutex.Lock()
 	defer c.graphsMutex.Unlock()
 	c.graphs = map[structure.GraphID]*structure.Graph[T, K]{}
 	c.lookup = structure.NewLookupTable(c.stateMachine)
+	if c.relinkLateAlerts {
+		c.lookup.TrackFollowingRelations(c.graphs)
+	}
 	c.sortedGraphs = structure.NewWriteEfficientSortedMap[structure.GraphID, float32](true)
+	c.relationCount = 0
+	c.mergedGraphs = map[structure.GraphID]structure.GraphMerge{}
+	c.merges = map[structure.GraphID][]structure.GraphMerge{}
+	c.firedAlertRules = map[structure.GraphID]map[string]bool{}
+	c.alertRuleStates = map[structure.GraphID]*structure.AlertRuleState[T]{}
+	c.watermark = time.Time{}
+	c.lastRetentionCheck = time.Time{}
+
+	if c.reorderBuffer != nil {
+		c.reorderBuffer = structure.NewReorderBuffer[T](c.reorderBuffer.Metrics().Window)
+	}
+
+	c.events.Publish(structure.GraphEvent{
+		Type: structure.GraphEventReload,
+	})
+}
+
+func (c *RTKCSMImplementation[T, K]) WorkerCount() int {
+	return c.workerCount
+}
+
+// GetQueuedAlerts returns the alerts that wait for the correlation, i.e. alerts passed to AddAlerts
+// that are not correlated yet and alerts in the reorder buffer
+func (c *RTKCSMImplementation[T, K]) GetQueuedAlerts() int {
+	c.graphsMutex.RLock()
+	defer c.graphsMutex.RUnlock()
+
+	queued := int(c.queuedAlerts.Load())
+	if c.reorderBuffer != nil {
+		queued += c.reorderBuffer.Metrics().Buffered
+	}
+
+	return queued
 }
 
 func (c *RTKCSMImplementation[T, K]) AddAlert(alert structure.Alert) error {
-	err := c.processStages(alert)
+	relation, err := c.processStages(alert)
 	if err != nil {
 		return err
 	}
-	return c.profilerOptions.TakeMeasurement(len(c.graphs), false)
+	return c.processRelation(relation)
 }
 
-func (c *RTKCSMImplementation[T, K]) processStages(alert structure.Alert) error {
+type stageResult[T structure.Stage] struct {
+	alert    structure.Alert
+	relation structure.EnrichedAlert[T]
+	err      error
+}
+
+// AddAlerts maps the stages of alerts in parallel and correlates them in the order of the channel until it is closed.
+// Alerts that cannot be processed are passed to handleError if it is not nil.
+func (c *RTKCSMImplementation[T, K]) AddAlerts(alerts <-chan structure.Alert, handleError func(alert structure.Alert, err error)) {
+	results := structure.OrderedMap(c.workerCount, alerts, func(alert structure.Alert) stageResult[T] {
+		c.queuedAlerts.Add(1)
+		relation, err := c.processStages(alert)
+		return stageResult[T]{
+			alert:    alert,
+			relation: relation,
+			err:      err,
+		}
+	})
+
+	for result := range results {
+		err := result.err
+		if err == nil {
+			err = c.processRelation(result.relation)
+		}
+		c.queuedAlerts.Add(-1)
+
+		if err != nil && handleError != nil {
+			handleError(result.alert, err)
+		}
+	}
+}
+
+// processStages does not access the graphs and is called by multiple workers
+func (c *RTKCSMImplementation[T, K]) processStages(alert structure.Alert) (structure.EnrichedAlert[T], error) {
 	if !alert.SourceIP.IsUnspecified() && !alert.DestinationIP.IsUnspecified() {
 		metaStage, err := c.stageMapper.DetermineStage(alert)
 		if err == nil {
-			relation := structure.EnrichedAlert[T]{
+			return structure.EnrichedAlert[T]{
 				Alert:     alert,
 				MetaStage: metaStage,
-			}
-
-			c.processRelation(relation)
-
-			return nil
+			}, nil
 		}
 
-		return fmt.Errorf("stage not found (%s) -> (%s): %s", alert.SourceIP, alert.DestinationIP, err)
+		return structure.EnrichedAlert[T]{}, fmt.Errorf("stage not found (%s) -> (%s): %s", alert.SourceIP, alert.DestinationIP, err)
 	} else {
-		return fmt.Errorf("undefined source (%s) or destination ip (%s)", alert.SourceIP, alert.DestinationIP)
+		return structure.EnrichedAlert[T]{}, fmt.Errorf("undefined source (%s) or destination ip (%s)", alert.SourceIP, alert.DestinationIP)
 	}
 }
 
-func (c *RTKCSMImplementation[T, K]) processRelation(alert structure.EnrichedAlert[T]) {
+func (c *RTKCSMImplementation[T, K]) processRelation(alert structure.EnrichedAlert[T]) error {
 	c.graphsMutex.Lock()
 	defer c.graphsMutex.Unlock()
-	graphIds := c.lookup.SearchRelations(&alert).ToSlice()
+
+	now := time.Now()
+
+	// alerts are logged when they are accepted, so that alerts in the reorder buffer are not lost
+	if c.writeAheadLog != nil {
+		err := c.writeAheadLog.Append(structure.WriteAheadLogEntry[T]{
+			Type:  structure.WriteAheadLogAlert,
+			Time:  now,
+			Alert: &alert,
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	if c.reorderBuffer != nil {
+		c.reorderTimer.Reset(c.reorderBuffer.Metrics().Window)
+	}
+
+	for _, releasedAlert := range c.bufferRelation(alert) {
+		if err := c.releaseRelation(releasedAlert, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bufferRelation expects the graphs mutex to be locked. It returns the alerts that are released by
+// the reorder buffer, or the alert itself without a reorder buffer.
+func (c *RTKCSMImplementation[T, K]) bufferRelation(alert structure.EnrichedAlert[T]) []structure.EnrichedAlert[T] {
+	if c.reorderBuffer == nil {
+		return []structure.EnrichedAlert[T]{alert}
+	}
+
+	return c.reorderBuffer.Push(alert)
+}
+
+// releaseRelation expects the graphs mutex to be locked
+func (c *RTKCSMImplementation[T, K]) releaseRelation(alert structure.EnrichedAlert[T], now time.Time) error {
+	c.correlateRelation(alert, now)
+
+	// measured with the lock as alerts of multiple connections are correlated concurrently
+	return c.profilerOptions.TakeMeasurement(len(c.graphs), false)
+}
+
+// correlateRelation expects the graphs mutex to be locked. Incidents are created and reopened at now,
+// which is the time of the write-ahead log entry during a replay.
+func (c *RTKCSMImplementation[T, K]) correlateRelation(alert structure.EnrichedAlert[T], now time.Time) {
+	graphIdSet := c.lookup.SearchRelations(&alert)
+	if c.relinkLateAlerts && alert.Timestamp.Before(c.watermark) {
+		// graphs with later relations would have been linked to the alert if it had arrived in order
+		graphIdSet.Append(c.lookup.SearchFollowingRelations(&alert).ToSlice()...)
+	}
+	graphIds := graphIdSet.ToSlice()
 
 	var graphId structure.GraphID = 0
 	var graph *structure.Graph[T, K]
+	relationCountBefore := 0
+	var relevanceBefore float32 = 0
 
 	if len(graphIds) == 0 {
 		graphId = structure.NextGraphID()
 		graph = structure.NewGraph[T, K]()
+		graph.SetIncident(structure.NewIncident(now))
 		c.graphs[graphId] = graph
 	} else if len(graphIds) == 1 {
 		graphId = graphIds[0]
 		graph = c.graphs[graphId]
+		relationCountBefore = graph.Len()
+		relevanceBefore = graph.Relevance()
 	} else {
 		// merge graphs if there is an overlap
 		for _, duplicateGraphId := range graphIds {
@@ -146,23 +358,46 @@ func (c *RTKCSMImplementation[T, K]) processRelation(alert structure.EnrichedAle
 		}
 
 		graph = c.graphs[graphId]
+		relationCountBefore = graph.Len()
+		relevanceBefore = graph.Relevance()
 
 		for _, duplicateGraphId := range graphIds {
 			if duplicateGraphId != graphId {
+				relationCountBefore += c.graphs[duplicateGraphId].Len()
 				graph.Merge(c.graphs[duplicateGraphId], duplicateGraphId, graphId)
+				c.lookup.MergeGraph(duplicateGraphId, graphId, c.graphs[duplicateGraphId])
+				c.recordMerge(duplicateGraphId, graphId, c.graphs[duplicateGraphId], alert)
+				c.mergeFiredAlertRules(duplicateGraphId, graphId)
+				c.publishGraphEvent(structure.GraphEventMerged, duplicateGraphId, c.graphs[duplicateGraphId], graphId)
 				c.sortedGraphs.Delete(duplicateGraphId)
 				delete(c.graphs, duplicateGraphId)
 			}
 		}
 
 	}
+	relationCountMerged := graph.Len()
 	relation := graph.Append(alert)
+	c.relationCount += graph.Len() - relationCountBefore
 
 	// update sorted graphs
 	c.sortedGraphs.Insert(graphId, graph.Relevance())
 
 	c.lookup.AddRelation(&relation, graphId, graph)
 
+	if len(graphIds) == 0 {
+		c.publishGraphEvent(structure.GraphEventCreated, graphId, graph, 0)
+	} else {
+		c.publishGraphEvent(structure.GraphEventGrown, graphId, graph, 0)
+		if graph.Relevance() != relevanceBefore {
+			c.publishGraphEvent(structure.GraphEventRanking, graphId, graph, 0)
+		}
+		if graph.Len() > relationCountMerged {
+			c.reopenIncident(graphId, graph, now)
+		}
+	}
+
+	c.evaluateAlertRules(graphId, graph, relation, graph.Len() > relationCountMerged)
+
 	// Get position of correct graph for eval
 	if c.profilerOptions.Has(structure.GraphRankingProfilerOptionFlag) {
 		series := structure.PerformanceManager.GetSeries("graph-ranking")
@@ -170,70 +405,297 @@ func (c *RTKCSMImplementation[T, K]) processRelation(alert structure.EnrichedAle
 			series.Add(relation.Timestamp, c.sortedGraphs.GetPosition(c.profilerOptions.GetGraphId()), len(c.graphs))
 		}
 	}
+
+	if relation.Timestamp.After(c.watermark) {
+		c.watermark = relation.Timestamp
+	}
+
+	c.enforceRetentionPolicy()
 }
 
 func (c *RTKCSMImplementation[T, K]) ImportGraphs(reader io.Reader) error {
 	c.graphsMutex.Lock()
 	defer c.graphsMutex.Unlock()
+
+	if err := c.importGraphs(reader); err != nil {
+		return err
+	}
+
+	c.events.Publish(structure.GraphEvent{
+		Type: structure.GraphEventReload,
+	})
+
+	return nil
+}
+
+type importedGraph[T structure.Stage, K structure.Stage] struct {
+	id    structure.GraphID
+	graph *structure.Graph[T, K]
+}
+
+// importGraphs reads all versions and formats of the export and only changes the state if the whole file is valid
+func (c *RTKCSMImplementation[T, K]) importGraphs(reader io.Reader) error {
+	bufferedReader := bufio.NewReader(reader)
+
+	var header structure.ExportHeader
+	var graphs []importedGraph[T, K]
+	var err error
+
+	prefix, _ := bufferedReader.Peek(len(structure.BINARY_EXPORT_MAGIC))
+	if structure.IsBinaryExport(prefix) {
+		header, graphs, err = readBinaryGraphs[T, K](bufferedReader)
+	} else {
+		header, graphs, err = readJsonGraphs[T, K](bufferedReader)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.restoreGraphs(header, graphs)
+}
+
+func validateExportHeader(header structure.ExportHeader) error {
+	if header.Format != structure.EXPORT_FORMAT_NAME {
+		return fmt.Errorf("unknown export format: %s", header.Format)
+	}
+
+	if header.Version < 1 || header.Version > structure.EXPORT_FORMAT_VERSION {
+		return fmt.Errorf("unsupported export format version: %d", header.Version)
+	}
+
+	return nil
+}
+
+func readJsonGraphs[T structure.Stage, K structure.Stage](reader io.Reader) (structure.ExportHeader, []importedGraph[T, K], error) {
 	scanner := bufio.NewScanner(reader)
 	bufferSize := 2097152 // 2 MB buffer
 	buffer := make([]byte, bufferSize)
 	scanner.Buffer(buffer, bufferSize)
 
+	// files without a header are version 1
+	header := structure.ExportHeader{
+		Format:  structure.EXPORT_FORMAT_NAME,
+		Version: 1,
+	}
+
+	graphs := []importedGraph[T, K]{}
+	lineNumber := 0
+
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := scanner.Bytes()
+		lineNumber += 1
 
-		splits := strings.SplitN(line, ",", 2)
+		if lineNumber == 1 && len(line) > 0 && line[0] == '{' {
+			if err := json.Unmarshal(line, &header); err != nil {
+				return header, nil, fmt.Errorf("error decoding header: %s", err)
+			}
+
+			if err := validateExportHeader(header); err != nil {
+				return header, nil, err
+			}
+
+			continue
+		}
+
+		splits := strings.SplitN(string(line), ",", 2)
 		if len(splits) != 2 {
-			return fmt.Errorf("wrong format of line: splits %d != 2", len(splits))
+			return header, nil, fmt.Errorf("wrong format of line %d: splits %d != 2", lineNumber, len(splits))
 		}
 
 		id, err := strconv.Atoi(splits[0])
 		if err != nil {
-			return fmt.Errorf("error converting graph id: %s", err)
+			return header, nil, fmt.Errorf("error converting graph id in line %d: %s", lineNumber, err)
 		}
 
 		var graph structure.Graph[T, K]
 		err = json.Unmarshal([]byte(splits[1]), &graph)
 		if err != nil {
-			return err
+			return header, nil, fmt.Errorf("error decoding graph in line %d: %s", lineNumber, err)
+		}
+
+		graphs = append(graphs, importedGraph[T, K]{
+			id:    structure.GraphID(id),
+			graph: &graph,
+		})
+	}
+
+	return header, graphs, scanner.Err()
+}
+
+func readBinaryGraphs[T structure.Stage, K structure.Stage](reader *bufio.Reader) (structure.ExportHeader, []importedGraph[T, K], error) {
+	graphs := []importedGraph[T, K]{}
+
+	header, err := structure.ReadBinaryGraphs(reader, func(id structure.GraphID, graph *structure.Graph[T, K]) error {
+		graphs = append(graphs, importedGraph[T, K]{
+			id:    id,
+			graph: graph,
+		})
+		return nil
+	})
+
+	if err != nil {
+		return header, nil, err
+	}
+
+	return header, graphs, validateExportHeader(header)
+}
+
+// restoreGraphs adds imported graphs after all of them are validated
+func (c *RTKCSMImplementation[T, K]) restoreGraphs(header structure.ExportHeader, graphs []importedGraph[T, K]) error {
+	graphIds := map[structure.GraphID]bool{}
+	var maxGraphId structure.GraphID = 0
+
+	for _, imported := range graphs {
+		if imported.id <= 0 {
+			return fmt.Errorf("invalid graph id %d", imported.id)
+		}
+
+		if _, ok := c.graphs[imported.id]; ok || graphIds[imported.id] {
+			return fmt.Errorf("graph id %d already exists", imported.id)
 		}
 
-		graphId := structure.GraphID(id)
-		c.graphs[graphId] = &graph
+		graphIds[imported.id] = true
+		maxGraphId = max(maxGraphId, imported.id)
+	}
+
+	// changed host risks are logged, so that they are restored with the imported graphs
+	hostRisksChanged := false
+	for _, hostRisk := range header.HostRisks {
+		address := structure.ParseIPAddress(hostRisk.IpAddress)
+		riskLevel := structure.RiskLevel(hostRisk.RiskLevel)
+		if structure.HostManager.GetHostRiskLevel(address) == riskLevel {
+			continue
+		}
+
+		hostRisksChanged = true
+		c.logHostRisk(structure.WriteAheadLogAddHostRisk, address, riskLevel)
+		structure.HostManager.AddHostRiskLevel(address, riskLevel)
+	}
+
+	// merges are recorded in the order they happened, so that the history moves to the surviving graph
+	for _, merge := range header.Merges {
+		c.restoreMerge(merge)
+	}
+
+	// relevances of graphs are computed with the current stage weights
+	weightsChanged := false
+
+	for _, imported := range graphs {
+		graph := imported.graph
+
+		if header.StageWeights != nil {
+			for stage := range graph.GetStageRelevances() {
+				weight, ok := header.StageWeights[strconv.Itoa(int(stage.Serialize()))]
+				if ok && weight != stage.GetWeight() {
+					weightsChanged = true
+				}
+			}
+		}
+
+		c.graphs[imported.id] = graph
 		for _, relation := range graph.GetRelations() {
-			c.lookup.AddRelation(&relation, graphId, &graph)
+			c.lookup.AddRelation(&relation, imported.id, graph)
+		}
+		c.relationCount += graph.Len()
+
+		if graph.LastSeen().After(c.watermark) {
+			c.watermark = graph.LastSeen()
 		}
+	}
 
-		c.sortedGraphs.Insert(graphId, graph.Relevance())
+	if weightsChanged {
+		log.Println("stage weights differ from the imported graphs, recomputing relevances")
 	}
 
-	return scanner.Err()
+	for _, imported := range graphs {
+		relevance := imported.graph.Relevance()
+		if weightsChanged || hostRisksChanged {
+			relevance = imported.graph.RecomputeRelevance()
+		}
+
+		c.sortedGraphs.Insert(imported.id, relevance)
+	}
+
+	// relevances of the existing graphs depend on the changed host risks, too
+	if hostRisksChanged {
+		for id, graph := range c.graphs {
+			if !graphIds[id] {
+				c.sortedGraphs.Insert(id, graph.RecomputeRelevance())
+			}
+		}
+	}
+
+	// prevent that new graphs overwrite imported ones
+	structure.RestoreGraphID(max(header.NextGraphID, maxGraphId))
+
+	return nil
 }
 
-func (c *RTKCSMImplementation[T, K]) ExportGraphs(writer io.Writer) (int, error) {
+func (c *RTKCSMImplementation[T, K]) ExportGraphs(writer io.Writer, format structure.ExportFormat) (int, error) {
 	c.graphsMutex.RLock()
 	defer c.graphsMutex.RUnlock()
 
-	totalSize := 0
+	switch format {
+	case structure.ExportFormatJson:
+		return c.exportGraphs(writer)
+	case structure.ExportFormatBinary:
+		return structure.WriteBinaryGraphs(writer, c.exportHeader(), c.graphs)
+	default:
+		return 0, fmt.Errorf("unknown export format: %s", format)
+	}
+}
 
-	for id, graph := range c.graphs {
-		text, err := json.Marshal(graph)
-		if err != nil {
-			return totalSize, fmt.Errorf("error encoding JSON: %s", err)
-		}
+func (c *RTKCSMImplementation[T, K]) exportHeader() structure.ExportHeader {
+	header := structure.NewExportHeader()
+	header.HostRisks = c.GetHostRisks()
+	header.Merges = c.getMerges()
 
-		size, err := writer.Write(append([]byte(strconv.Itoa(int(id))+","), append(text, []byte("\n")...)...))
-		if err != nil {
-			return totalSize, fmt.Errorf("error writing JSON: %s", err)
+	for _, graph := range c.graphs {
+		for stage := range graph.GetStageRelevances() {
+			header.StageWeights[strconv.Itoa(int(stage.Serialize()))] = stage.GetWeight()
 		}
+	}
+
+	return header
+}
 
+func (c *RTKCSMImplementation[T, K]) exportGraphs(writer io.Writer) (int, error) {
+	text, err := json.Marshal(c.exportHeader())
+	if err != nil {
+		return 0, fmt.Errorf("error encoding header: %s", err)
+	}
+
+	totalSize, err := writer.Write(append(text, '\n'))
+	if err != nil {
+		return totalSize, fmt.Errorf("error writing header: %s", err)
+	}
+
+	for id, graph := range c.graphs {
+		size, err := writeGraph(writer, id, graph)
 		totalSize += size
+		if err != nil {
+			return totalSize, err
+		}
 	}
 
 	return totalSize, nil
 }
 
+func writeGraph[T structure.Stage, K structure.Stage](writer io.Writer, id structure.GraphID, graph *structure.Graph[T, K]) (int, error) {
+	text, err := json.Marshal(graph)
+	if err != nil {
+		return 0, fmt.Errorf("error encoding JSON: %s", err)
+	}
+
+	size, err := writer.Write(append([]byte(strconv.Itoa(int(id))+","), append(text, []byte("\n")...)...))
+	if err != nil {
+		return size, fmt.Errorf("error writing JSON: %s", err)
+	}
+
+	return size, nil
+}
+
 func (c *RTKCSMImplementation[T, K]) GetHostRisks() []structure.HostRisk {
 	hostRisks := []structure.HostRisk{}
 
@@ -247,20 +709,32 @@ func (c *RTKCSMImplementation[T, K]) GetHostRisks() []structure.HostRisk {
 	return hostRisks
 }
 
+// recomputeGraphRelevances expects the graphs mutex to be locked
 func (c *RTKCSMImplementation[T, K]) recomputeGraphRelevances() {
-	c.graphsMutex.Lock()
-	defer c.graphsMutex.Unlock()
 	for graphID, graph := range c.graphs {
 		c.sortedGraphs.Insert(graphID, graph.RecomputeRelevance())
 	}
+
+	c.events.Publish(structure.GraphEvent{
+		Type: structure.GraphEventReload,
+	})
 }
 
 func (c *RTKCSMImplementation[T, K]) AddHostRisk(address structure.IPAddress, riskLevel structure.RiskLevel) {
+	c.graphsMutex.Lock()
+	defer c.graphsMutex.Unlock()
+
+	// a snapshot between logging and applying the risk would lose it
+	c.logHostRisk(structure.WriteAheadLogAddHostRisk, address, riskLevel)
 	structure.HostManager.AddHostRiskLevel(address, riskLevel)
 	c.recomputeGraphRelevances()
 }
 
 func (c *RTKCSMImplementation[T, K]) DeleteHostRisk(address structure.IPAddress) {
+	c.graphsMutex.Lock()
+	defer c.graphsMutex.Unlock()
+
+	c.logHostRisk(structure.WriteAheadLogDeleteHostRisk, address, 0)
 	structure.HostManager.DeleteHostRiskLevel(address)
 	c.recomputeGraphRelevances()
 }
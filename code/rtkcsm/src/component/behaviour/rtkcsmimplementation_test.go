@@ -0,0 +1,361 @@
+package behaviour
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"rtkcsm/component/structure"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var profilerOptions = structure.NewProfilerOptions()
+
+// newTestRTKCSM creates an RTKCSM with a single worker, so alerts are correlated in the order they are added
+func newTestRTKCSM() *RTKCSMImplementation[structure.SimplifiedUKCStage, structure.UKCStage] {
+	return NewIncrementalRTKCSM(1, structure.NewSimplifiedUKCStageMapper(), structure.NewUKCStateMachine[structure.SimplifiedUKCStage](), &profilerOptions)
+}
+
+// newTestAlert creates an alert with full confidence between two hosts
+func newTestAlert(timestamp time.Time, source string, destination string, severity float32) structure.Alert {
+	return structure.Alert{
+		Timestamp:     timestamp,
+		SourceIP:      structure.ParseIPAddress(source),
+		DestinationIP: structure.ParseIPAddress(destination),
+		Severity:      severity,
+		Confidence:    1,
+	}
+}
+
+func TestSearchGraphs(t *testing.T) {
+	rtkcsm := newTestRTKCSM()
+
+	seconds := time.Now().Unix()
+	alerts := structure.Alerts{
+		// large graph with the highest relevance and the oldest activity
+		structure.Alert{
+			Timestamp:     time.Unix(seconds-100, 0),
+			SourceIP:      structure.ParseIPAddress("94.141.120.36"),
+			DestinationIP: structure.ParseIPAddress("172.16.42.42"),
+			Severity:      1,
+			Confidence:    1,
+			SignatureId:   1,
+		},
+		structure.Alert{
+			Timestamp:     time.Unix(seconds-90, 0),
+			SourceIP:      structure.ParseIPAddress("172.16.42.42"),
+			DestinationIP: structure.ParseIPAddress("218.92.0.27"),
+			Severity:      1,
+			Confidence:    1,
+			SignatureId:   2,
+			Label:         "exfiltration",
+		},
+		structure.Alert{
+			Timestamp:     time.Unix(seconds-80, 0),
+			SourceIP:      structure.ParseIPAddress("172.16.42.42"),
+			DestinationIP: structure.ParseIPAddress("172.16.42.1"),
+			Severity:      1,
+			Confidence:    1,
+			SignatureId:   3,
+		},
+		// small graph with the lowest relevance and the newest activity
+		structure.Alert{
+			Timestamp:     time.Unix(seconds-10, 0),
+			SourceIP:      structure.ParseIPAddress("94.141.120.37"),
+			DestinationIP: structure.ParseIPAddress("10.0.0.5"),
+			Severity:      0.5,
+			Confidence:    1,
+			SignatureId:   1,
+		},
+		// medium graph
+		structure.Alert{
+			Timestamp:     time.Unix(seconds-50, 0),
+			SourceIP:      structure.ParseIPAddress("94.141.120.38"),
+			DestinationIP: structure.ParseIPAddress("192.168.1.5"),
+			Severity:      0.2,
+			Confidence:    1,
+			SignatureId:   4,
+		},
+		structure.Alert{
+			Timestamp:     time.Unix(seconds-40, 0),
+			SourceIP:      structure.ParseIPAddress("192.168.1.5"),
+			DestinationIP: structure.ParseIPAddress("192.168.1.5"),
+			Severity:      0.2,
+			Confidence:    1,
+			SignatureId:   5,
+		},
+	}
+	sort.Sort(alerts)
+
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+
+	graphs := rtkcsm.GetGraphList(-1).Graphs
+	if len(graphs) != 3 {
+		t.Fatalf("created %d graphs instead of 3", len(graphs))
+	}
+	idsBySize := map[int]structure.GraphID{}
+	for _, graph := range graphs {
+		idsBySize[graph.Relations] = graph.ID
+	}
+	large, medium, small := idsBySize[3], idsBySize[2], idsBySize[1]
+
+	tests := []struct {
+		query    string
+		expected []structure.GraphID
+		count    int
+	}{
+		{"", []structure.GraphID{large, medium, small}, 3},
+		{"ip=172.16.42.1", []structure.GraphID{large}, 1},
+		{"ip=10.0.0.0/8&ip=192.168.0.0/16", []structure.GraphID{medium, small}, 2},
+		{"signature=1", []structure.GraphID{large, small}, 2},
+		{"signature=1&ip=10.0.0.5", []structure.GraphID{small}, 1},
+		{"stage=X", []structure.GraphID{medium}, 1},
+		{"stage=lateral%20movement&stage=Exfiltration", []structure.GraphID{large}, 1},
+		{"label=exfiltration", []structure.GraphID{large}, 1},
+		{"label=unknown", []structure.GraphID{}, 0},
+		{fmt.Sprintf("from=%d", (seconds-60)*1000), []structure.GraphID{medium, small}, 2},
+		{fmt.Sprintf("to=%s", time.Unix(seconds-60, 0).Format(time.RFC3339)), []structure.GraphID{large}, 1},
+		{"min_relevance=0.06", []structure.GraphID{large, medium}, 2},
+		{"min_relations=2&max_relations=2", []structure.GraphID{medium}, 1},
+		{"sort=last_seen", []structure.GraphID{small, medium, large}, 3},
+		{"sort=size", []structure.GraphID{large, medium, small}, 3},
+		{"sort=size&page_size=2", []structure.GraphID{large, medium}, 3},
+		{"sort=size&page_size=2&page=1", []structure.GraphID{small}, 3},
+		{"page_size=1&page=5", []structure.GraphID{}, 3},
+		{"status=closed", []structure.GraphID{}, 0},
+	}
+
+	for _, test := range tests {
+		values, _ := url.ParseQuery(test.query)
+		query, err := structure.ParseGraphQuery(values)
+		if err != nil {
+			t.Errorf("%s: %s", test.query, err)
+			continue
+		}
+
+		result := rtkcsm.SearchGraphs(query)
+		ids := []structure.GraphID{}
+		for _, graph := range result.Graphs {
+			ids = append(ids, graph.ID)
+		}
+
+		if !reflect.DeepEqual(ids, test.expected) || result.Count != test.count {
+			t.Errorf("%s: got graphs %v of %d, expected %v of %d", test.query, ids, result.Count, test.expected, test.count)
+		}
+	}
+
+	for _, query := range []string{"ip=172.16.42", "signature=-1", "stage=unknown", "sort=name", "page_size=0", "from=yesterday", "status=resolved"} {
+		values, _ := url.ParseQuery(query)
+		if _, err := structure.ParseGraphQuery(values); err == nil {
+			t.Errorf("invalid query %s was accepted", query)
+		}
+	}
+}
+
+func TestImportedHostRisks(t *testing.T) {
+	folder := t.TempDir()
+	host := structure.ParseIPAddress("172.16.42.42")
+	defer structure.HostManager.DeleteHostRiskLevel(host)
+
+	rtkcsm := newTestRTKCSM()
+	if err := rtkcsm.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	rtkcsm.AddAlert(structure.Alert{
+		Timestamp:     time.Unix(time.Now().Unix(), 0),
+		SourceIP:      structure.ParseIPAddress("94.141.120.36"),
+		DestinationIP: host,
+		Severity:      1,
+		Confidence:    1,
+	})
+	relevance := rtkcsm.GetGraphList(-1).Graphs[0].Relevance
+
+	header := structure.NewExportHeader()
+	header.HostRisks = []structure.HostRisk{{IpAddress: host.String(), RiskLevel: float32(structure.HighRisk)}}
+	export, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rtkcsm.ImportGraphs(bytes.NewReader(export)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the existing graph is ranked with the imported host risk
+	if importedRelevance := rtkcsm.GetGraphList(-1).Graphs[0].Relevance; importedRelevance <= relevance {
+		t.Errorf("relevance %.2f did not increase with the imported host risk (%.2f before)", importedRelevance, relevance)
+	}
+
+	// the imported host risk is restored from the write-ahead log
+	structure.HostManager.DeleteHostRiskLevel(host)
+	restoredRTKCSM := newTestRTKCSM()
+	if err := restoredRTKCSM.EnablePersistence(folder, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer restoredRTKCSM.ClosePersistence()
+
+	if riskLevel := structure.HostManager.GetHostRiskLevel(host); riskLevel != structure.HighRisk {
+		t.Errorf("restored host risk %.2f instead of %.2f", riskLevel, structure.HighRisk)
+	}
+}
+
+func TestGraphImportExport(t *testing.T) {
+	rtkcsm := newTestRTKCSM()
+
+	seconds := time.Now().Unix()
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-2, 0), "94.141.120.36", "172.16.42.42", 1))
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-1, 0), "172.16.42.42", "172.16.42.1", 1))
+
+	export := bytes.Buffer{}
+	if _, err := rtkcsm.ExportGraphs(&export, structure.ExportFormatJson); err != nil {
+		t.Fatal(err)
+	}
+
+	header, graphLines, _ := strings.Cut(export.String(), "\n")
+	if !strings.Contains(header, fmt.Sprintf(`"version":%d`, structure.EXPORT_FORMAT_VERSION)) {
+		t.Errorf("export header is missing: %s", header)
+	}
+
+	importedRTKCSM := newTestRTKCSM()
+	if err := importedRTKCSM.ImportGraphs(strings.NewReader(export.String())); err != nil {
+		t.Fatal(err)
+	}
+
+	graph := rtkcsm.GetGraphList(-1).Graphs[0]
+	importedGraph := importedRTKCSM.GetGraphList(-1).Graphs[0]
+	if graph != importedGraph {
+		t.Errorf("imported graph %v differs from %v", importedGraph, graph)
+	}
+
+	if structure.CurrentGraphID() < graph.ID {
+		t.Errorf("graph id counter %d is smaller than imported graph id %d", structure.CurrentGraphID(), graph.ID)
+	}
+
+	// graph ids must not be overwritten
+	if err := importedRTKCSM.ImportGraphs(strings.NewReader(export.String())); err == nil {
+		t.Errorf("importing an existing graph id succeeded")
+	}
+
+	// version 1 files have no header
+	legacyRTKCSM := newTestRTKCSM()
+	if err := legacyRTKCSM.ImportGraphs(strings.NewReader(graphLines)); err != nil {
+		t.Fatal(err)
+	}
+
+	if legacyGraph := legacyRTKCSM.GetGraphList(-1).Graphs[0]; graph != legacyGraph {
+		t.Errorf("imported graph %v differs from %v", legacyGraph, graph)
+	}
+
+	if err := legacyRTKCSM.ImportGraphs(strings.NewReader(`{"format":"rtkcsm-graphs","version":99}`)); err == nil {
+		t.Errorf("importing an unsupported version succeeded")
+	}
+}
+
+func TestBinaryGraphExport(t *testing.T) {
+	seconds := time.Now().Unix()
+
+	alerts := structure.Alerts{
+		structure.Alert{
+			Timestamp:     time.Unix(seconds-5, 0),
+			SourceIP:      structure.ParseIPAddress("94.141.120.36"),
+			DestinationIP: structure.ParseIPAddress("172.16.42.42"),
+			Severity:      1,
+			Confidence:    1,
+			SignatureId:   2001,
+			Label:         "exploit",
+		},
+		structure.Alert{
+			Timestamp:     time.Unix(seconds-4, 0),
+			SourceIP:      structure.ParseIPAddress("94.141.120.36"),
+			DestinationIP: structure.ParseIPAddress("172.16.42.42"),
+			Severity:      1,
+			Confidence:    1,
+			SignatureId:   2001,
+			Label:         "scan",
+		},
+		newTestAlert(time.Unix(seconds-3, 0), "172.16.42.42", "2001:db8::1", 0.5),
+		structure.Alert{
+			Timestamp:     time.Unix(seconds-2, 0),
+			SourceIP:      structure.ParseIPAddress("172.16.42.42"),
+			DestinationIP: structure.ParseIPAddress("172.16.42.1"),
+			Severity:      1,
+			Confidence:    0.5,
+		},
+		newTestAlert(time.Unix(seconds-1, 0), "94.141.120.37", "172.16.42.43", 1),
+	}
+
+	rtkcsm := newTestRTKCSM()
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+
+	jsonExport := bytes.Buffer{}
+	if _, err := rtkcsm.ExportGraphs(&jsonExport, structure.ExportFormatJson); err != nil {
+		t.Fatal(err)
+	}
+
+	binaryExport := bytes.Buffer{}
+	if _, err := rtkcsm.ExportGraphs(&binaryExport, structure.ExportFormatBinary); err != nil {
+		t.Fatal(err)
+	}
+
+	if binaryExport.Len() >= jsonExport.Len() {
+		t.Errorf("binary export (%d bytes) is not smaller than JSON export (%d bytes)", binaryExport.Len(), jsonExport.Len())
+	}
+
+	jsonRTKCSM := newTestRTKCSM()
+	if err := jsonRTKCSM.ImportGraphs(&jsonExport); err != nil {
+		t.Fatal(err)
+	}
+
+	binaryRTKCSM := newTestRTKCSM()
+	if err := binaryRTKCSM.ImportGraphs(bytes.NewReader(binaryExport.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	graphList := rtkcsm.GetGraphList(-1)
+	jsonGraphList := jsonRTKCSM.GetGraphList(-1)
+	binaryGraphList := binaryRTKCSM.GetGraphList(-1)
+
+	if !reflect.DeepEqual(jsonGraphList, graphList) || !reflect.DeepEqual(binaryGraphList, graphList) {
+		t.Fatalf("imported graph lists differ: json %v, binary %v, expected %v", jsonGraphList, binaryGraphList, graphList)
+	}
+
+	alertCount := 0
+	for _, graph := range graphList.Graphs {
+		jsonGraph := jsonRTKCSM.GetGraph(graph.ID).GetPreComputed()
+		binaryGraph := binaryRTKCSM.GetGraph(graph.ID).GetPreComputed()
+
+		if !reflect.DeepEqual(jsonGraph, binaryGraph) {
+			t.Errorf("binary import of graph %d differs from JSON import: %v != %v", graph.ID, binaryGraph, jsonGraph)
+		}
+
+		for _, relation := range binaryGraph.PreComputedDirectedRelations {
+			alertCount += relation.Count
+		}
+	}
+
+	// the repeated alert is restored with its count
+	if alertCount != len(alerts) {
+		t.Errorf("imported relations count %d alerts instead of %d", alertCount, len(alerts))
+	}
+
+	// a truncated file must not change the state, also if it ends after a length prefix or a graph
+	for length := len(structure.BINARY_EXPORT_MAGIC); length < binaryExport.Len(); length++ {
+		truncatedRTKCSM := newTestRTKCSM()
+		if err := truncatedRTKCSM.ImportGraphs(bytes.NewReader(binaryExport.Bytes()[:length])); err == nil {
+			t.Errorf("importing a binary export truncated to %d of %d bytes succeeded", length, binaryExport.Len())
+		}
+
+		if count := truncatedRTKCSM.GetGraphList(-1).Count; count != 0 {
+			t.Errorf("binary export truncated to %d bytes imported %d graphs", length, count)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package structure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const DEFAULT_ALERT_RULE_POLL_INTERVAL = 5 * time.Second
+
+// AlertRule fires once per graph when all of its conditions are met, unset conditions are ignored
+type AlertRule struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// the relevance of the graph reaches this value
+	MinRelevance float32 `json:"min_relevance,omitempty"`
+	MinRelations int     `json:"min_relations,omitempty"`
+	// stages that are reached in this order, names of the stage mapper (e.g. "incoming", "outgoing") or
+	// of the unified kill chain (e.g. "R", "Exfiltration")
+	Sequence []string `json:"sequence,omitempty"`
+	// a host of the graph has at least this risk level, e.g. 1.5 for high risk hosts
+	MinHostRisk RiskLevel `json:"min_host_risk,omitempty"`
+	// relations between internal hosts touch this number of zones, addresses outside of all zones
+	// belong to the zone of their subnet
+	MinLateralZones int `json:"min_lateral_zones,omitempty"`
+}
+
+type AlertRuleFile struct {
+	Rules []AlertRule `json:"rules"`
+}
+
+// ParseAlertRules reads a rule file: {"rules": [{"name": "exfiltration", "sequence": ["incoming", "outgoing"]}]}
+func ParseAlertRules(reader io.Reader) ([]AlertRule, error) {
+	var file AlertRuleFile
+
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid alert rules: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, rule := range file.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("alert rule without name")
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate alert rule: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if rule.MinRelevance <= 0 && rule.MinRelations <= 0 && len(rule.Sequence) == 0 && rule.MinHostRisk <= 0 && rule.MinLateralZones <= 0 {
+			return nil, fmt.Errorf("alert rule %s has no conditions", rule.Name)
+		}
+
+		for _, name := range rule.Sequence {
+			if _, err := ParseUKCStage(name); err != nil && NewSimplifiedUKCStageFromString(strings.ToLower(name)) == None {
+				return nil, fmt.Errorf("alert rule %s: unknown stage: %s", rule.Name, name)
+			}
+		}
+	}
+
+	return file.Rules, nil
+}
+
+// AlertNotification is sent when a graph matches a rule for the first time
+type AlertNotification struct {
+	Rule        string    `json:"rule"`
+	Description string    `json:"description,omitempty"`
+	GraphID     GraphID   `json:"graph_id"`
+	Relevance   float32   `json:"relevance"`
+	Relations   int       `json:"relations"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+type Notifier interface {
+	Notify(notification AlertNotification) error
+}
+
+// LogNotifier writes notifications to the log
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(notification AlertNotification) error {
+	log.Printf("alert rule %s matched graph %d (relevance %.2f, %d relations)", notification.Rule, notification.GraphID, notification.Relevance, notification.Relations)
+	return nil
+}
+
+// AlertRuleWatcher loads a rule file again when its modification time changes
+type AlertRuleWatcher struct {
+	Path         string
+	PollInterval time.Duration
+	modified     time.Time
+}
+
+func NewAlertRuleWatcher(path string) *AlertRuleWatcher {
+	return &AlertRuleWatcher{
+		Path:         filepath.Clean(path),
+		PollInterval: DEFAULT_ALERT_RULE_POLL_INTERVAL,
+	}
+}
+
+// Load returns the rules if the file changed since the last call
+func (w *AlertRuleWatcher) Load() ([]AlertRule, bool, error) {
+	info, err := os.Stat(w.Path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if info.ModTime().Equal(w.modified) {
+		return nil, false, nil
+	}
+
+	file, err := os.Open(w.Path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	rules, err := ParseAlertRules(file)
+	if err != nil {
+		return nil, false, err
+	}
+
+	w.modified = info.ModTime()
+	return rules, true, nil
+}
+
+// Watch applies changed rules until stop is closed. Invalid files are logged and the previous rules are kept.
+func (w *AlertRuleWatcher) Watch(apply func([]AlertRule), stop <-chan struct{}) {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			rules, changed, err := w.Load()
+			if err != nil {
+				log.Printf("error reloading alert rules: %s", err)
+			} else if changed {
+				log.Printf("reloaded %d alert rules from %s", len(rules), w.Path)
+				apply(rules)
+			}
+		}
+	}
+}
+
+// AlertRuleState keeps the conditions of rules that depend on all relations of a graph, so that new
+// relations update them instead of evaluating all relations again
+type AlertRuleState[T Stage] struct {
+	sequences map[string]int  // reached stages of the sequence of a rule
+	zones     map[string]bool // zones of relations between internal hosts
+	latest    time.Time       // timestamp of the latest relation
+}
+
+// NewAlertRuleState evaluates all relations of a graph once, e.g. after a merge or a change of the rules
+func NewAlertRuleState[T Stage, K Stage](graph *Graph[T, K], rules []AlertRule) *AlertRuleState[T] {
+	state := &AlertRuleState[T]{
+		sequences: map[string]int{},
+		zones:     map[string]bool{},
+	}
+
+	// the earliest relation of a stage is used, so that the remaining stages can match as many relations as possible
+	relations := graph.GetRelations()
+	sort.Slice(relations, func(i, j int) bool {
+		return relations[i].Timestamp.Before(relations[j].Timestamp)
+	})
+
+	for _, relation := range relations {
+		state.Add(relation, rules)
+	}
+
+	return state
+}
+
+// Add updates the state with a new relation of the graph. Stages of a sequence are reached in the
+// order of the timestamps, so a relation before the latest one (e.g. of a late alert) is not added
+// and false is returned. The state has to be evaluated again with NewAlertRuleState in this case.
+func (s *AlertRuleState[T]) Add(relation DirectedRelation[T], rules []AlertRule) bool {
+	if relation.Timestamp.Before(s.latest) {
+		return false
+	}
+	s.latest = relation.Timestamp
+
+	for _, rule := range rules {
+		next := s.sequences[rule.Name]
+		if next < len(rule.Sequence) && matchesStageName(relation.MetaStage, rule.Sequence[next]) {
+			s.sequences[rule.Name] = next + 1
+		}
+	}
+
+	src := relation.SrcNode
+	dst := relation.DstNode
+	if src.IsInternal() && dst.IsInternal() && !src.Equal(dst) {
+		s.zones[zoneOrSubnet(src)] = true
+		s.zones[zoneOrSubnet(dst)] = true
+	}
+
+	return true
+}
+
+// MatchesRule checks all conditions of a rule
+func (g *Graph[T, K]) MatchesRule(rule AlertRule, state *AlertRuleState[T]) bool {
+	g.relationsMutex.RLock()
+	defer g.relationsMutex.RUnlock()
+
+	if g.ComputedRelevance < rule.MinRelevance || len(g.Relations) < rule.MinRelations {
+		return false
+	}
+
+	if rule.MinHostRisk > 0 && !g.hasHostRisk(rule.MinHostRisk) {
+		return false
+	}
+
+	if rule.MinLateralZones > 0 && len(state.zones) < rule.MinLateralZones {
+		return false
+	}
+
+	if len(rule.Sequence) > 0 && state.sequences[rule.Name] < len(rule.Sequence) {
+		return false
+	}
+
+	return true
+}
+
+// hasHostRisk expects the relations mutex to be locked
+func (g *Graph[T, K]) hasHostRisk(riskLevel RiskLevel) bool {
+	for address := range g.reverseLookup {
+		if HostManager.GetHostRiskLevel(address) >= riskLevel {
+			return true
+		}
+	}
+	return false
+}
+
+func zoneOrSubnet(address IPAddress) string {
+	if zone := address.Zone(); zone != "" {
+		return zone
+	}
+
+	ip := address.IP()
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(64, 128)).String()
+}
+
+func matchesStageName[T Stage](stage T, name string) bool {
+	if strings.EqualFold(fmt.Sprint(stage), name) {
+		return true
+	}
+
+	ukcStage, err := ParseUKCStage(name)
+	return err == nil && slices.Contains(stage.ToUKCStages(), ukcStage)
+}
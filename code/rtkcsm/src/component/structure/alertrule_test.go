@@ -0,0 +1,22 @@
+package structure
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAlertRules(t *testing.T) {
+	invalidRules := map[string]string{
+		"unknown stage":   `{"rules": [{"name": "stage", "sequence": ["incoming", "sideways"]}]}`,
+		"no conditions":   `{"rules": [{"name": "empty"}]}`,
+		"duplicate name":  `{"rules": [{"name": "a", "min_relations": 1}, {"name": "a", "min_relations": 2}]}`,
+		"missing name":    `{"rules": [{"min_relations": 1}]}`,
+		"unknown field":   `{"rules": [{"name": "a", "min_relevanc": 0.5}]}`,
+		"invalid content": `{"rules": [`,
+	}
+	for name, content := range invalidRules {
+		if _, err := ParseAlertRules(strings.NewReader(content)); err == nil {
+			t.Errorf("%s: rules were accepted", name)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package structure
+
+import (
+	"bytes"
+	"sort"
+)
+
+const DEFAULT_ATTACK_PATH_LIMIT = 3
+
+// AttackPath is a chain of relations in which every relation follows the previous one in time
+// and continues from one of its hosts in a stage that the state machine allows to follow
+type AttackPath[T Stage] struct {
+	Relevance float32                          `json:"relevance"`
+	Steps     []PreComputedDirectedRelation[T] `json:"steps"`
+}
+
+type attackPathNode[T Stage] struct {
+	id          OptimizedDirectedRelationID
+	relation    OptimizedDirectedRelation[T]
+	relevance   float32
+	predecessor int
+}
+
+// GetAttackPaths returns up to limit paths with the highest sum of relation relevances, ordered by
+// relevance. Paths end at different relations and do not end on a relation of a more relevant path.
+func (g *Graph[T, K]) GetAttackPaths(stateMachine StateMachine[T, K], limit int) []AttackPath[T] {
+	g.relationsMutex.RLock()
+	defer g.relationsMutex.RUnlock()
+
+	nodes := make([]attackPathNode[T], 0, len(g.Relations))
+	for id, relation := range g.Relations {
+		nodes = append(nodes, attackPathNode[T]{
+			id:          id,
+			relation:    relation,
+			predecessor: -1,
+		})
+	}
+
+	// equal timestamps are ordered by id, so that the order is total and a path cannot contain a cycle
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].relation.Timestamp.Equal(nodes[j].relation.Timestamp) {
+			return bytes.Compare(nodes[i].id[:], nodes[j].id[:]) < 0
+		}
+		return nodes[i].relation.Timestamp.Before(nodes[j].relation.Timestamp)
+	})
+
+	// the most relevant path reaching a stage on a host so far, like the lookup table during correlation
+	best := map[LookupEntry]int{}
+
+	for i := range nodes {
+		node := &nodes[i]
+		src := node.id.GetSrc()
+		dst := node.id.GetDst()
+
+		if src.IsInternal() {
+			for _, precedingStage := range stateMachine.GetPrecedingStages(node.relation.MetaStage) {
+				if predecessor, ok := best[NewLookupEntry(src, precedingStage)]; ok {
+					if node.predecessor < 0 || nodes[predecessor].relevance > nodes[node.predecessor].relevance {
+						node.predecessor = predecessor
+					}
+				}
+			}
+		}
+
+		node.relevance = node.relation.Relevance(node.id)
+		if node.predecessor >= 0 {
+			node.relevance += nodes[node.predecessor].relevance
+		}
+
+		for _, address := range []IPAddress{src, dst} {
+			if !address.IsInternal() {
+				continue
+			}
+
+			for _, stage := range stateMachine.GetCurrentStateStages(node.relation.MetaStage) {
+				entry := NewLookupEntry(address, stage)
+				if previous, ok := best[entry]; !ok || node.relevance > nodes[previous].relevance {
+					best[entry] = i
+				}
+			}
+		}
+	}
+
+	ends := make([]int, len(nodes))
+	for i := range nodes {
+		ends[i] = i
+	}
+	sort.SliceStable(ends, func(i, j int) bool {
+		return nodes[ends[i]].relevance > nodes[ends[j]].relevance
+	})
+
+	paths := []AttackPath[T]{}
+	onPath := make([]bool, len(nodes))
+
+	for _, end := range ends {
+		if len(paths) >= limit {
+			break
+		}
+
+		if onPath[end] {
+			continue
+		}
+
+		steps := []PreComputedDirectedRelation[T]{}
+		for i := end; i >= 0; i = nodes[i].predecessor {
+			onPath[i] = true
+
+			node := nodes[i]
+			stages := node.relation.getConfirmedStages(g.reverseLookup[node.id.GetSrc()].HasLateralMovement, g.reverseLookup[node.id.GetDst()].HasOutgoingActivity)
+			steps = append(steps, node.relation.GetPreComputed(stages, node.relation.Count, node.id))
+		}
+
+		for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+			steps[i], steps[j] = steps[j], steps[i]
+		}
+
+		paths = append(paths, AttackPath[T]{
+			Relevance: nodes[end].relevance,
+			Steps:     steps,
+		})
+	}
+
+	return paths
+}
@@ -0,0 +1,103 @@
+package structure
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAttackPaths(t *testing.T) {
+	stageMapper := NewSimplifiedUKCStageMapper()
+	stateMachine := NewUKCStateMachine[SimplifiedUKCStage]()
+	graph := NewGraph[SimplifiedUKCStage, UKCStage]()
+
+	seconds := time.Now().Unix()
+	alerts := Alerts{
+		// outgoing activity before the host was reached starts its own path
+		Alert{
+			Timestamp:     time.Unix(seconds-11, 0),
+			SourceIP:      ParseIPAddress("172.16.42.1"),
+			DestinationIP: ParseIPAddress("218.92.0.99"),
+			Severity:      1,
+			Confidence:    1,
+		},
+		Alert{
+			Timestamp:     time.Unix(seconds-10, 0),
+			SourceIP:      ParseIPAddress("94.141.120.36"),
+			DestinationIP: ParseIPAddress("172.16.42.42"),
+			Severity:      1,
+			Confidence:    1,
+		},
+		Alert{
+			Timestamp:     time.Unix(seconds-9, 0),
+			SourceIP:      ParseIPAddress("172.16.42.42"),
+			DestinationIP: ParseIPAddress("172.16.42.1"),
+			Severity:      1,
+			Confidence:    1,
+		},
+		Alert{
+			Timestamp:     time.Unix(seconds-8, 0),
+			SourceIP:      ParseIPAddress("172.16.42.1"),
+			DestinationIP: ParseIPAddress("218.92.0.27"),
+			Severity:      1,
+			Confidence:    1,
+		},
+		Alert{
+			Timestamp:     time.Unix(seconds-7, 0),
+			SourceIP:      ParseIPAddress("172.16.42.42"),
+			DestinationIP: ParseIPAddress("218.92.0.28"),
+			Severity:      0.2,
+			Confidence:    1,
+		},
+	}
+
+	for _, alert := range alerts {
+		stage, err := stageMapper.DetermineStage(alert)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		graph.Append(EnrichedAlert[SimplifiedUKCStage]{
+			Alert:     alert,
+			MetaStage: stage,
+		})
+	}
+
+	paths := graph.GetAttackPaths(stateMachine, 5)
+
+	// the second path continues from the first compromised host after the lateral movement
+	expected := [][]string{
+		{"94.141.120.36>172.16.42.42", "172.16.42.42>172.16.42.1", "172.16.42.1>218.92.0.27"},
+		{"94.141.120.36>172.16.42.42", "172.16.42.42>172.16.42.1", "172.16.42.42>218.92.0.28"},
+		{"172.16.42.1>218.92.0.99"},
+	}
+	expectedRelevances := []string{"3.0000", "2.2000", "1.0000"}
+
+	if len(paths) != len(expected) {
+		t.Fatalf("got %d paths instead of %d", len(paths), len(expected))
+	}
+
+	for i, path := range paths {
+		steps := []string{}
+		for _, step := range path.Steps {
+			steps = append(steps, step.From+">"+step.To)
+		}
+
+		if !reflect.DeepEqual(steps, expected[i]) {
+			t.Errorf("path %d has steps %v, expected %v", i, steps, expected[i])
+		}
+
+		if relevance := fmt.Sprintf("%.4f", path.Relevance); relevance != expectedRelevances[i] {
+			t.Errorf("path %d has relevance %s instead of %s", i, relevance, expectedRelevances[i])
+		}
+	}
+
+	if stages := paths[0].Steps[0].ConfirmedStages; !reflect.DeepEqual(stages, []UKCStage{R, D1}) {
+		t.Errorf("first step has stages %v", stages)
+	}
+
+	if paths := graph.GetAttackPaths(stateMachine, 1); len(paths) != 1 || len(paths[0].Steps) != 3 {
+		t.Errorf("limited paths %v", paths)
+	}
+}
@@ -0,0 +1,545 @@
+package structure
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// Binary export format:
+//
+//	magic, header (JSON), stage dictionary (JSON values), label dictionary, cause dictionary
+//	graph records, each prefixed with its length:
+//	  id, relevance, stage relevances, host flags, relations, incident
+//	relation: relation id (46 bytes), stage, timestamp delta (ns), count, labels, cause
+//	end marker (an empty record) and the number of graph records, so that truncated files are detected
+//
+// Integers are varints, strings and dictionaries are length-prefixed and floats are big endian float32.
+var BINARY_EXPORT_MAGIC = []byte("RTKCSM\x00B")
+
+const binaryHostLateralMovement = 0b0000_0001
+const binaryHostOutgoingActivity = 0b0000_0010
+
+// Upper bound of a graph record to reject corrupt length prefixes before allocating memory
+const BINARY_EXPORT_MAX_RECORD_SIZE = 1 << 30
+
+func IsBinaryExport(prefix []byte) bool {
+	return bytes.HasPrefix(prefix, BINARY_EXPORT_MAGIC)
+}
+
+type binaryWriter struct {
+	writer *bufio.Writer
+	size   int
+	err    error
+	buffer [binary.MaxVarintLen64]byte
+}
+
+func (w *binaryWriter) write(data []byte) {
+	if w.err != nil {
+		return
+	}
+
+	n, err := w.writer.Write(data)
+	w.size += n
+	w.err = err
+}
+
+func (w *binaryWriter) uvarint(value uint64) {
+	w.write(w.buffer[:binary.PutUvarint(w.buffer[:], value)])
+}
+
+func (w *binaryWriter) bytes(data []byte) {
+	w.uvarint(uint64(len(data)))
+	w.write(data)
+}
+
+func appendFloat32(buffer []byte, value float32) []byte {
+	return binary.BigEndian.AppendUint32(buffer, math.Float32bits(value))
+}
+
+type binaryDictionary struct {
+	indices map[string]uint64
+	entries []string
+}
+
+func newBinaryDictionary() *binaryDictionary {
+	return &binaryDictionary{
+		indices: map[string]uint64{},
+		entries: []string{},
+	}
+}
+
+func (d *binaryDictionary) add(entry string) uint64 {
+	index, ok := d.indices[entry]
+	if !ok {
+		index = uint64(len(d.entries))
+		d.indices[entry] = index
+		d.entries = append(d.entries, entry)
+	}
+
+	return index
+}
+
+// WriteBinaryGraphs writes graphs in the binary export format and returns the number of written bytes
+func WriteBinaryGraphs[T Stage, K Stage](writer io.Writer, header ExportHeader, graphs map[GraphID]*Graph[T, K]) (int, error) {
+	output := &binaryWriter{
+		writer: bufio.NewWriter(writer),
+	}
+
+	stages := newBinaryDictionary()
+	causes := newBinaryDictionary()
+
+	ids := make([]GraphID, 0, len(graphs))
+	for id, graph := range graphs {
+		ids = append(ids, id)
+
+		graph.relationsMutex.RLock()
+		for _, relation := range graph.Relations {
+			stage, err := json.Marshal(relation.MetaStage)
+			if err != nil {
+				graph.relationsMutex.RUnlock()
+				return 0, err
+			}
+			stages.add(string(stage))
+			causes.add(relation.Cause)
+		}
+		for stage := range graph.relevances {
+			encodedStage, err := json.Marshal(stage)
+			if err != nil {
+				graph.relationsMutex.RUnlock()
+				return 0, err
+			}
+			stages.add(string(encodedStage))
+		}
+		graph.relationsMutex.RUnlock()
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// bit i of relation labels refers to entry i
+	labels := make([]string, len(allLabels))
+	for label, bit := range allLabels {
+		labels[bits.TrailingZeros64(bit)] = label
+	}
+
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return 0, err
+	}
+
+	output.write(BINARY_EXPORT_MAGIC)
+	output.bytes(encodedHeader)
+
+	for _, dictionary := range [][]string{stages.entries, labels, causes.entries} {
+		output.uvarint(uint64(len(dictionary)))
+		for _, entry := range dictionary {
+			output.bytes([]byte(entry))
+		}
+	}
+
+	for _, id := range ids {
+		record, err := encodeBinaryGraph(id, graphs[id], stages, causes)
+		if err != nil {
+			return output.size, err
+		}
+
+		output.bytes(record)
+	}
+
+	output.bytes(nil)
+	output.uvarint(uint64(len(ids)))
+
+	if output.err == nil {
+		output.err = output.writer.Flush()
+	}
+
+	return output.size, output.err
+}
+
+func encodeBinaryGraph[T Stage, K Stage](id GraphID, graph *Graph[T, K], stages *binaryDictionary, causes *binaryDictionary) ([]byte, error) {
+	graph.relationsMutex.RLock()
+	defer graph.relationsMutex.RUnlock()
+
+	record := binary.AppendVarint([]byte{}, int64(id))
+	record = appendFloat32(record, graph.ComputedRelevance)
+
+	record = binary.AppendUvarint(record, uint64(len(graph.relevances)))
+	for stage, relevance := range graph.relevances {
+		encodedStage, err := json.Marshal(stage)
+		if err != nil {
+			return nil, err
+		}
+
+		record = binary.AppendUvarint(record, stages.add(string(encodedStage)))
+		record = appendFloat32(record, relevance)
+	}
+
+	record = binary.AppendUvarint(record, uint64(len(graph.reverseLookup)))
+	for address, entry := range graph.reverseLookup {
+		flags := byte(0)
+		if entry.HasLateralMovement {
+			flags |= binaryHostLateralMovement
+		}
+		if entry.HasOutgoingActivity {
+			flags |= binaryHostOutgoingActivity
+		}
+
+		record = append(record, address[:]...)
+		record = append(record, flags)
+	}
+
+	// sorted relations keep timestamp deltas small
+	relationIds := make([]OptimizedDirectedRelationID, 0, len(graph.Relations))
+	for relationId := range graph.Relations {
+		relationIds = append(relationIds, relationId)
+	}
+	sort.Slice(relationIds, func(i, j int) bool {
+		a := graph.Relations[relationIds[i]].Timestamp
+		b := graph.Relations[relationIds[j]].Timestamp
+		if a.Equal(b) {
+			return bytes.Compare(relationIds[i][:], relationIds[j][:]) < 0
+		}
+		return a.Before(b)
+	})
+
+	record = binary.AppendUvarint(record, uint64(len(relationIds)))
+	previousTimestamp := int64(0)
+	for _, relationId := range relationIds {
+		relation := graph.Relations[relationId]
+
+		encodedStage, err := json.Marshal(relation.MetaStage)
+		if err != nil {
+			return nil, err
+		}
+
+		timestamp := relation.Timestamp.UnixNano()
+
+		record = append(record, relationId[:]...)
+		record = binary.AppendUvarint(record, stages.add(string(encodedStage)))
+		record = binary.AppendVarint(record, timestamp-previousTimestamp)
+		record = binary.AppendUvarint(record, uint64(relation.Count))
+		record = binary.AppendUvarint(record, relation.Labels)
+		record = binary.AppendUvarint(record, causes.add(relation.Cause))
+
+		previousTimestamp = timestamp
+	}
+
+	encodedIncident, err := json.Marshal(graph.incident)
+	if err != nil {
+		return nil, err
+	}
+
+	record = binary.AppendUvarint(record, uint64(len(encodedIncident)))
+	record = append(record, encodedIncident...)
+
+	return record, nil
+}
+
+type binaryReader struct {
+	reader *bytes.Reader
+}
+
+func (r *binaryReader) uvarint() (uint64, error) {
+	return binary.ReadUvarint(r.reader)
+}
+
+func (r *binaryReader) varint() (int64, error) {
+	return binary.ReadVarint(r.reader)
+}
+
+func (r *binaryReader) float32() (float32, error) {
+	buffer := [4]byte{}
+	if _, err := io.ReadFull(r.reader, buffer[:]); err != nil {
+		return 0, err
+	}
+
+	return math.Float32frombits(binary.BigEndian.Uint32(buffer[:])), nil
+}
+
+func (r *binaryReader) index(length int) (int, error) {
+	index, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+
+	if index >= uint64(length) {
+		return 0, fmt.Errorf("dictionary index %d out of range (%d entries)", index, length)
+	}
+
+	return int(index), nil
+}
+
+func readBinaryBytes(reader *bufio.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if length > BINARY_EXPORT_MAX_RECORD_SIZE {
+		return nil, fmt.Errorf("record length %d exceeds limit", length)
+	}
+
+	// only the end of the file before a length prefix can be the end of the export
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func readBinaryDictionary(reader *bufio.Reader) ([]string, error) {
+	count, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []string{}
+	for range count {
+		entry, err := readBinaryBytes(reader)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, string(entry))
+	}
+
+	return entries, nil
+}
+
+// ReadBinaryGraphs reads the binary export format and calls add for every graph
+func ReadBinaryGraphs[T Stage, K Stage](reader *bufio.Reader, add func(id GraphID, graph *Graph[T, K]) error) (ExportHeader, error) {
+	header := ExportHeader{}
+
+	magic := make([]byte, len(BINARY_EXPORT_MAGIC))
+	if _, err := io.ReadFull(reader, magic); err != nil || !IsBinaryExport(magic) {
+		return header, fmt.Errorf("not a binary export")
+	}
+
+	encodedHeader, err := readBinaryBytes(reader)
+	if err != nil {
+		return header, fmt.Errorf("error reading header: %s", err)
+	}
+
+	if err := json.Unmarshal(encodedHeader, &header); err != nil {
+		return header, fmt.Errorf("error decoding header: %s", err)
+	}
+
+	dictionaries := [3][]string{}
+	for i := range dictionaries {
+		dictionaries[i], err = readBinaryDictionary(reader)
+		if err != nil {
+			return header, fmt.Errorf("error reading dictionary: %s", err)
+		}
+	}
+
+	stages := []T{}
+	for _, encodedStage := range dictionaries[0] {
+		var stage T
+		if err := json.Unmarshal([]byte(encodedStage), &stage); err != nil {
+			return header, fmt.Errorf("error decoding stage %s: %s", encodedStage, err)
+		}
+		stages = append(stages, stage)
+	}
+
+	labels := dictionaries[1]
+	causes := dictionaries[2]
+
+	graphCount := uint64(0)
+	for {
+		record, err := readBinaryBytes(reader)
+		if errors.Is(err, io.EOF) {
+			// exports before version 4 end after the last graph
+			if header.Version < 4 {
+				return header, nil
+			}
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return header, fmt.Errorf("error reading graph: %s", err)
+		}
+
+		if len(record) == 0 {
+			expectedCount, err := binary.ReadUvarint(reader)
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return header, fmt.Errorf("error reading number of graphs: %s", err)
+			}
+
+			if graphCount != expectedCount {
+				return header, fmt.Errorf("read %d of %d graphs", graphCount, expectedCount)
+			}
+			return header, nil
+		}
+
+		id, graph, err := decodeBinaryGraph[T, K](record, stages, labels, causes)
+		if err != nil {
+			return header, fmt.Errorf("error decoding graph: %s", err)
+		}
+
+		if err := add(id, graph); err != nil {
+			return header, err
+		}
+		graphCount += 1
+	}
+}
+
+func decodeBinaryGraph[T Stage, K Stage](record []byte, stages []T, labels []string, causes []string) (GraphID, *Graph[T, K], error) {
+	r := &binaryReader{
+		reader: bytes.NewReader(record),
+	}
+
+	id, err := r.varint()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	computedRelevance, err := r.float32()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	count, err := r.uvarint()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	relevances := map[T]float32{}
+	for range count {
+		stage, err := r.index(len(stages))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		relevance, err := r.float32()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		relevances[stages[stage]] = relevance
+	}
+
+	count, err = r.uvarint()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	hosts := map[IPAddress]GraphHostJson{}
+	for range count {
+		address := IPAddress{}
+		if _, err := io.ReadFull(r.reader, address[:]); err != nil {
+			return 0, nil, err
+		}
+
+		flags, err := r.reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		// the internal flag depends on the current zone configuration
+		hosts[ParseIPAddress(address.String())] = GraphHostJson{
+			HasLateralMovement:  flags&binaryHostLateralMovement != 0,
+			HasOutgoingActivity: flags&binaryHostOutgoingActivity != 0,
+		}
+	}
+
+	count, err = r.uvarint()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	graph := NewGraph[T, K]()
+	timestamp := int64(0)
+
+	for range count {
+		relationId := OptimizedDirectedRelationID{}
+		if _, err := io.ReadFull(r.reader, relationId[:]); err != nil {
+			return 0, nil, err
+		}
+
+		stage, err := r.index(len(stages))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		delta, err := r.varint()
+		if err != nil {
+			return 0, nil, err
+		}
+		timestamp += delta
+
+		relationCount, err := r.uvarint()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		labelBits, err := r.uvarint()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		cause, err := r.index(len(causes))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		relationLabels := []string{}
+		for i, label := range labels {
+			if labelBits&(1<<i) != 0 {
+				relationLabels = append(relationLabels, label)
+			}
+		}
+
+		graph.append(DirectedRelation[T]{
+			SrcNode:     ParseIPAddress(relationId.GetSrc().String()),
+			DstNode:     ParseIPAddress(relationId.GetDst().String()),
+			Timestamp:   time.Unix(0, timestamp),
+			MetaStage:   stages[stage],
+			Severity:    relationId.Severity(),
+			Confidence:  relationId.Confidence(),
+			SignatureId: relationId.GetSignatureId(),
+			Cause:       causes[cause],
+			Labels:      relationLabels,
+		}, int(max(relationCount, 1)))
+	}
+
+	// graphs before version 3 end after the relations
+	if r.reader.Len() != 0 {
+		length, err := r.uvarint()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if length > uint64(r.reader.Len()) {
+			return 0, nil, fmt.Errorf("incident of graph %d is longer than the record", id)
+		}
+
+		encodedIncident := make([]byte, length)
+		if _, err := io.ReadFull(r.reader, encodedIncident); err != nil {
+			return 0, nil, err
+		}
+
+		if err := json.Unmarshal(encodedIncident, &graph.incident); err != nil {
+			return 0, nil, fmt.Errorf("error decoding incident of graph %d: %s", id, err)
+		}
+	}
+
+	if r.reader.Len() != 0 {
+		return 0, nil, fmt.Errorf("%d unexpected bytes after graph %d", r.reader.Len(), id)
+	}
+
+	graph.restoreState(relevances, computedRelevance, hosts)
+
+	return GraphID(id), graph, nil
+}
@@ -2,5 +2,5 @@ package structure
 
 type EnrichedAlert[T Stage] struct {
 	Alert
-	MetaStage T
+	MetaStage T `json:"stage"`
 }
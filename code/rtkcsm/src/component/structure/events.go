@@ -0,0 +1,104 @@
+package structure
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type GraphEventType string
+
+const (
+	GraphEventCreated GraphEventType = "created"
+	GraphEventGrown   GraphEventType = "grown"
+	GraphEventMerged  GraphEventType = "merged"
+	// the relevance and thereby the ranking of a graph changed
+	GraphEventRanking GraphEventType = "ranking"
+	GraphEventRemoved GraphEventType = "removed"
+	// the incident state of a graph changed
+	GraphEventIncident GraphEventType = "incident"
+	// many graphs changed at once (reset, import, host risks), so clients reload all graphs
+	GraphEventReload GraphEventType = "reload"
+)
+
+type GraphEvent struct {
+	Type       GraphEventType `json:"type"`
+	ID         GraphID        `json:"id"`
+	MergedInto GraphID        `json:"merged_into,omitempty"`
+	Relevance  float32        `json:"relevance"`
+	Relations  int            `json:"relations"`
+}
+
+const GRAPH_EVENT_BUFFER_SIZE = 1024
+
+// GraphEventSubscription receives graph events until it is closed. Events are dropped if the
+// subscriber is too slow, which is reported once by Overflowed.
+type GraphEventSubscription struct {
+	Events     chan GraphEvent
+	overflowed atomic.Bool
+	broker     *GraphEventBroker
+}
+
+// Overflowed returns whether events were dropped since the last call
+func (s *GraphEventSubscription) Overflowed() bool {
+	return s.overflowed.Swap(false)
+}
+
+func (s *GraphEventSubscription) Close() {
+	s.broker.unsubscribe(s)
+}
+
+type GraphEventBroker struct {
+	subscriptions map[*GraphEventSubscription]bool
+	count         atomic.Int32
+	mutex         sync.RWMutex
+}
+
+func NewGraphEventBroker() *GraphEventBroker {
+	return &GraphEventBroker{
+		subscriptions: map[*GraphEventSubscription]bool{},
+	}
+}
+
+func (b *GraphEventBroker) Subscribe() *GraphEventSubscription {
+	subscription := &GraphEventSubscription{
+		Events: make(chan GraphEvent, GRAPH_EVENT_BUFFER_SIZE),
+		broker: b,
+	}
+
+	b.mutex.Lock()
+	b.subscriptions[subscription] = true
+	b.count.Store(int32(len(b.subscriptions)))
+	b.mutex.Unlock()
+
+	return subscription
+}
+
+func (b *GraphEventBroker) unsubscribe(subscription *GraphEventSubscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.subscriptions[subscription] {
+		delete(b.subscriptions, subscription)
+		close(subscription.Events)
+		b.count.Store(int32(len(b.subscriptions)))
+	}
+}
+
+// HasSubscribers allows to skip creating events nobody receives
+func (b *GraphEventBroker) HasSubscribers() bool {
+	return b.count.Load() > 0
+}
+
+// Publish does not block the correlation if a subscriber is slow
+func (b *GraphEventBroker) Publish(event GraphEvent) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for subscription := range b.subscriptions {
+		select {
+		case subscription.Events <- event:
+		default:
+			subscription.overflowed.Store(true)
+		}
+	}
+}
@@ -3,5 +3,40 @@ package structure
 type ExportFormat string
 
 const (
-	ExportFormatJson ExportFormat = "json"
+	ExportFormatJson   ExportFormat = "json"
+	ExportFormatBinary ExportFormat = "binary"
 )
+
+// formats for other tools, which can not be imported again
+const (
+	ExportFormatGraphML   ExportFormat = "graphml"
+	ExportFormatDot       ExportFormat = "dot"
+	ExportFormatCytoscape ExportFormat = "cytoscape"
+	ExportFormatStix      ExportFormat = "stix"
+)
+
+const EXPORT_FORMAT_NAME = "rtkcsm-graphs"
+
+// Version 1 files have no header, version 2 adds the header and the graph state,
+// version 3 adds the incidents of the graphs, version 4 ends binary exports with the number of graphs
+const EXPORT_FORMAT_VERSION = 4
+
+// ExportHeader is the first line of an export and restores the engine state that is not part of the graphs
+type ExportHeader struct {
+	Format       string             `json:"format"`
+	Version      int                `json:"version"`
+	NextGraphID  GraphID            `json:"next_graph_id"`
+	StageWeights map[string]float32 `json:"stage_weights"`
+	HostRisks    []HostRisk         `json:"host_risks"`
+	Merges       []GraphMerge       `json:"merges,omitempty"`
+}
+
+func NewExportHeader() ExportHeader {
+	return ExportHeader{
+		Format:       EXPORT_FORMAT_NAME,
+		Version:      EXPORT_FORMAT_VERSION,
+		NextGraphID:  CurrentGraphID(),
+		StageWeights: map[string]float32{},
+		HostRisks:    []HostRisk{},
+	}
+}
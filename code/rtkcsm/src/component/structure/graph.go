@@ -21,6 +21,18 @@ func NextGraphID() GraphID {
 	return nextGraphID
 }
 
+// CurrentGraphID returns the last assigned graph id
+func CurrentGraphID() GraphID {
+	return nextGraphID
+}
+
+// RestoreGraphID makes sure that new graph ids are larger than the given id
+func RestoreGraphID(id GraphID) {
+	if id > nextGraphID {
+		nextGraphID = id
+	}
+}
+
 type GraphInformationList struct {
 	Graphs []GraphInformation `json:"graphs"`
 	Count  int                `json:"count"`
@@ -119,6 +131,7 @@ func (r *OptimizedDirectedRelation[T]) GetLabels() []string {
 			matchingLabels = append(matchingLabels, name)
 		}
 	}
+	sort.Strings(matchingLabels)
 
 	return matchingLabels
 }
@@ -142,8 +155,11 @@ type ReverseLookupEntry[T Stage] struct {
 }
 
 type GraphInformation struct {
-	ID        GraphID `json:"id"`
-	Relevance float32 `json:"relevance"`
+	ID        GraphID        `json:"id"`
+	Relevance float32        `json:"relevance"`
+	Status    IncidentStatus `json:"status"`
+	Relations int            `json:"relations"`
+	LastSeen  int64          `json:"last_seen"`
 }
 
 type ReverseLookupStoreRequest[T Stage] struct {
@@ -167,6 +183,8 @@ type Graph[T Stage, K Stage] struct {
 	reverseLookup     map[IPAddress]ReverseLookupEntry[K]
 	relevances        map[T]float32
 	relationsMutex    *sync.RWMutex
+	lastSeen          time.Time
+	incident          Incident
 	ComputedRelevance float32 `json:"computed_relevance"`
 }
 
@@ -176,6 +194,7 @@ func NewGraph[T Stage, K Stage]() *Graph[T, K] {
 		relevances:     map[T]float32{},
 		relationsMutex: &sync.RWMutex{},
 		reverseLookup:  map[IPAddress]ReverseLookupEntry[K]{},
+		incident:       NewIncident(time.Now()),
 	}
 
 	return &graph
@@ -185,6 +204,56 @@ func (g *Graph[T, K]) Relevance() float32 {
 	return g.ComputedRelevance
 }
 
+// LastSeen returns the newest relation timestamp of the graph
+func (g *Graph[T, K]) LastSeen() time.Time {
+	g.relationsMutex.RLock()
+	defer g.relationsMutex.RUnlock()
+	return g.lastSeen
+}
+
+func (g *Graph[T, K]) GetIncident() Incident {
+	g.relationsMutex.RLock()
+	defer g.relationsMutex.RUnlock()
+	return g.incident.Copy()
+}
+
+func (g *Graph[T, K]) SetIncident(incident Incident) {
+	g.relationsMutex.Lock()
+	defer g.relationsMutex.Unlock()
+	g.incident = incident.Copy()
+}
+
+func (g *Graph[T, K]) IncidentStatus() IncidentStatus {
+	g.relationsMutex.RLock()
+	defer g.relationsMutex.RUnlock()
+	return g.incident.Status
+}
+
+// ReopenIncident opens the incident of the graph again if it was closed
+func (g *Graph[T, K]) ReopenIncident(now time.Time) bool {
+	g.relationsMutex.Lock()
+	defer g.relationsMutex.Unlock()
+	return g.incident.Reopen(now)
+}
+
+func (g *Graph[T, K]) GetStageRelevances() map[T]float32 {
+	g.relationsMutex.RLock()
+	defer g.relationsMutex.RUnlock()
+
+	relevances := map[T]float32{}
+	for stage, relevance := range g.relevances {
+		relevances[stage] = relevance
+	}
+
+	return relevances
+}
+
+func (g *Graph[T, K]) Len() int {
+	g.relationsMutex.RLock()
+	defer g.relationsMutex.RUnlock()
+	return len(g.Relations)
+}
+
 func (g *Graph[T, K]) GetRelations() []DirectedRelation[T] {
 	relations := []DirectedRelation[T]{}
 	for id, r := range g.Relations {
@@ -215,24 +284,32 @@ func (g *Graph[T, K]) Append(r EnrichedAlert[T]) DirectedRelation[T] {
 		Cause:       r.Cause,
 		Labels:      []string{r.Label},
 	}
-	g.append(relation)
+	g.append(relation, 1)
 
 	return relation
 }
 
-func (g *Graph[T, K]) append(r DirectedRelation[T]) {
+func (g *Graph[T, K]) append(r DirectedRelation[T], count int) {
 	g.relationsMutex.Lock()
 	defer g.relationsMutex.Unlock()
 
 	relation, id := NewOptimizedDirectedRelation(r)
 	if existingRelation, ok := g.Relations[id]; ok {
+		// relations keep the timestamp of their first occurrence, which can be a late alert
+		if r.Timestamp.Before(existingRelation.Timestamp) {
+			existingRelation.Timestamp = r.Timestamp
+		}
 		relation = existingRelation
 	}
 
-	relation.Count += 1
+	relation.Count += count
 	relation.AddLabel(r.Labels...)
 
 	g.Relations[id] = relation
+	if r.Timestamp.After(g.lastSeen) {
+		g.lastSeen = r.Timestamp
+	}
+
 	relationRelevance := relation.Relevance(id)
 	existingMaxStageRelevance := g.relevances[relation.MetaStage]
 
@@ -279,6 +356,15 @@ func (g *Graph[T, K]) StoreBucket(address IPAddress, stage K, bucket *timebucket
 	g.reverseLookup[address] = entry
 }
 
+func (g *Graph[T, K]) GetAddresses() []IPAddress {
+	addresses := []IPAddress{}
+	for address := range g.reverseLookup {
+		addresses = append(addresses, address)
+	}
+
+	return addresses
+}
+
 func (g *Graph[T, K]) GetBucket(address IPAddress, stage K) *timebucket.Bucket[GraphID] {
 	if entry, ok := g.reverseLookup[address]; ok {
 		return entry.Buckets[stage]
@@ -317,6 +403,13 @@ func (g *Graph[T, K]) Merge(otherGraph *Graph[T, K], oldGraphID GraphID, newGrap
 	defer g.relationsMutex.Unlock()
 	otherGraph.relationsMutex.RLock()
 	defer otherGraph.relationsMutex.RUnlock()
+
+	if otherGraph.lastSeen.After(g.lastSeen) {
+		g.lastSeen = otherGraph.lastSeen
+	}
+
+	g.incident.Merge(otherGraph.incident)
+
 	for id, relation := range otherGraph.Relations {
 		if existingRelation, ok := g.Relations[id]; ok {
 			relation.Count += existingRelation.Count
@@ -383,6 +476,8 @@ type PreComputedDirectedRelation[T Stage] struct {
 	ToIsInternal          bool       `json:"to_is_internal"`
 	FromRiskLevel         RiskLevel  `json:"from_risk_level"`
 	ToRiskLevel           RiskLevel  `json:"to_risk_level"`
+	FromZone              string     `json:"from_zone,omitempty"`
+	ToZone                string     `json:"to_zone,omitempty"`
 }
 
 func (r *OptimizedDirectedRelation[T]) getConfirmedStages(hasLateralMovement bool, hasOutgoingActivity bool) []UKCStage {
@@ -437,6 +532,8 @@ func (r *OptimizedDirectedRelation[T]) GetPreComputed(stages []UKCStage, count i
 		ToIsInternal:          dst.IsInternal(),
 		FromRiskLevel:         HostManager.GetHostRiskLevel(src),
 		ToRiskLevel:           HostManager.GetHostRiskLevel(dst),
+		FromZone:              src.Zone(),
+		ToZone:                dst.Zone(),
 	}
 }
 
@@ -483,9 +580,17 @@ func (g *Graph[T, K]) GetPreComputed() PreComputedGraph[T] {
 	return graph
 }
 
+type GraphHostJson struct {
+	HasLateralMovement  bool `json:"lateral_movement"`
+	HasOutgoingActivity bool `json:"outgoing_activity"`
+}
+
 type GraphJson[T Stage] struct {
 	Relations         []DirectedRelationJson[T] `json:"relations"`
 	ComputedRelevance float32                   `json:"computed_relevance"`
+	StageRelevances   map[T]float32             `json:"stage_relevances,omitempty"`
+	Hosts             map[string]GraphHostJson  `json:"hosts,omitempty"`
+	Incident          *Incident                 `json:"incident,omitempty"`
 }
 
 func (g *Graph[T, K]) MarshalJSON() ([]byte, error) {
@@ -510,6 +615,17 @@ func (g *Graph[T, K]) MarshalJSON() ([]byte, error) {
 	}
 
 	graph.ComputedRelevance = g.ComputedRelevance
+	graph.StageRelevances = g.relevances
+	graph.Hosts = map[string]GraphHostJson{}
+	incident := g.incident.Copy()
+	graph.Incident = &incident
+
+	for address, entry := range g.reverseLookup {
+		graph.Hosts[address.String()] = GraphHostJson{
+			HasLateralMovement:  entry.HasLateralMovement,
+			HasOutgoingActivity: entry.HasOutgoingActivity,
+		}
+	}
 
 	return json.Marshal(graph)
 }
@@ -525,8 +641,12 @@ func (g *Graph[T, K]) UnmarshalJSON(data []byte) error {
 	g.reverseLookup = map[IPAddress]ReverseLookupEntry[K]{}
 	g.relationsMutex = &sync.RWMutex{}
 	g.Relations = map[OptimizedDirectedRelationID]OptimizedDirectedRelation[T]{}
+	g.incident = NewIncident(time.Now())
 
 	for _, relation := range jsonObject.Relations {
+		// relations without a count were seen once
+		count := max(relation.Count, 1)
+
 		g.append(DirectedRelation[T]{
 			SrcNode:     ParseIPAddress(relation.From),
 			DstNode:     ParseIPAddress(relation.To),
@@ -537,8 +657,36 @@ func (g *Graph[T, K]) UnmarshalJSON(data []byte) error {
 			SignatureId: relation.SignatureId,
 			Cause:       relation.Cause,
 			Labels:      relation.Labels,
-		})
+		}, count)
+	}
+
+	hosts := map[IPAddress]GraphHostJson{}
+	for address, host := range jsonObject.Hosts {
+		hosts[ParseIPAddress(address)] = host
+	}
+
+	// exports before version 2 do not contain the state and keep the recomputed one
+	g.restoreState(jsonObject.StageRelevances, jsonObject.ComputedRelevance, hosts)
+
+	// exports before version 3 do not contain incidents
+	if jsonObject.Incident != nil {
+		g.incident = *jsonObject.Incident
 	}
 
 	return nil
 }
+
+func (g *Graph[T, K]) restoreState(relevances map[T]float32, computedRelevance float32, hosts map[IPAddress]GraphHostJson) {
+	if relevances != nil {
+		g.relevances = relevances
+		g.ComputedRelevance = computedRelevance
+	}
+
+	for address, host := range hosts {
+		if entry, ok := g.reverseLookup[address]; ok {
+			entry.HasLateralMovement = host.HasLateralMovement
+			entry.HasOutgoingActivity = host.HasOutgoingActivity
+			g.reverseLookup[address] = entry
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package structure
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type GraphSortOrder string
+
+const (
+	GraphSortRelevance GraphSortOrder = "relevance"
+	// newest activity first
+	GraphSortLastSeen GraphSortOrder = "last_seen"
+	// most relations first
+	GraphSortSize GraphSortOrder = "size"
+)
+
+const DEFAULT_GRAPH_PAGE_SIZE = 100
+const MAX_GRAPH_PAGE_SIZE = 1000
+
+// GraphQuery selects a page of graphs. Multiple values of a filter match any of them,
+// different filters have to match all. Relation filters can be matched by different relations of a graph.
+type GraphQuery struct {
+	Networks     []*net.IPNet
+	SignatureIds []uint32
+	Stages       []UKCStage
+	Labels       []string
+	// graphs that were active in the time range, zero times are not limited
+	From         time.Time
+	To           time.Time
+	MinRelevance float32
+	MinRelations int
+	// zero does not limit the relation count
+	MaxRelations int
+	Statuses     []IncidentStatus
+	Sort         GraphSortOrder
+	Page         int
+	PageSize     int
+}
+
+func NewGraphQuery(page int) GraphQuery {
+	return GraphQuery{
+		Sort:     GraphSortRelevance,
+		Page:     page,
+		PageSize: DEFAULT_GRAPH_PAGE_SIZE,
+	}
+}
+
+// HasFilters returns whether graphs have to be checked individually
+func (q GraphQuery) HasFilters() bool {
+	return len(q.Networks) > 0 || len(q.SignatureIds) > 0 || len(q.Stages) > 0 || len(q.Labels) > 0 ||
+		!q.From.IsZero() || !q.To.IsZero() || q.MinRelevance > 0 || q.MinRelations > 0 || q.MaxRelations > 0 ||
+		len(q.Statuses) > 0
+}
+
+// ParseGraphQuery reads a query from the parameters of the graph list API
+func ParseGraphQuery(values url.Values) (GraphQuery, error) {
+	query := NewGraphQuery(0)
+	var err error
+
+	if page := values.Get("page"); page != "" {
+		if query.Page, err = strconv.Atoi(page); err != nil {
+			return query, fmt.Errorf("invalid page: %s", page)
+		}
+	}
+
+	if pageSize := values.Get("page_size"); pageSize != "" {
+		query.PageSize, err = strconv.Atoi(pageSize)
+		if err != nil || query.PageSize < 1 || query.PageSize > MAX_GRAPH_PAGE_SIZE {
+			return query, fmt.Errorf("page size has to be between 1 and %d: %s", MAX_GRAPH_PAGE_SIZE, pageSize)
+		}
+	}
+
+	if sortOrder := values.Get("sort"); sortOrder != "" {
+		switch GraphSortOrder(sortOrder) {
+		case GraphSortRelevance, GraphSortLastSeen, GraphSortSize:
+			query.Sort = GraphSortOrder(sortOrder)
+		default:
+			return query, fmt.Errorf("unknown sort order: %s", sortOrder)
+		}
+	}
+
+	for _, address := range values["ip"] {
+		network, err := parseNetwork(address)
+		if err != nil {
+			return query, err
+		}
+		query.Networks = append(query.Networks, network)
+	}
+
+	for _, signatureId := range values["signature"] {
+		id, err := strconv.ParseUint(signatureId, 10, 32)
+		if err != nil {
+			return query, fmt.Errorf("invalid signature id: %s", signatureId)
+		}
+		query.SignatureIds = append(query.SignatureIds, uint32(id))
+	}
+
+	for _, name := range values["stage"] {
+		stage, err := ParseUKCStage(name)
+		if err != nil {
+			return query, err
+		}
+		query.Stages = append(query.Stages, stage)
+	}
+
+	query.Labels = values["label"]
+
+	for _, name := range values["status"] {
+		status, err := ParseIncidentStatus(name)
+		if err != nil {
+			return query, err
+		}
+		query.Statuses = append(query.Statuses, status)
+	}
+
+	if query.From, err = parseQueryTime(values.Get("from")); err != nil {
+		return query, err
+	}
+
+	if query.To, err = parseQueryTime(values.Get("to")); err != nil {
+		return query, err
+	}
+
+	if minRelevance := values.Get("min_relevance"); minRelevance != "" {
+		relevance, err := strconv.ParseFloat(minRelevance, 32)
+		if err != nil {
+			return query, fmt.Errorf("invalid minimum relevance: %s", minRelevance)
+		}
+		query.MinRelevance = float32(relevance)
+	}
+
+	if minRelations := values.Get("min_relations"); minRelations != "" {
+		if query.MinRelations, err = strconv.Atoi(minRelations); err != nil {
+			return query, fmt.Errorf("invalid minimum relation count: %s", minRelations)
+		}
+	}
+
+	if maxRelations := values.Get("max_relations"); maxRelations != "" {
+		if query.MaxRelations, err = strconv.Atoi(maxRelations); err != nil {
+			return query, fmt.Errorf("invalid maximum relation count: %s", maxRelations)
+		}
+	}
+
+	return query, nil
+}
+
+// parseNetwork accepts a CIDR or a single address
+func parseNetwork(address string) (*net.IPNet, error) {
+	if strings.Contains(address, "/") {
+		_, network, err := net.ParseCIDR(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network: %s", address)
+		}
+		return network, nil
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address: %s", address)
+	}
+
+	bits := net.IPv6len * 8
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = net.IPv4len * 8
+	}
+
+	return &net.IPNet{
+		IP:   ip,
+		Mask: net.CIDRMask(bits, bits),
+	}, nil
+}
+
+// parseQueryTime accepts RFC 3339 times and unix timestamps in milliseconds like the graph API returns
+func parseQueryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if milliseconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(milliseconds), nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time: %s", value)
+	}
+
+	return timestamp, nil
+}
+
+// MatchesQuery checks the filters of a query, but not the page
+func (g *Graph[T, K]) MatchesQuery(query GraphQuery) bool {
+	g.relationsMutex.RLock()
+	defer g.relationsMutex.RUnlock()
+
+	if g.ComputedRelevance < query.MinRelevance || len(g.Relations) < query.MinRelations {
+		return false
+	}
+
+	if query.MaxRelations > 0 && len(g.Relations) > query.MaxRelations {
+		return false
+	}
+
+	if len(query.Statuses) > 0 && !slices.Contains(query.Statuses, g.incident.Status) {
+		return false
+	}
+
+	if !query.To.IsZero() && g.firstSeen().After(query.To) {
+		return false
+	}
+
+	if !query.From.IsZero() && g.lastSeen.Before(query.From) {
+		return false
+	}
+
+	labels := uint64(0)
+	for _, label := range query.Labels {
+		labels |= allLabels[label]
+	}
+
+	matchesNetwork := len(query.Networks) == 0
+	matchesSignature := len(query.SignatureIds) == 0
+	matchesStage := len(query.Stages) == 0
+	matchesLabel := len(query.Labels) == 0
+
+	if matchesNetwork && matchesSignature && matchesStage && matchesLabel {
+		return true
+	}
+
+	for id, relation := range g.Relations {
+		if !matchesNetwork {
+			src := id.GetSrc().IP()
+			dst := id.GetDst().IP()
+			matchesNetwork = slices.ContainsFunc(query.Networks, func(network *net.IPNet) bool {
+				return network.Contains(src) || network.Contains(dst)
+			})
+		}
+
+		if !matchesSignature {
+			matchesSignature = slices.Contains(query.SignatureIds, id.GetSignatureId())
+		}
+
+		if !matchesStage {
+			matchesStage = slices.ContainsFunc(relation.MetaStage.ToUKCStages(), func(stage UKCStage) bool {
+				return slices.Contains(query.Stages, stage)
+			})
+		}
+
+		if !matchesLabel {
+			matchesLabel = relation.Labels&labels != 0
+		}
+
+		if matchesNetwork && matchesSignature && matchesStage && matchesLabel {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firstSeen expects the relations mutex to be locked
+func (g *Graph[T, K]) firstSeen() time.Time {
+	firstSeen := g.lastSeen
+	for _, relation := range g.Relations {
+		if relation.Timestamp.Before(firstSeen) {
+			firstSeen = relation.Timestamp
+		}
+	}
+	return firstSeen
+}
@@ -0,0 +1,32 @@
+package structure
+
+import "time"
+
+// GraphMerge records that a graph was merged into another graph by a relation
+type GraphMerge struct {
+	From      GraphID   `json:"from"`
+	Into      GraphID   `json:"into"`
+	Timestamp time.Time `json:"timestamp"`
+	Relation  string    `json:"relation"`
+	Relations int       `json:"relations"`
+}
+
+type GraphHistoryEntryType string
+
+const (
+	GraphHistoryRelation GraphHistoryEntryType = "relation"
+	GraphHistoryMerge    GraphHistoryEntryType = "merge"
+)
+
+type GraphHistoryEntry struct {
+	Type        GraphHistoryEntryType `json:"type"`
+	Timestamp   int64                 `json:"timestamp"`
+	Relation    string                `json:"relation"`
+	MergedGraph GraphID               `json:"merged_graph,omitempty"`
+	Relations   int                   `json:"relations,omitempty"`
+}
+
+type GraphHistory struct {
+	ID      GraphID             `json:"id"`
+	Entries []GraphHistoryEntry `json:"entries"`
+}
@@ -0,0 +1,129 @@
+package structure
+
+import (
+	"slices"
+	"time"
+)
+
+type HostRole string
+
+const (
+	HostRoleAttacker HostRole = "attacker"
+	HostRoleVictim   HostRole = "victim"
+)
+
+// HostGraphActivity describes the relations of a host in one graph. The timestamps are the first
+// occurrences of the relations, because later occurrences are only counted.
+type HostGraphActivity struct {
+	ID        GraphID        `json:"id"`
+	Relevance float32        `json:"relevance"`
+	Status    IncidentStatus `json:"status"`
+	Roles     []HostRole     `json:"roles"`
+	Stages    []UKCStage     `json:"ukc_stages"`
+	Relations int            `json:"relations"`
+	FirstSeen int64          `json:"first_seen"`
+	LastSeen  int64          `json:"last_seen"`
+}
+
+type HostActivity struct {
+	Address    string              `json:"address"`
+	IsInternal bool                `json:"is_internal"`
+	Zone       string              `json:"zone,omitempty"`
+	RiskLevel  RiskLevel           `json:"risk_level"`
+	Roles      []HostRole          `json:"roles"`
+	Stages     []UKCStage          `json:"ukc_stages"`
+	FirstSeen  int64               `json:"first_seen,omitempty"`
+	LastSeen   int64               `json:"last_seen,omitempty"`
+	Graphs     []HostGraphActivity `json:"graphs"`
+}
+
+func NewHostActivity(address IPAddress) HostActivity {
+	return HostActivity{
+		Address:    address.String(),
+		IsInternal: address.IsInternal(),
+		Zone:       address.Zone(),
+		RiskLevel:  HostManager.GetHostRiskLevel(address),
+		Roles:      []HostRole{},
+		Stages:     []UKCStage{},
+		Graphs:     []HostGraphActivity{},
+	}
+}
+
+// Add combines the activity of a graph with the activity of the host in other graphs
+func (a *HostActivity) Add(activity HostGraphActivity) {
+	if len(a.Graphs) == 0 || activity.FirstSeen < a.FirstSeen {
+		a.FirstSeen = activity.FirstSeen
+	}
+	a.LastSeen = max(a.LastSeen, activity.LastSeen)
+
+	a.Roles = appendUnique(a.Roles, activity.Roles...)
+	a.Stages = appendUnique(a.Stages, activity.Stages...)
+	a.Graphs = append(a.Graphs, activity)
+}
+
+func appendUnique[T HostRole | UKCStage](values []T, additionalValues ...T) []T {
+	for _, value := range additionalValues {
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+
+	slices.Sort(values)
+	return values
+}
+
+// GetHostActivity returns the relations of a host in the graph. The host is the victim of a relation
+// on the side returned by GetVictim and the attacker on the other side.
+func (g *Graph[T, K]) GetHostActivity(id GraphID, address IPAddress) (HostGraphActivity, bool) {
+	g.relationsMutex.RLock()
+	defer g.relationsMutex.RUnlock()
+
+	activity := HostGraphActivity{
+		ID:        id,
+		Relevance: g.ComputedRelevance,
+		Status:    g.incident.Status,
+		Roles:     []HostRole{},
+		Stages:    []UKCStage{},
+	}
+
+	var firstSeen, lastSeen time.Time
+
+	for relationId, relation := range g.Relations {
+		src := relationId.GetSrc()
+		dst := relationId.GetDst()
+		if !src.Equal(address) && !dst.Equal(address) {
+			continue
+		}
+
+		victim := dst
+		if relation.MetaStage.GetVictim() == Source {
+			victim = src
+		}
+
+		if victim.Equal(address) {
+			activity.Roles = appendUnique(activity.Roles, HostRoleVictim)
+		} else {
+			activity.Roles = appendUnique(activity.Roles, HostRoleAttacker)
+		}
+
+		stages := relation.getConfirmedStages(g.reverseLookup[src].HasLateralMovement, g.reverseLookup[dst].HasOutgoingActivity)
+		activity.Stages = appendUnique(activity.Stages, stages...)
+
+		if activity.Relations == 0 || relation.Timestamp.Before(firstSeen) {
+			firstSeen = relation.Timestamp
+		}
+		if relation.Timestamp.After(lastSeen) {
+			lastSeen = relation.Timestamp
+		}
+		activity.Relations += 1
+	}
+
+	if activity.Relations == 0 {
+		return activity, false
+	}
+
+	activity.FirstSeen = firstSeen.UnixMilli()
+	activity.LastSeen = lastSeen.UnixMilli()
+
+	return activity, true
+}
@@ -0,0 +1,188 @@
+package structure
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+type IncidentStatus string
+
+const (
+	IncidentStatusOpen         IncidentStatus = "open"
+	IncidentStatusAcknowledged IncidentStatus = "acknowledged"
+	IncidentStatusClosed       IncidentStatus = "closed"
+)
+
+// progress orders the statuses from untriaged to resolved
+func (s IncidentStatus) progress() int {
+	switch s {
+	case IncidentStatusAcknowledged:
+		return 1
+	case IncidentStatusClosed:
+		return 2
+	default:
+		return 0
+	}
+}
+
+func ParseIncidentStatus(status string) (IncidentStatus, error) {
+	switch IncidentStatus(status) {
+	case IncidentStatusOpen, IncidentStatusAcknowledged, IncidentStatusClosed:
+		return IncidentStatus(status), nil
+	default:
+		return "", fmt.Errorf("unknown incident status: %s", status)
+	}
+}
+
+type IncidentNote struct {
+	Author    string    `json:"author,omitempty"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Incident is the triage state of a graph. The timestamps are wall clock times of the changes
+// and not alert times.
+type Incident struct {
+	Status    IncidentStatus `json:"status"`
+	Assignee  string         `json:"assignee,omitempty"`
+	Notes     []IncidentNote `json:"notes,omitempty"`
+	Tags      []string       `json:"tags,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	ClosedAt  *time.Time     `json:"closed_at,omitempty"`
+	// number of times the graph was reopened by new relations after it was closed
+	Reopened int `json:"reopened,omitempty"`
+}
+
+func NewIncident(now time.Time) Incident {
+	return Incident{
+		Status:    IncidentStatusOpen,
+		Notes:     []IncidentNote{},
+		Tags:      []string{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// IncidentUpdate changes the fields that are set
+type IncidentUpdate struct {
+	Status   *string   `json:"status,omitempty"`
+	Assignee *string   `json:"assignee,omitempty"`
+	Tags     *[]string `json:"tags,omitempty"`
+}
+
+func (i *Incident) Update(update IncidentUpdate, now time.Time) error {
+	if update.Status != nil {
+		status, err := ParseIncidentStatus(*update.Status)
+		if err != nil {
+			return err
+		}
+		i.setStatus(status, now)
+	}
+
+	if update.Assignee != nil {
+		i.Assignee = *update.Assignee
+	}
+
+	if update.Tags != nil {
+		i.Tags = normalizeTags(*update.Tags)
+	}
+
+	i.UpdatedAt = now
+
+	return nil
+}
+
+func (i *Incident) AddNote(note IncidentNote, now time.Time) error {
+	if note.Text == "" {
+		return fmt.Errorf("note without text")
+	}
+
+	note.CreatedAt = now
+	i.Notes = append(i.Notes, note)
+	i.UpdatedAt = now
+
+	return nil
+}
+
+func (i *Incident) setStatus(status IncidentStatus, now time.Time) {
+	if status == IncidentStatusClosed {
+		if i.Status != IncidentStatusClosed {
+			i.ClosedAt = &now
+		}
+	} else {
+		i.ClosedAt = nil
+	}
+
+	i.Status = status
+}
+
+// Reopen opens a closed incident again and returns whether it was closed
+func (i *Incident) Reopen(now time.Time) bool {
+	if i.Status != IncidentStatusClosed {
+		return false
+	}
+
+	i.setStatus(IncidentStatusOpen, now)
+	i.Reopened += 1
+	i.UpdatedAt = now
+
+	return true
+}
+
+// Merge combines the state of two incidents. The least progressed status wins, because the merged
+// graph contains relations nobody triaged in that state yet. The assignee of the incident is kept
+// if it is set, notes and tags are combined.
+func (i *Incident) Merge(other Incident) {
+	if other.Status.progress() < i.Status.progress() {
+		i.Status = other.Status
+	}
+
+	if i.Status == IncidentStatusClosed {
+		if other.ClosedAt != nil && (i.ClosedAt == nil || other.ClosedAt.After(*i.ClosedAt)) {
+			i.ClosedAt = other.ClosedAt
+		}
+	} else {
+		i.ClosedAt = nil
+	}
+
+	if i.Assignee == "" {
+		i.Assignee = other.Assignee
+	}
+
+	i.Notes = append(i.Notes, other.Notes...)
+	sort.SliceStable(i.Notes, func(a, b int) bool {
+		return i.Notes[a].CreatedAt.Before(i.Notes[b].CreatedAt)
+	})
+
+	i.Tags = normalizeTags(append(i.Tags, other.Tags...))
+
+	if other.CreatedAt.Before(i.CreatedAt) {
+		i.CreatedAt = other.CreatedAt
+	}
+	if other.UpdatedAt.After(i.UpdatedAt) {
+		i.UpdatedAt = other.UpdatedAt
+	}
+
+	i.Reopened += other.Reopened
+}
+
+func (i Incident) Copy() Incident {
+	i.Notes = slices.Clone(i.Notes)
+	i.Tags = slices.Clone(i.Tags)
+	return i
+}
+
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		if tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	sort.Strings(normalized)
+	return slices.Compact(normalized)
+}
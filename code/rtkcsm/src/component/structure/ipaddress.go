@@ -15,8 +15,12 @@ const IS_UNSPECIFIED = 0b0000_0100
 const IS_IPV6 = 0b0000_0010
 const IS_PRIVATE = 0b0000_0001
 
+// ParseIPAddress parses the notations of net.ParseIP and of String
 func ParseIPAddress(address string) IPAddress {
 	ipBytes := net.ParseIP(address)
+	if ipBytes == nil {
+		ipBytes = parseIPv6Bytes(address)
+	}
 
 	ipAddress := IPAddress{}
 
@@ -39,7 +43,7 @@ func ParseIPAddress(address string) IPAddress {
 		ipAddress[14] = ipBytes[14]
 		ipAddress[15] = ipBytes[15]
 
-		if ipBytes.IsPrivate() {
+		if ZoneManager.IsInternal(ipBytes) {
 			ipAddress[16] |= IS_PRIVATE
 		}
 
@@ -57,6 +61,25 @@ func ParseIPAddress(address string) IPAddress {
 	return ipAddress
 }
 
+// parseIPv6Bytes parses the notation of String for IPv6 addresses, i.e. 16 hexadecimal bytes separated by colons
+func parseIPv6Bytes(address string) net.IP {
+	parts := strings.Split(address, ":")
+	if len(parts) != net.IPv6len {
+		return nil
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	for i, part := range parts {
+		value, err := strconv.ParseUint(part, 16, 8)
+		if err != nil {
+			return nil
+		}
+		ip[i] = byte(value)
+	}
+
+	return ip
+}
+
 func (ipAddress IPAddress) IsInternal() bool {
 	return ipAddress[16]&IS_PRIVATE == IS_PRIVATE
 }
@@ -69,6 +92,34 @@ func (ipAddress IPAddress) Equal(otherIpAdress IPAddress) bool {
 	return ipAddress == otherIpAdress
 }
 
+// IsSameZone compares the configured zones and falls back to IsSameSubnet for addresses outside of all zones
+func (ipAddress IPAddress) IsSameZone(otherIpAdress IPAddress) bool {
+	if ZoneManager.HasZones() {
+		zone := ipAddress.Zone()
+		otherZone := otherIpAdress.Zone()
+
+		if zone != "" || otherZone != "" {
+			return zone == otherZone
+		}
+	}
+
+	return ipAddress.IsSameSubnet(otherIpAdress)
+}
+
+func (ipAddress IPAddress) Zone() string {
+	if ipAddress.IsUnspecified() {
+		return ""
+	}
+
+	return ZoneManager.GetZone(ipAddress.IP())
+}
+
+func (ipAddress IPAddress) IP() net.IP {
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, ipAddress[0:net.IPv6len])
+	return ip
+}
+
 func (ipAddress IPAddress) IsSameSubnet(otherIpAdress IPAddress) bool {
 	networkSize := 24
 	if ipAddress[16]&IS_IPV6 == IS_IPV6 {
@@ -84,12 +135,12 @@ func (ipAddress IPAddress) IsSameSubnet(otherIpAdress IPAddress) bool {
 		return false
 	}
 
-	_, network, err := net.ParseCIDR(fmt.Sprintf("%s/%d", ipAddress.String(), networkSize))
+	_, network, err := net.ParseCIDR(fmt.Sprintf("%s/%d", ipAddress.IP(), networkSize))
 	if err != nil {
 		return false
 	}
 
-	return network.Contains(net.ParseIP(otherIpAdress.String()))
+	return network.Contains(otherIpAdress.IP())
 }
 
 func (ipAddress IPAddress) String() string {
@@ -117,3 +168,12 @@ func (ipAddress IPAddress) String() string {
 func (ipAddress IPAddress) Bytes() [IP_ADDRESS_LENGTH]byte {
 	return ipAddress
 }
+
+func (ipAddress IPAddress) MarshalText() ([]byte, error) {
+	return []byte(ipAddress.String()), nil
+}
+
+func (ipAddress *IPAddress) UnmarshalText(text []byte) error {
+	*ipAddress = ParseIPAddress(string(text))
+	return nil
+}
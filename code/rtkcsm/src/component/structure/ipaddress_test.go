@@ -0,0 +1,34 @@
+package structure
+
+import "testing"
+
+func TestIPAddressString(t *testing.T) {
+	addresses := map[string]string{
+		"172.16.42.42":    "172.16.42.42",
+		"2001:db8::1":     "20:1:d:b8:0:0:0:0:0:0:0:0:0:0:0:1",
+		"fe80::a:1":       "fe:80:0:0:0:0:0:0:0:0:0:0:0:a:0:1",
+		"::ffff:10.0.0.1": "10.0.0.1",
+	}
+
+	for address, expected := range addresses {
+		ipAddress := ParseIPAddress(address)
+		if ipAddress.String() != expected {
+			t.Errorf("%s: expected %s instead of %s", address, expected, ipAddress.String())
+		}
+
+		// exports and the write-ahead log store the notation of String
+		if parsed := ParseIPAddress(ipAddress.String()); parsed != ipAddress {
+			t.Errorf("%s: parsed %s as %v instead of %v", address, ipAddress.String(), parsed, ipAddress)
+		}
+	}
+
+	for _, invalid := range []string{"", "not an address", "20:1:d:b8:0:0:0:0:0:0:0:0:0:0:0:100", "20:1:d:b8:0:0:0:0:0:0:0:0:0:0:1"} {
+		if !ParseIPAddress(invalid).IsUnspecified() {
+			t.Errorf("invalid address %q was parsed", invalid)
+		}
+	}
+
+	if !ParseIPAddress("2001:db8::1").IsSameSubnet(ParseIPAddress("2001:db8::2")) || ParseIPAddress("2001:db8::1").IsSameSubnet(ParseIPAddress("2001:db9::1")) {
+		t.Error("unexpected subnets of IPv6 addresses")
+	}
+}
@@ -18,12 +18,20 @@ func NewLookupEntry[T Stage](ipAddress IPAddress, stage T) LookupEntry {
 type LookupTable[T Stage, K Stage] struct {
 	relations    map[LookupEntry]*timebucket.TimeBucketIndex[GraphID]
 	stateMachine StateMachine[T, K]
+
+	// hosts maps internal addresses to the graphs they are part of
+	hosts map[IPAddress]set.Set[GraphID]
+
+	// following maps the source and a preceding stage of relations to the latest timestamp per graph,
+	// so that late relations can find the graphs they precede
+	following map[LookupEntry]*timebucket.Bucket[GraphID]
 }
 
 func NewLookupTable[T Stage, K Stage](stateMachine StateMachine[T, K]) LookupTable[T, K] {
 	return LookupTable[T, K]{
 		relations:    map[LookupEntry]*timebucket.TimeBucketIndex[GraphID]{},
 		stateMachine: stateMachine,
+		hosts:        map[IPAddress]set.Set[GraphID]{},
 	}
 }
 
@@ -43,7 +51,100 @@ func (l *LookupTable[T, K]) SearchRelations(relation *EnrichedAlert[T]) set.Set[
 	return graphIds
 }
 
+// SearchFollowingRelations returns the graphs with relations at or after a relation that would have been
+// linked to it if it had arrived in order. It requires TrackFollowingRelations.
+func (l *LookupTable[T, K]) SearchFollowingRelations(relation *EnrichedAlert[T]) set.Set[GraphID] {
+	graphIds := set.NewSet[GraphID]()
+	if l.following == nil {
+		return graphIds
+	}
+
+	for _, address := range []IPAddress{relation.Alert.SourceIP, relation.Alert.DestinationIP} {
+		if !address.IsInternal() {
+			continue
+		}
+
+		for _, stage := range l.stateMachine.GetCurrentStateStages(relation.MetaStage) {
+			bucket := l.following[NewLookupEntry(address, stage)]
+			if bucket != nil {
+				graphIds.Append(bucket.GetAllAfter(relation.Timestamp)...)
+			}
+		}
+	}
+
+	return graphIds
+}
+
+// TrackFollowingRelations enables SearchFollowingRelations for the given and all further relations
+func (l *LookupTable[T, K]) TrackFollowingRelations(graphs map[GraphID]*Graph[T, K]) {
+	if l.following != nil {
+		return
+	}
+
+	l.following = map[LookupEntry]*timebucket.Bucket[GraphID]{}
+	for graphID, graph := range graphs {
+		for _, relation := range graph.GetRelations() {
+			l.addFollowingRelation(&relation, graphID)
+		}
+	}
+}
+
+func (l *LookupTable[T, K]) followingEntries(relation *DirectedRelation[T]) []LookupEntry {
+	entries := []LookupEntry{}
+	if relation.SrcNode.IsInternal() {
+		for _, precedingStage := range l.stateMachine.GetPrecedingStages(relation.MetaStage) {
+			entries = append(entries, NewLookupEntry(relation.SrcNode, precedingStage))
+		}
+	}
+
+	return entries
+}
+
+func (l *LookupTable[T, K]) addFollowingRelation(relation *DirectedRelation[T], graphID GraphID) {
+	for _, entry := range l.followingEntries(relation) {
+		bucket := l.following[entry]
+		if bucket == nil {
+			bucket = timebucket.NewBucket[GraphID]()
+			l.following[entry] = bucket
+		}
+
+		if relation.Timestamp.After(bucket.Get(graphID)) {
+			bucket.Set(graphID, relation.Timestamp)
+		}
+	}
+}
+
+// MergeGraph moves the hosts and following relations of a merged graph, the time buckets are moved by Graph.Merge
+func (l *LookupTable[T, K]) MergeGraph(oldGraphID GraphID, newGraphID GraphID, oldGraph *Graph[T, K]) {
+	for _, address := range oldGraph.GetAddresses() {
+		l.removeHost(address, oldGraphID)
+		l.addHost(address, newGraphID)
+	}
+
+	if l.following == nil {
+		return
+	}
+
+	for _, relation := range oldGraph.GetRelations() {
+		for _, entry := range l.followingEntries(&relation) {
+			bucket := l.following[entry]
+			if bucket == nil {
+				continue
+			}
+
+			if timestamp := bucket.Get(oldGraphID); timestamp.After(bucket.Get(newGraphID)) {
+				bucket.Set(newGraphID, timestamp)
+			}
+			bucket.Delete(oldGraphID)
+		}
+	}
+}
+
 func (l *LookupTable[T, K]) AddRelation(relation *DirectedRelation[T], graphID GraphID, graph *Graph[T, K]) {
+	if l.following != nil {
+		l.addFollowingRelation(relation, graphID)
+	}
+
 	addresses := []IPAddress{}
 
 	if relation.SrcNode.IsInternal() {
@@ -58,6 +159,7 @@ func (l *LookupTable[T, K]) AddRelation(relation *DirectedRelation[T], graphID G
 
 	for i := range len(addresses) {
 		address := addresses[i]
+		l.addHost(address, graphID)
 
 		for _, stage := range stages {
 			entry := NewLookupEntry(address, stage)
@@ -84,3 +186,60 @@ func (l *LookupTable[T, K]) AddRelation(relation *DirectedRelation[T], graphID G
 		}
 	}
 }
+
+// RemoveGraph deletes all time buckets entries of a graph and drops lookup entries that became empty
+func (l *LookupTable[T, K]) RemoveGraph(graphID GraphID, graph *Graph[T, K]) {
+	for _, address := range graph.GetAddresses() {
+		l.removeHost(address, graphID)
+
+		for stage, bucket := range graph.GetBuckets(address) {
+			bucket.Delete(graphID)
+
+			entry := NewLookupEntry(address, stage)
+			if bucketIndex, ok := l.relations[entry]; ok && bucketIndex.Prune() == 0 {
+				delete(l.relations, entry)
+			}
+		}
+	}
+
+	if l.following != nil {
+		for _, relation := range graph.GetRelations() {
+			for _, entry := range l.followingEntries(&relation) {
+				if bucket, ok := l.following[entry]; ok {
+					bucket.Delete(graphID)
+					if bucket.Len() == 0 {
+						delete(l.following, entry)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (l *LookupTable[T, K]) addHost(address IPAddress, graphID GraphID) {
+	graphIds, ok := l.hosts[address]
+	if !ok {
+		graphIds = set.NewSet[GraphID]()
+		l.hosts[address] = graphIds
+	}
+
+	graphIds.Append(graphID)
+}
+
+func (l *LookupTable[T, K]) removeHost(address IPAddress, graphID GraphID) {
+	if graphIds, ok := l.hosts[address]; ok {
+		delete(graphIds, graphID)
+		if graphIds.Size() == 0 {
+			delete(l.hosts, address)
+		}
+	}
+}
+
+// SearchHost returns all graphs an internal address is part of
+func (l *LookupTable[T, K]) SearchHost(address IPAddress) set.Set[GraphID] {
+	return set.NewSet(l.hosts[address].ToSlice()...)
+}
+
+func (l *LookupTable[T, K]) Len() int {
+	return len(l.relations)
+}
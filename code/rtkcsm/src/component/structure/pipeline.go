@@ -0,0 +1,49 @@
+package structure
+
+type orderedJob[I any, O any] struct {
+	input  I
+	result chan O
+}
+
+// OrderedMap transforms the values of the input channel with multiple workers.
+// The output channel returns the results in the order of the input and is closed after the input.
+func OrderedMap[I any, O any](workerCount int, input <-chan I, transform func(value I) O) <-chan O {
+	workerCount = max(workerCount, 1)
+
+	jobs := make(chan orderedJob[I, O], workerCount)
+	results := make(chan chan O, 2*workerCount)
+	output := make(chan O, workerCount)
+
+	for range workerCount {
+		go func() {
+			for job := range jobs {
+				job.result <- transform(job.input)
+			}
+		}()
+	}
+
+	go func() {
+		for value := range input {
+			// the result channel is queued before the job to keep the order
+			result := make(chan O, 1)
+			results <- result
+			jobs <- orderedJob[I, O]{
+				input:  value,
+				result: result,
+			}
+		}
+
+		close(jobs)
+		close(results)
+	}()
+
+	go func() {
+		for result := range results {
+			output <- <-result
+		}
+
+		close(output)
+	}()
+
+	return output
+}
@@ -0,0 +1,125 @@
+package structure
+
+import (
+	"container/heap"
+	"slices"
+	"time"
+)
+
+type ReorderMetrics struct {
+	Window      time.Duration `json:"window"`
+	Buffered    int           `json:"buffered"`
+	Reordered   int           `json:"reordered"`
+	LateAlerts  int           `json:"late_alerts"`
+	MaxLateness time.Duration `json:"max_lateness"`
+}
+
+type reorderEntry[T Stage] struct {
+	alert    EnrichedAlert[T]
+	sequence uint64
+}
+
+type reorderHeap[T Stage] []reorderEntry[T]
+
+func (h reorderHeap[T]) Len() int      { return len(h) }
+func (h reorderHeap[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h reorderHeap[T]) Less(i, j int) bool {
+	if h[i].alert.Timestamp.Equal(h[j].alert.Timestamp) {
+		return h[i].sequence < h[j].sequence
+	}
+	return h[i].alert.Timestamp.Before(h[j].alert.Timestamp)
+}
+
+func (h *reorderHeap[T]) Push(entry any) {
+	*h = append(*h, entry.(reorderEntry[T]))
+}
+
+func (h *reorderHeap[T]) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// ReorderBuffer holds alerts back until the watermark (latest timestamp minus the window) passes them,
+// so that alerts arriving slightly out of order are released in timestamp order.
+// Alerts older than the last released alert are late and released immediately.
+type ReorderBuffer[T Stage] struct {
+	window   time.Duration
+	alerts   reorderHeap[T]
+	latest   time.Time
+	released time.Time
+	sequence uint64
+	metrics  ReorderMetrics
+}
+
+func NewReorderBuffer[T Stage](window time.Duration) *ReorderBuffer[T] {
+	return &ReorderBuffer[T]{
+		window: window,
+		alerts: reorderHeap[T]{},
+		metrics: ReorderMetrics{
+			Window: window,
+		},
+	}
+}
+
+// Push adds an alert and returns the alerts that passed the watermark in timestamp order
+func (b *ReorderBuffer[T]) Push(alert EnrichedAlert[T]) []EnrichedAlert[T] {
+	if alert.Timestamp.Before(b.released) {
+		b.metrics.LateAlerts += 1
+		b.metrics.MaxLateness = max(b.metrics.MaxLateness, b.released.Sub(alert.Timestamp))
+		return []EnrichedAlert[T]{alert}
+	}
+
+	if alert.Timestamp.Before(b.latest) {
+		b.metrics.Reordered += 1
+	} else {
+		b.latest = alert.Timestamp
+	}
+
+	b.sequence += 1
+	heap.Push(&b.alerts, reorderEntry[T]{
+		alert:    alert,
+		sequence: b.sequence,
+	})
+
+	return b.release(b.latest.Add(-b.window))
+}
+
+// Flush returns all buffered alerts in timestamp order
+func (b *ReorderBuffer[T]) Flush() []EnrichedAlert[T] {
+	return b.release(b.latest)
+}
+
+func (b *ReorderBuffer[T]) release(watermark time.Time) []EnrichedAlert[T] {
+	alerts := []EnrichedAlert[T]{}
+
+	for len(b.alerts) > 0 && !b.alerts[0].alert.Timestamp.After(watermark) {
+		entry := heap.Pop(&b.alerts).(reorderEntry[T])
+		b.released = entry.alert.Timestamp
+		alerts = append(alerts, entry.alert)
+	}
+
+	return alerts
+}
+
+// Alerts returns the buffered alerts in timestamp order without releasing them
+func (b *ReorderBuffer[T]) Alerts() []EnrichedAlert[T] {
+	entries := slices.Clone(b.alerts)
+
+	alerts := []EnrichedAlert[T]{}
+	for len(entries) > 0 {
+		alerts = append(alerts, heap.Pop(&entries).(reorderEntry[T]).alert)
+	}
+	return alerts
+}
+
+func (b *ReorderBuffer[T]) Len() int {
+	return len(b.alerts)
+}
+
+func (b *ReorderBuffer[T]) Metrics() ReorderMetrics {
+	metrics := b.metrics
+	metrics.Buffered = len(b.alerts)
+	return metrics
+}
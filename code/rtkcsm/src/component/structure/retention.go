@@ -0,0 +1,46 @@
+package structure
+
+import (
+	"io"
+	"time"
+)
+
+// Share of a cap that remains after an eviction, so that eviction does not run for every alert
+const RETENTION_LOW_WATER_MARK = 0.9
+
+const DEFAULT_RETENTION_CHECK_INTERVAL = time.Minute
+
+// RetentionPolicy bounds the memory of long-running deployments. All durations refer to alert time.
+type RetentionPolicy struct {
+	MaxIdleTime   time.Duration // evict graphs without a new relation for this duration (0 = never)
+	MaxGraphs     int           // maximum number of graphs (0 = unlimited)
+	MaxRelations  int           // maximum number of relations of all graphs (0 = unlimited)
+	CheckInterval time.Duration // how often idle graphs are searched for
+	Archive       io.Writer     // evicted graphs are written in the export format if set
+}
+
+func NewRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		CheckInterval: DEFAULT_RETENTION_CHECK_INTERVAL,
+	}
+}
+
+func (p *RetentionPolicy) IsEnabled() bool {
+	return p.MaxIdleTime > 0 || p.MaxGraphs > 0 || p.MaxRelations > 0
+}
+
+func (p *RetentionPolicy) ExceedsGraphs(count int) bool {
+	return p.MaxGraphs > 0 && count > p.MaxGraphs
+}
+
+func (p *RetentionPolicy) ExceedsRelations(count int) bool {
+	return p.MaxRelations > 0 && count > p.MaxRelations
+}
+
+func (p *RetentionPolicy) GraphsLowWaterMark() int {
+	return int(float64(p.MaxGraphs) * RETENTION_LOW_WATER_MARK)
+}
+
+func (p *RetentionPolicy) RelationsLowWaterMark() int {
+	return int(float64(p.MaxRelations) * RETENTION_LOW_WATER_MARK)
+}
@@ -13,16 +13,24 @@ const (
 	None
 )
 
-func NewSimplifiedUKCStageFromString(stage string) SimplifiedUKCStage {
-	stageStringMap := map[string]SimplifiedUKCStage{
-		"incoming":       Incoming,
-		"same-zone":      SameZone,
-		"different-zone": DifferentZone,
-		"outgoing":       Outgoing,
-		"host":           Host,
+var simplifiedUkcStageNames = map[string]SimplifiedUKCStage{
+	"incoming":       Incoming,
+	"same-zone":      SameZone,
+	"different-zone": DifferentZone,
+	"outgoing":       Outgoing,
+	"host":           Host,
+}
+
+var simplifiedUkcStageStrings = func() map[SimplifiedUKCStage]string {
+	strings := map[SimplifiedUKCStage]string{}
+	for name, stage := range simplifiedUkcStageNames {
+		strings[stage] = name
 	}
+	return strings
+}()
 
-	stageNumber, ok := stageStringMap[stage]
+func NewSimplifiedUKCStageFromString(stage string) SimplifiedUKCStage {
+	stageNumber, ok := simplifiedUkcStageNames[stage]
 	if !ok {
 		stageNumber = None
 	}
@@ -38,6 +46,14 @@ var SimplifiedUkcStageWeights = map[SimplifiedUKCStage]float32{
 	Host:          1.0,
 }
 
+func (stage SimplifiedUKCStage) String() string {
+	if name, ok := simplifiedUkcStageStrings[stage]; ok {
+		return name
+	}
+
+	return "none"
+}
+
 func (stage SimplifiedUKCStage) GetVictim() Direction {
 	if stage == Outgoing {
 		return Source
@@ -75,7 +91,7 @@ func HostStage(source IPAddress, destination IPAddress) bool {
 }
 
 func InternalDifferentSubnetStage(source IPAddress, destination IPAddress) bool {
-	return source.IsInternal() && destination.IsInternal() && !source.IsSameSubnet(destination)
+	return source.IsInternal() && destination.IsInternal() && !source.IsSameZone(destination)
 }
 
 func InternalSameSubnetStage(source IPAddress, destination IPAddress) bool {
@@ -3,6 +3,7 @@ package structure
 import (
 	"fmt"
 	"rtkcsm/component/structure/set"
+	"strings"
 	"testing"
 )
 
@@ -81,3 +82,54 @@ func TestDetermineStage(t *testing.T) {
 		})
 	}
 }
+
+func TestDetermineStageWithZones(t *testing.T) {
+	defer ZoneManager.Reset()
+
+	if err := ZoneManager.Load(strings.NewReader(`{"internal": ["130.1.0.0/16"], "zones": {"dmz": ["130.1.1.0/24"], "office": ["130.1.8.0/21"]}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	stageMapper := SimplifiedUKCStageMapper{}
+	tests := []struct {
+		name       string
+		attackerIP string
+		victimIP   string
+		expected   SimplifiedUKCStage
+	}{
+		{name: "Public internal address", attackerIP: "1.1.1.1", victimIP: "130.1.1.5", expected: Incoming},
+		{name: "Private address is external", attackerIP: "10.0.0.1", victimIP: "130.1.1.5", expected: Incoming},
+		{name: "Zone larger than /24", attackerIP: "130.1.8.1", victimIP: "130.1.15.2", expected: SameZone},
+		{name: "Different zones", attackerIP: "130.1.1.5", victimIP: "130.1.8.1", expected: DifferentZone},
+		{name: "Zone and unnamed internal network", attackerIP: "130.1.1.5", victimIP: "130.1.2.1", expected: DifferentZone},
+		{name: "Outgoing", attackerIP: "130.1.8.1", victimIP: "10.0.0.1", expected: Outgoing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := stageMapper.DetermineStage(Alert{
+				SourceIP:      ParseIPAddress(tt.attackerIP),
+				DestinationIP: ParseIPAddress(tt.victimIP),
+			})
+			if err != nil || result != tt.expected {
+				t.Errorf("Test Case %s: Expected %v but got %v (%v)", tt.name, tt.expected, result, err)
+			}
+		})
+	}
+
+	if zone := ParseIPAddress("130.1.9.1").Zone(); zone != "office" {
+		t.Errorf("expected zone office, got %q", zone)
+	}
+}
+
+func TestSimplifiedUKCStageString(t *testing.T) {
+	for _, stage := range []SimplifiedUKCStage{Incoming, SameZone, DifferentZone, Outgoing, Host} {
+		if parsed := NewSimplifiedUKCStageFromString(stage.String()); parsed != stage {
+			t.Errorf("stage %d is named %q, which is parsed as %d", stage, stage.String(), parsed)
+		}
+	}
+
+	if name := None.String(); name != "none" {
+		t.Errorf("expected the name none instead of %q", name)
+	}
+}
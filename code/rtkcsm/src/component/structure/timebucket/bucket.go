@@ -40,3 +40,15 @@ func (t *Bucket[T]) GetAllBefore(time time.Time) []T {
 
 	return keys
 }
+
+// GetAllAfter returns values stored at or after a time
+func (t *Bucket[T]) GetAllAfter(time time.Time) []T {
+	keys := []T{}
+	for key, storedTime := range t.store {
+		if !storedTime.Before(time) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
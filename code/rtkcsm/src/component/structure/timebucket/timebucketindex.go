@@ -53,6 +53,21 @@ func (t TimeBucketIndex[T]) GetAllBefore(time time.Time) []T {
 	}
 }
 
+// GetAll returns all values without pruning empty buckets, so it can be used by concurrent readers
+func (t *TimeBucketIndex[T]) GetAll() []T {
+	t.bucketsMutex.RLock()
+	defer t.bucketsMutex.RUnlock()
+
+	values := []T{}
+	for _, bucket := range t.buckets {
+		for value := range bucket.store {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
 func (t *TimeBucketIndex[T]) deleteBucket(index int) {
 	t.bucketsMutex.Lock()
 	defer t.bucketsMutex.Unlock()
@@ -61,6 +76,27 @@ func (t *TimeBucketIndex[T]) deleteBucket(index int) {
 	t.buckets = append(t.buckets[:index], t.buckets[index+1:]...)
 }
 
+// Prune removes empty buckets and returns the number of remaining buckets
+func (t *TimeBucketIndex[T]) Prune() int {
+	t.bucketsMutex.Lock()
+	defer t.bucketsMutex.Unlock()
+
+	index := []time.Time{}
+	buckets := []*Bucket[T]{}
+
+	for i, bucket := range t.buckets {
+		if bucket.Len() > 0 {
+			index = append(index, t.index[i])
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	t.index = index
+	t.buckets = buckets
+
+	return len(t.buckets)
+}
+
 func (t *TimeBucketIndex[T]) addBucket(value T, time time.Time) *Bucket[T] {
 	t.bucketsMutex.Lock()
 	defer t.bucketsMutex.Unlock()
@@ -1,6 +1,9 @@
 package structure
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type UKCStage int
 
@@ -34,6 +37,34 @@ func (stage UKCStage) String() string {
 	return humanReadableNames[stage]
 }
 
+var ukcStageAbbreviations = map[string]UKCStage{
+	"R":  R,
+	"D1": D1,
+	"D2": D2,
+	"C2": C2,
+	"L":  L,
+	"S":  S,
+	"P":  P,
+	"E":  E,
+	"O":  O,
+	"X":  X,
+}
+
+// ParseUKCStage accepts the abbreviation or the human readable name of a stage
+func ParseUKCStage(name string) (UKCStage, error) {
+	if stage, ok := ukcStageAbbreviations[strings.ToUpper(name)]; ok {
+		return stage, nil
+	}
+
+	for _, stage := range ukcStageAbbreviations {
+		if strings.EqualFold(stage.String(), name) {
+			return stage, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown stage: %s", name)
+}
+
 func (stage UKCStage) ToUKCStages() []UKCStage {
 	panic("not implemented")
 }
@@ -0,0 +1,163 @@
+package structure
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type WriteAheadLogEntryType string
+
+const (
+	WriteAheadLogAlert          WriteAheadLogEntryType = "alert"
+	WriteAheadLogAddHostRisk    WriteAheadLogEntryType = "add-host-risk"
+	WriteAheadLogDeleteHostRisk WriteAheadLogEntryType = "delete-host-risk"
+	WriteAheadLogIncident       WriteAheadLogEntryType = "incident"
+	// the reorder buffer released all alerts, e.g. after no alert arrived within the window
+	WriteAheadLogFlushReorderBuffer WriteAheadLogEntryType = "flush-reorder-buffer"
+)
+
+// WriteAheadLogEntry records a change with the wall clock time it was applied at, so that a replay
+// sets the same timestamps, e.g. of reopened incidents
+type WriteAheadLogEntry[T Stage] struct {
+	Sequence uint64                 `json:"seq"`
+	Type     WriteAheadLogEntryType `json:"type"`
+	Time     time.Time              `json:"time"`
+	Alert    *EnrichedAlert[T]      `json:"alert,omitempty"`
+	HostRisk *HostRisk              `json:"host_risk,omitempty"`
+	GraphID  GraphID                `json:"graph_id,omitempty"`
+	Incident *Incident              `json:"incident,omitempty"`
+}
+
+// WriteAheadLog stores changes of the engine state since the last snapshot as JSON lines
+type WriteAheadLog[T Stage] struct {
+	file     *os.File
+	sequence uint64
+	mutex    sync.Mutex
+}
+
+func OpenWriteAheadLog[T Stage](path string) (*WriteAheadLog[T], error) {
+	file, err := os.OpenFile(filepath.Clean(path), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WriteAheadLog[T]{
+		file: file,
+	}, nil
+}
+
+// Append writes an entry and syncs it to the disk before it returns, so that every entry that was
+// applied to the engine state survives a crash of the process or the host
+func (w *WriteAheadLog[T]) Append(entry WriteAheadLogEntry[T]) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	entry.Sequence = w.sequence + 1
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error encoding write-ahead log entry: %s", err)
+	}
+
+	_, err = w.file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("error writing write-ahead log entry: %s", err)
+	}
+
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("error syncing write-ahead log entry: %s", err)
+	}
+
+	w.sequence = entry.Sequence
+
+	return nil
+}
+
+func (w *WriteAheadLog[T]) Sequence() uint64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.sequence
+}
+
+// Replay calls apply for all entries after a sequence number. An incomplete last entry
+// (e.g. after a crash) is cut off, so that new entries are appended after valid ones.
+func (w *WriteAheadLog[T]) Replay(after uint64, apply func(entry WriteAheadLogEntry[T]) error) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	w.sequence = after
+	validOffset := int64(0)
+	reader := bufio.NewReader(w.file)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				log.Printf("discarding incomplete write-ahead log entry at offset %d", validOffset)
+			}
+			break
+		} else if err != nil {
+			return err
+		}
+
+		var entry WriteAheadLogEntry[T]
+		if err := json.Unmarshal(line, &entry); err != nil {
+			log.Printf("discarding write-ahead log after offset %d: %s", validOffset, err)
+			break
+		}
+
+		validOffset += int64(len(line))
+
+		if entry.Sequence <= after {
+			continue
+		}
+
+		if err := apply(entry); err != nil {
+			return err
+		}
+
+		w.sequence = entry.Sequence
+	}
+
+	if err := w.file.Truncate(validOffset); err != nil {
+		return err
+	}
+
+	_, err := w.file.Seek(validOffset, io.SeekStart)
+	return err
+}
+
+// Truncate removes all entries but keeps the sequence number
+func (w *WriteAheadLog[T]) Truncate() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err := w.file.Seek(0, io.SeekStart)
+	return err
+}
+
+func (w *WriteAheadLog[T]) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+
+	return w.file.Close()
+}
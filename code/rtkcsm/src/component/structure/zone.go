@@ -0,0 +1,178 @@
+package structure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+type NetworkZone struct {
+	Name     string
+	Networks []*net.IPNet
+}
+
+// NetworkZoneConfiguration is the file format of zone definitions:
+// {"internal": ["10.0.0.0/8"], "zones": {"dmz": ["130.1.1.0/24"]}}
+type NetworkZoneConfiguration struct {
+	Internal []string            `json:"internal"`
+	Zones    map[string][]string `json:"zones"`
+}
+
+// Internal networks and named zones. Without any configuration RFC1918/RFC4193
+// addresses are internal and zones are approximated by /24 (IPv4) and /64 (IPv6) subnets.
+type NetworkZoneManager struct {
+	mutex    sync.RWMutex
+	internal []*net.IPNet
+	zones    []NetworkZone
+}
+
+func NewNetworkZoneManager() NetworkZoneManager {
+	return NetworkZoneManager{
+		mutex:    sync.RWMutex{},
+		internal: []*net.IPNet{},
+		zones:    []NetworkZone{},
+	}
+}
+
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %s: %s", cidr, err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+func (m *NetworkZoneManager) AddInternalNetworks(cidrs ...string) error {
+	networks, err := parseNetworks(cidrs)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.internal = append(m.internal, networks...)
+
+	return nil
+}
+
+func (m *NetworkZoneManager) AddZone(name string, cidrs ...string) error {
+	if name == "" {
+		return fmt.Errorf("zone name is empty")
+	}
+
+	networks, err := parseNetworks(cidrs)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, zone := range m.zones {
+		if zone.Name == name {
+			m.zones[i].Networks = append(zone.Networks, networks...)
+			return nil
+		}
+	}
+
+	m.zones = append(m.zones, NetworkZone{
+		Name:     name,
+		Networks: networks,
+	})
+
+	return nil
+}
+
+func (m *NetworkZoneManager) Load(reader io.Reader) error {
+	var configuration NetworkZoneConfiguration
+	if err := json.NewDecoder(reader).Decode(&configuration); err != nil {
+		return fmt.Errorf("error decoding zone configuration: %s", err)
+	}
+
+	if err := m.AddInternalNetworks(configuration.Internal...); err != nil {
+		return err
+	}
+
+	for name, cidrs := range configuration.Zones {
+		if err := m.AddZone(name, cidrs...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *NetworkZoneManager) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.internal = []*net.IPNet{}
+	m.zones = []NetworkZone{}
+}
+
+func (m *NetworkZoneManager) HasZones() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return len(m.zones) > 0
+}
+
+func (m *NetworkZoneManager) IsInternal(ip net.IP) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if len(m.internal) == 0 && len(m.zones) == 0 {
+		return ip.IsPrivate()
+	}
+
+	for _, network := range m.internal {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	for _, zone := range m.zones {
+		for _, network := range zone.Networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// GetZone returns the name of the zone with the most specific network containing the address
+func (m *NetworkZoneManager) GetZone(ip net.IP) string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	name := ""
+	longestPrefix := -1
+
+	for _, zone := range m.zones {
+		for _, network := range zone.Networks {
+			prefix, _ := network.Mask.Size()
+			if prefix > longestPrefix && network.Contains(ip) {
+				name = zone.Name
+				longestPrefix = prefix
+			}
+		}
+	}
+
+	return name
+}
+
+var ZoneManager = NewNetworkZoneManager()
@@ -0,0 +1,386 @@
+package reader
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// CEFAlertReader reads ArcSight Common Event Format lines, optionally preceded by a syslog header:
+//
+//	CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+//
+// The fields are mapped as follows:
+//
+//	Signature ID                   SignatureId (numeric ids are used, others are hashed)
+//	Name                           Cause
+//	Severity                       Severity, 0-10 divided by 10 or Low (0.3), Medium (0.6), High (0.8), Very-High (1)
+//	src or c6a2                    SourceIP
+//	dst or c6a3                    DestinationIP
+//	dvc or c6a1                    SourceIP and DestinationIP of host alerts without src and dst
+//	rt, end or start               Timestamp in milliseconds since the epoch or as MMM dd yyyy HH:mm:ss[.SSS][ zzz],
+//	                               the time of reading if not set
+//	csN with csNLabel=confidence   Confidence, Low (0.25), Medium (0.5), High (1) or 0-100 (1 if not set)
+//	csN with csNLabel=label        Label
+//
+// In the header "\|" and "\\" are escaped, in the extension "\=", "\\", "\n" and "\r". Values of the
+// extension end at the last space before the next key, unescaped equals signs that do not follow a
+// key are kept in the value.
+type CEFAlertReader[T structure.Stage, K structure.Stage] struct{}
+
+type cefEvent struct {
+	Version       string
+	DeviceVendor  string
+	DeviceProduct string
+	DeviceVersion string
+	SignatureId   string
+	Name          string
+	Severity      string
+	Extension     map[string]string
+}
+
+var cefSeverityMapping = map[string]float32{
+	"low":       0.3,
+	"medium":    0.6,
+	"high":      0.8,
+	"very-high": 1,
+}
+
+func (cefAlertReader *CEFAlertReader[T, K]) ChannelAlerts(rtkcsm behaviour.RTKCSM[T, K], reader io.ReadCloser) error {
+	defer reader.Close()
+	return channelAlerts(rtkcsm, reader, cefAlertReader.decode, decodedAlerts, logAlertError)
+}
+
+func (cefAlertReader *CEFAlertReader[T, K]) decode(line []byte) []structure.Alert {
+	event, err := parseCEF(string(line))
+	if err != nil {
+		log.Printf("error decoding: %s (%s)\n", err, line)
+		return nil
+	}
+
+	source := firstValue(event.Extension, "src", "c6a2")
+	destination := firstValue(event.Extension, "dst", "c6a3")
+	if source == "" && destination == "" {
+		source = firstValue(event.Extension, "dvc", "c6a1")
+		destination = source
+	}
+
+	if source == "" || destination == "" {
+		log.Printf("error parsing an ip address of a cef alert: src: %s, dst: %s\n", source, destination)
+		return nil
+	}
+
+	timestamp := time.Now()
+	if value := firstValue(event.Extension, "rt", "end", "start"); value != "" {
+		timestamp, err = parseDeviceTime(value, "")
+		if err != nil {
+			log.Printf("error parsing time: %s", err)
+			return nil
+		}
+	}
+
+	severity, ok := cefSeverityMapping[strings.ToLower(event.Severity)]
+	if !ok {
+		severity = parseSeverity(event.Severity, 10)
+	}
+
+	customStrings := cefCustomStrings(event.Extension)
+
+	confidence := float32(1)
+	if value, ok := customStrings["confidence"]; ok {
+		confidence = parseConfidence(value)
+	}
+
+	return []structure.Alert{
+		{
+			Timestamp:     timestamp,
+			SourceIP:      structure.ParseIPAddress(source),
+			DestinationIP: structure.ParseIPAddress(destination),
+			Severity:      severity,
+			Confidence:    confidence,
+			SignatureId:   parseSignatureId(event.SignatureId),
+			Cause:         event.Name,
+			Label:         customStrings["label"],
+		},
+	}
+}
+
+// parseCEF ignores everything before the CEF header, e.g. a syslog header
+func parseCEF(line string) (cefEvent, error) {
+	start := strings.Index(line, "CEF:")
+	if start < 0 {
+		return cefEvent{}, fmt.Errorf("missing CEF header")
+	}
+
+	fields, extension, err := splitHeader(line[start+len("CEF:"):], 7)
+	if err != nil {
+		return cefEvent{}, err
+	}
+
+	extensionFields, err := parseCEFExtension(extension)
+	if err != nil {
+		return cefEvent{}, err
+	}
+
+	return cefEvent{
+		Version:       fields[0],
+		DeviceVendor:  fields[1],
+		DeviceProduct: fields[2],
+		DeviceVersion: fields[3],
+		SignatureId:   fields[4],
+		Name:          fields[5],
+		Severity:      fields[6],
+		Extension:     extensionFields,
+	}, nil
+}
+
+// splitHeader returns count fields separated by pipes, in which "\|" and "\\" are escaped, and the
+// remaining text after the last pipe
+func splitHeader(text string, count int) ([]string, string, error) {
+	fields := make([]string, 0, count)
+	field := strings.Builder{}
+
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && i+1 < len(text) && (text[i+1] == '|' || text[i+1] == '\\'):
+			i++
+			field.WriteByte(text[i])
+		case text[i] == '|':
+			fields = append(fields, field.String())
+			field.Reset()
+
+			if len(fields) == count {
+				return fields, text[i+1:], nil
+			}
+		default:
+			field.WriteByte(text[i])
+		}
+	}
+
+	return nil, "", fmt.Errorf("header with %d instead of %d fields", len(fields), count)
+}
+
+// parseCEFExtension parses space-separated key=value pairs, values can contain spaces
+func parseCEFExtension(extension string) (map[string]string, error) {
+	fields := map[string]string{}
+	extension = strings.TrimRight(extension, " \r\n")
+	if strings.TrimSpace(extension) == "" {
+		return fields, nil
+	}
+
+	key := ""
+	// the unescaped text since the last unescaped equals sign
+	text := strings.Builder{}
+
+	for i := 0; i < len(extension); i++ {
+		character := extension[i]
+
+		if character == '\\' && i+1 < len(extension) {
+			i++
+			switch extension[i] {
+			case '=', '\\':
+				text.WriteByte(extension[i])
+			case 'n':
+				text.WriteByte('\n')
+			case 'r':
+				text.WriteByte('\r')
+			default:
+				// other characters do not need to be escaped
+				text.WriteByte('\\')
+				text.WriteByte(extension[i])
+			}
+			continue
+		}
+
+		if character != '=' {
+			text.WriteByte(character)
+			continue
+		}
+
+		// the key of the next pair follows the last space of the text
+		value := text.String()
+		if key == "" {
+			key = strings.TrimLeft(value, " ")
+			if !isCEFKey(key) {
+				return nil, fmt.Errorf("invalid extension key: %q", key)
+			}
+			text.Reset()
+			continue
+		}
+
+		separator := strings.LastIndexByte(value, ' ')
+		if separator < 0 || !isCEFKey(value[separator+1:]) {
+			// an unescaped equals sign in a value, e.g. of an URL
+			text.WriteByte(character)
+			continue
+		}
+
+		fields[key] = strings.TrimRight(value[:separator], " ")
+		key = value[separator+1:]
+		text.Reset()
+	}
+
+	if key == "" {
+		return nil, fmt.Errorf("extension without key: %q", extension)
+	}
+	fields[key] = text.String()
+
+	return fields, nil
+}
+
+func isCEFKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for _, character := range key {
+		if !unicode.IsLetter(character) && !unicode.IsDigit(character) && character != '_' && character != '.' && character != '[' && character != ']' {
+			return false
+		}
+	}
+	return true
+}
+
+// cefCustomStrings maps the lowercase labels of custom strings (cs1 to cs6) to their values
+func cefCustomStrings(extension map[string]string) map[string]string {
+	customStrings := map[string]string{}
+	for i := 1; i <= 6; i++ {
+		key := fmt.Sprintf("cs%d", i)
+		label, hasLabel := extension[key+"Label"]
+		value, hasValue := extension[key]
+		if hasLabel && hasValue {
+			customStrings[strings.ToLower(label)] = value
+		}
+	}
+	return customStrings
+}
+
+func firstValue(fields map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := fields[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseSeverity divides the severity by its maximum, unknown severities are mapped to 1
+func parseSeverity(value string, maxSeverity float32) float32 {
+	severity, err := strconv.ParseFloat(strings.TrimSpace(value), 32)
+	if err != nil || severity < 0 || float32(severity) > maxSeverity {
+		return 1
+	}
+	return float32(severity) / maxSeverity
+}
+
+// parseConfidence accepts the confidence levels of Suricata or a percentage
+func parseConfidence(value string) float32 {
+	if confidence, ok := confidenceLevelMapping[value]; ok {
+		return confidence
+	}
+
+	confidence, err := strconv.ParseFloat(strings.TrimSpace(value), 32)
+	if err != nil || confidence < 0 || confidence > 100 {
+		log.Printf("error mapping confidence level: %s\n", value)
+		return 1
+	}
+	return float32(confidence) / 100
+}
+
+var deviceTimeLayouts = []string{
+	"Jan 02 2006 15:04:05",
+	"Jan 02 2006 15:04:05.000",
+	"Jan 02 2006 15:04:05 MST",
+	"Jan 02 2006 15:04:05.000 MST",
+	"Jan 02 2006 15:04:05 -0700",
+	"Jan 02 2006 15:04:05.000 -0700",
+	time.RFC3339Nano,
+}
+
+// parseDeviceTime parses milliseconds since the epoch, the date formats of CEF or a date with the
+// given Java date format (e.g. LEEF's devTimeFormat)
+func parseDeviceTime(value string, javaFormat string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if milliseconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(milliseconds), nil
+	}
+
+	layouts := deviceTimeLayouts
+	if javaFormat != "" {
+		layouts = []string{fromJavaDateFormat(javaFormat)}
+	}
+
+	for _, layout := range layouts {
+		if timestamp, err := time.Parse(layout, value); err == nil {
+			return timestamp, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown date format: %s", value)
+}
+
+// javaDateFormatElements are replaced longest first
+var javaDateFormatElements = []struct {
+	java   string
+	layout string
+}{
+	{"yyyy", "2006"},
+	{"MMMM", "January"},
+	{"EEEE", "Monday"},
+	{"MMM", "Jan"},
+	{"EEE", "Mon"},
+	{"SSS", "000"},
+	{"XXX", "Z07:00"},
+	{"yy", "06"},
+	{"MM", "01"},
+	{"dd", "02"},
+	{"HH", "15"},
+	{"hh", "03"},
+	{"mm", "04"},
+	{"ss", "05"},
+	{"a", "PM"},
+	{"z", "MST"},
+	{"Z", "-0700"},
+}
+
+func fromJavaDateFormat(format string) string {
+	layout := strings.Builder{}
+
+	for i := 0; i < len(format); {
+		if format[i] == '\'' {
+			// quoted text, e.g. 'T'
+			end := strings.IndexByte(format[i+1:], '\'')
+			if end < 0 {
+				layout.WriteString(format[i+1:])
+				break
+			}
+			layout.WriteString(format[i+1 : i+1+end])
+			i += end + 2
+			continue
+		}
+
+		matched := false
+		for _, element := range javaDateFormatElements {
+			if strings.HasPrefix(format[i:], element.java) {
+				layout.WriteString(element.layout)
+				i += len(element.java)
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			layout.WriteByte(format[i])
+			i++
+		}
+	}
+
+	return layout.String()
+}
@@ -0,0 +1,284 @@
+package reader
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"reflect"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCEFExtension(t *testing.T) {
+	tests := []struct {
+		name      string
+		extension string
+		expected  map[string]string
+		invalid   bool
+	}{
+		{"empty", "", map[string]string{}, false},
+		{"single", "src=10.0.0.1", map[string]string{"src": "10.0.0.1"}, false},
+		{"pairs", "src=10.0.0.1 dst=10.0.0.2 spt=1232", map[string]string{"src": "10.0.0.1", "dst": "10.0.0.2", "spt": "1232"}, false},
+		{"spaces in values", "msg=Detected a threat. No action needed act=blocked", map[string]string{"msg": "Detected a threat. No action needed", "act": "blocked"}, false},
+		{"several spaces before a key", "msg=threat   act=blocked", map[string]string{"msg": "threat", "act": "blocked"}, false},
+		{"trailing spaces", "act=blocked  \r\n", map[string]string{"act": "blocked"}, false},
+		{"escaped equals sign", `msg=a\=b c\=d act=blocked`, map[string]string{"msg": "a=b c=d", "act": "blocked"}, false},
+		{"escaped equals sign before a space", `msg=key\= act=blocked`, map[string]string{"msg": "key=", "act": "blocked"}, false},
+		{"escaped backslash", `filePath=C:\\Windows\\System32 act=blocked`, map[string]string{"filePath": `C:\Windows\System32`, "act": "blocked"}, false},
+		{"escaped backslash before equals sign", `msg=a\\=b`, map[string]string{"msg": `a\=b`}, false},
+		{"escaped newlines", `msg=first\nsecond\rthird`, map[string]string{"msg": "first\nsecond\rthird"}, false},
+		{"unescaped pipe", "msg=a|b", map[string]string{"msg": "a|b"}, false},
+		{"escaped pipe", `msg=a\|b`, map[string]string{"msg": `a\|b`}, false},
+		{"unescaped equals sign in an url", "request=http://example.com/?a=1&b=2 act=blocked", map[string]string{"request": "http://example.com/?a=1&b=2", "act": "blocked"}, false},
+		{"unescaped equals sign after a space", "msg=(a b=c) act=blocked", map[string]string{"msg": "(a", "b": "c)", "act": "blocked"}, false},
+		{"empty value", "msg= act=blocked", map[string]string{"msg": "", "act": "blocked"}, false},
+		{"trailing backslash", `msg=a\`, map[string]string{"msg": `a\`}, false},
+		{"custom strings", "cs1Label=confidence cs1=High cs2Label=label cs2=malicious", map[string]string{"cs1Label": "confidence", "cs1": "High", "cs2Label": "label", "cs2": "malicious"}, false},
+		{"missing key", "=10.0.0.1", nil, true},
+		{"key with spaces", "source address=10.0.0.1", nil, true},
+		{"text without key", "10.0.0.1", nil, true},
+	}
+
+	for _, test := range tests {
+		fields, err := parseCEFExtension(test.extension)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%s: extension %q was accepted as %v", test.name, test.extension, fields)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		} else if !reflect.DeepEqual(fields, test.expected) {
+			t.Errorf("%s: expected %q instead of %q", test.name, test.expected, fields)
+		}
+	}
+}
+
+func TestCEFHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected cefEvent
+		invalid  bool
+	}{
+		{
+			"syslog header",
+			"<134>May  1 10:00:00 fw01 CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2",
+			cefEvent{"0", "Security", "threatmanager", "1.0", "100", "worm successfully stopped", "10", map[string]string{"src": "10.0.0.1", "dst": "2.1.2.2"}},
+			false,
+		},
+		{
+			"escaped pipe and backslash",
+			`CEF:0|security|threat\|manager|1.0|100|detected a \\ in packet|High|`,
+			cefEvent{"0", "security", "threat|manager", "1.0", "100", `detected a \ in packet`, "High", map[string]string{}},
+			false,
+		},
+		{
+			"equals sign in the header",
+			"CEF:1|vendor|product|1.0|a=b|name=value|5|act=blocked",
+			cefEvent{"1", "vendor", "product", "1.0", "a=b", "name=value", "5", map[string]string{"act": "blocked"}},
+			false,
+		},
+		{"missing header", "src=10.0.0.1 dst=2.1.2.2", cefEvent{}, true},
+		{"missing fields", "CEF:0|vendor|product|1.0|100|name", cefEvent{}, true},
+		{"escaped last pipe", `CEF:0|vendor|product|1.0|100|name|5\|src=10.0.0.1`, cefEvent{}, true},
+	}
+
+	for _, test := range tests {
+		event, err := parseCEF(test.line)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%s: line %q was accepted as %v", test.name, test.line, event)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		} else if !reflect.DeepEqual(event, test.expected) {
+			t.Errorf("%s: expected %v instead of %v", test.name, test.expected, event)
+		}
+	}
+}
+
+func TestLEEF(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected leefEvent
+		invalid  bool
+	}{
+		{
+			"version 1.0",
+			"<13>May  1 10:00:00 ids01 LEEF:1.0|Vendor|IDS|2.0|Port Scan|src=10.0.0.1\tdst=10.0.0.2\tsev=7\tmsg=a=b c",
+			leefEvent{"1.0", "Vendor", "IDS", "2.0", "Port Scan", map[string]string{"src": "10.0.0.1", "dst": "10.0.0.2", "sev": "7", "msg": "a=b c"}},
+			false,
+		},
+		{
+			"version 2.0 with a delimiter",
+			"LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^sev=5",
+			leefEvent{"2.0", "Lancope", "StealthWatch", "1.0", "41", map[string]string{"src": "10.0.1.8", "dst": "10.0.0.5", "sev": "5"}},
+			false,
+		},
+		{
+			"version 2.0 with a hex delimiter",
+			"LEEF:2.0|Vendor|Product|1.0|41|0x7C|src=10.0.1.8|dst=10.0.0.5",
+			leefEvent{"2.0", "Vendor", "Product", "1.0", "41", map[string]string{"src": "10.0.1.8", "dst": "10.0.0.5"}},
+			false,
+		},
+		{
+			"version 2.0 with the default delimiter",
+			"LEEF:2.0|Vendor|Product|1.0|41||src=10.0.1.8\tdst=10.0.0.5\t",
+			leefEvent{"2.0", "Vendor", "Product", "1.0", "41", map[string]string{"src": "10.0.1.8", "dst": "10.0.0.5"}},
+			false,
+		},
+		{"invalid delimiter", "LEEF:2.0|Vendor|Product|1.0|41|xZZ|src=10.0.1.8", leefEvent{}, true},
+		{"missing delimiter", "LEEF:2.0|Vendor|Product|1.0|41", leefEvent{}, true},
+		{"attribute without value", "LEEF:1.0|Vendor|Product|1.0|41|src", leefEvent{}, true},
+	}
+
+	for _, test := range tests {
+		event, err := parseLEEF(test.line)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%s: line %q was accepted as %v", test.name, test.line, event)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		} else if !reflect.DeepEqual(event, test.expected) {
+			t.Errorf("%s: expected %v instead of %v", test.name, test.expected, event)
+		}
+	}
+}
+
+func TestDeviceTime(t *testing.T) {
+	expected := time.Date(2024, 5, 1, 10, 0, 0, 123_000_000, time.UTC)
+
+	tests := []struct {
+		value      string
+		javaFormat string
+	}{
+		{"1714557600123", ""},
+		{"May 01 2024 10:00:00.123", ""},
+		{"May 01 2024 10:00:00.123 UTC", ""},
+		{"May 01 2024 12:00:00.123 +0200", ""},
+		{"2024-05-01T10:00:00.123Z", ""},
+		{"2024-05-01 10:00:00.123", "yyyy-MM-dd HH:mm:ss.SSS"},
+		{"01/05/24 10:00:00.123 AM +0000", "dd/MM/yy hh:mm:ss.SSS a Z"},
+		{"2024-05-01T10:00:00.123+00:00", "yyyy-MM-dd'T'HH:mm:ss.SSSXXX"},
+	}
+
+	for _, test := range tests {
+		timestamp, err := parseDeviceTime(test.value, test.javaFormat)
+		if err != nil {
+			t.Errorf("%s: %s", test.value, err)
+		} else if !timestamp.Equal(expected) {
+			t.Errorf("%s: expected %s instead of %s", test.value, expected, timestamp)
+		}
+	}
+
+	if _, err := parseDeviceTime("yesterday", ""); err == nil {
+		t.Errorf("unknown date format was accepted")
+	}
+}
+
+// readerRelation is the part of a relation that is set by alert readers
+type readerRelation struct {
+	from        string
+	to          string
+	stage       structure.SimplifiedUKCStage
+	severity    string
+	signatureId uint32
+	timestamp   int64
+}
+
+func readerRelations(rtkcsm behaviour.RTKCSM[structure.SimplifiedUKCStage, structure.UKCStage], id structure.GraphID) []readerRelation {
+	relations := []readerRelation{}
+	for _, directedRelation := range rtkcsm.GetGraph(id).GetPreComputed().PreComputedDirectedRelations {
+		relations = append(relations, readerRelation{
+			from:        directedRelation.From,
+			to:          directedRelation.To,
+			stage:       directedRelation.MetaStage,
+			severity:    fmt.Sprintf("%.2f", directedRelation.Severity),
+			signatureId: directedRelation.SignatureId,
+			timestamp:   directedRelation.Timestamp,
+		})
+	}
+	sort.Slice(relations, func(i, j int) bool { return relations[i].timestamp < relations[j].timestamp })
+
+	return relations
+}
+
+func TestCEFAndLEEFAlertReaders(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC).UnixMilli()
+
+	cefLines := strings.Join([]string{
+		fmt.Sprintf(`<134>May  1 10:00:00 fw01 CEF:0|Vendor|Firewall|1.0|2019401|Inbound \| scan|8|src=94.141.120.36 dst=172.16.42.42 rt=%d cs1Label=confidence cs1=Medium`, start),
+		`CEF:0|Vendor|EDR|1.0|malware-detected|Malware detected|Very-High|dvc=172.16.42.42 rt=May 01 2024 10:00:05 UTC msg=file\=C:\\temp\\a.exe`,
+		`CEF:0|Vendor|IDS|1.0|100|Lateral movement|3|src=172.16.42.42 dst=172.16.42.1 rt=May 01 2024 10:00:10.000 +0000`,
+		// alerts without addresses are skipped
+		`CEF:0|Vendor|IDS|1.0|100|Without addresses|3|rt=May 01 2024 10:00:15 UTC`,
+	}, "\n")
+
+	leefLines := strings.Join([]string{
+		"<13>May  1 10:00:00 ids01 LEEF:1.0|Vendor|IDS|2.0|Port Scan|src=94.141.120.36\tdst=172.16.42.42\tsev=5\tdevTime=May 01 2024 10:00:00",
+		"LEEF:2.0|Vendor|IDS|2.0|4711|^|cat=Lateral Movement^src=172.16.42.42^dst=172.16.42.1^sev=10^devTime=2024-05-01 10:00:10^devTimeFormat=yyyy-MM-dd HH:mm:ss",
+	}, "\n")
+
+	cefRTKCSM := newTestRTKCSM()
+	cefReader := CEFAlertReader[structure.SimplifiedUKCStage, structure.UKCStage]{}
+	if err := cefReader.ChannelAlerts(cefRTKCSM, io.NopCloser(strings.NewReader(cefLines))); err != nil {
+		t.Fatal(err)
+	}
+
+	leefRTKCSM := newTestRTKCSM()
+	leefReader := LEEFAlertReader[structure.SimplifiedUKCStage, structure.UKCStage]{}
+	if err := leefReader.ChannelAlerts(leefRTKCSM, io.NopCloser(strings.NewReader(leefLines))); err != nil {
+		t.Fatal(err)
+	}
+
+	malwareHash := fnv.New32a()
+	malwareHash.Write([]byte("malware-detected"))
+	portScanHash := fnv.New32a()
+	portScanHash.Write([]byte("Port Scan"))
+
+	expected := map[string][]readerRelation{
+		"cef": {
+			{"94.141.120.36", "172.16.42.42", structure.Incoming, "0.80", 2019401, start},
+			{"172.16.42.42", "172.16.42.42", structure.Host, "1.00", malwareHash.Sum32(), start + 5000},
+			{"172.16.42.42", "172.16.42.1", structure.SameZone, "0.30", 100, start + 10000},
+		},
+		"leef": {
+			{"94.141.120.36", "172.16.42.42", structure.Incoming, "0.50", portScanHash.Sum32(), start},
+			{"172.16.42.42", "172.16.42.1", structure.SameZone, "1.00", 4711, start + 10000},
+		},
+	}
+
+	for format, rtkcsm := range map[string]behaviour.RTKCSM[structure.SimplifiedUKCStage, structure.UKCStage]{"cef": cefRTKCSM, "leef": leefRTKCSM} {
+		graphList := rtkcsm.GetGraphList(-1)
+		if graphList.Count != 1 {
+			t.Errorf("%s: expected one graph instead of %d", format, graphList.Count)
+			continue
+		}
+
+		if relations := readerRelations(rtkcsm, graphList.Graphs[0].ID); !reflect.DeepEqual(relations, expected[format]) {
+			t.Errorf("%s: expected relations %v instead of %v", format, expected[format], relations)
+		}
+	}
+
+	// the custom string labeled confidence sets the confidence of the alert
+	for _, relation := range cefRTKCSM.GetGraph(cefRTKCSM.GetGraphList(-1).Graphs[0].ID).GetPreComputed().PreComputedDirectedRelations {
+		if relation.From == "94.141.120.36" && relation.Confidence != 0.5 {
+			t.Errorf("expected confidence 0.5 of the first cef alert instead of %.2f", relation.Confidence)
+		}
+	}
+}
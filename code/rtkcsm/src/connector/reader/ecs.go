@@ -0,0 +1,191 @@
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ECSSeverityNormalization maps event.severity linearly to a severity between 0 and 1. The meaning of
+// event.severity depends on the source, so Lowest can be greater than Highest for sources where
+// lower values are more severe (e.g. 4:1 for Suricata).
+type ECSSeverityNormalization struct {
+	Lowest  float32 // maps to 0
+	Highest float32 // maps to 1
+}
+
+// DefaultECSSeverityNormalization matches the severity of Elastic detection rules (21, 47, 73, 99)
+var DefaultECSSeverityNormalization = ECSSeverityNormalization{
+	Lowest:  0,
+	Highest: 100,
+}
+
+// ParseECSSeverityNormalization parses LOWEST:HIGHEST, e.g. 0:100 or 4:1
+func ParseECSSeverityNormalization(value string) (ECSSeverityNormalization, error) {
+	lowestString, highestString, found := strings.Cut(value, ":")
+	if !found {
+		return ECSSeverityNormalization{}, fmt.Errorf("invalid severity normalization: %s", value)
+	}
+
+	lowest, err := strconv.ParseFloat(strings.TrimSpace(lowestString), 32)
+	if err != nil {
+		return ECSSeverityNormalization{}, fmt.Errorf("invalid lowest severity: %w", err)
+	}
+	highest, err := strconv.ParseFloat(strings.TrimSpace(highestString), 32)
+	if err != nil {
+		return ECSSeverityNormalization{}, fmt.Errorf("invalid highest severity: %w", err)
+	}
+
+	if lowest == highest {
+		return ECSSeverityNormalization{}, fmt.Errorf("lowest and highest severity are equal: %s", value)
+	}
+
+	return ECSSeverityNormalization{
+		Lowest:  float32(lowest),
+		Highest: float32(highest),
+	}, nil
+}
+
+// Normalize clamps severities outside of the range
+func (n ECSSeverityNormalization) Normalize(severity float32) float32 {
+	if n.Lowest == n.Highest {
+		return 1
+	}
+	return min(max((severity-n.Lowest)/(n.Highest-n.Lowest), 0), 1)
+}
+
+// ECSAlertReader reads alerts in the Elastic Common Schema, e.g. of the Suricata and Zeek modules of
+// Filebeat or of Elastic detection rules. Fields can be nested objects or dotted keys. Alerts without
+// source or destination are host alerts of host.ip.
+type ECSAlertReader[T structure.Stage, K structure.Stage] struct {
+	Severity ECSSeverityNormalization // DefaultECSSeverityNormalization if not set
+}
+
+func (ecsAlertReader *ECSAlertReader[T, K]) ChannelAlerts(rtkcsm behaviour.RTKCSM[T, K], reader io.ReadCloser) error {
+	defer reader.Close()
+	return channelAlerts(rtkcsm, reader, ecsAlertReader.decode, decodedAlerts, logAlertError)
+}
+
+func (ecsAlertReader *ECSAlertReader[T, K]) decode(line []byte) []structure.Alert {
+	var document map[string]any
+
+	if err := json.Unmarshal(line, &document); err != nil {
+		log.Printf("error decoding: %s (%s)\n", err, line)
+		return nil
+	}
+
+	// flows and other events are not alerts, detection rules create signals
+	if kind := ecsString(document, "event.kind"); kind != "" && kind != "alert" && kind != "signal" {
+		return nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, ecsString(document, "@timestamp"))
+	if err != nil {
+		log.Printf("error parsing time: %s", err)
+		return nil
+	}
+
+	source := ecsString(document, "source.ip")
+	destination := ecsString(document, "destination.ip")
+	host := ecsString(document, "host.ip")
+
+	switch {
+	case source != "" && destination != "":
+	case source != "" && host != "":
+		destination = host
+	case source == "" && destination == "" && host != "":
+		source = host
+		destination = host
+	default:
+		log.Printf("error parsing an ip address of an ecs alert: source: %s, destination: %s, host: %s\n", source, destination, host)
+		return nil
+	}
+
+	normalization := ecsAlertReader.Severity
+	if normalization == (ECSSeverityNormalization{}) {
+		normalization = DefaultECSSeverityNormalization
+	}
+
+	// unknown severities are mapped to 1
+	severity := float32(1)
+	if value, ok := ecsNumber(document, "event.severity"); ok {
+		severity = normalization.Normalize(value)
+	}
+
+	cause := ecsString(document, "rule.name")
+	if cause == "" {
+		cause = ecsString(document, "message")
+	}
+
+	return []structure.Alert{
+		{
+			Timestamp:     timestamp,
+			SourceIP:      structure.ParseIPAddress(source),
+			DestinationIP: structure.ParseIPAddress(destination),
+			Severity:      severity,
+			Confidence:    1,
+			SignatureId:   parseSignatureId(ecsString(document, "rule.id")),
+			Cause:         cause,
+			Label:         ecsString(document, "label"),
+		},
+	}
+}
+
+// ecsField looks up a field given as nested objects ({"source": {"ip": ...}}) or with dotted keys
+// ({"source.ip": ...}). The first value of an array is used (e.g. for host.ip).
+func ecsField(document map[string]any, path string) any {
+	if value, ok := document[path]; ok {
+		if values, ok := value.([]any); ok {
+			if len(values) == 0 {
+				return nil
+			}
+			return values[0]
+		}
+		return value
+	}
+
+	for i := strings.Index(path, "."); i >= 0; {
+		if nested, ok := document[path[:i]].(map[string]any); ok {
+			if value := ecsField(nested, path[i+1:]); value != nil {
+				return value
+			}
+		}
+
+		next := strings.Index(path[i+1:], ".")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+
+	return nil
+}
+
+func ecsString(document map[string]any, path string) string {
+	switch value := ecsField(document, path).(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+func ecsNumber(document map[string]any, path string) (float32, bool) {
+	switch value := ecsField(document, path).(type) {
+	case float64:
+		return float32(value), true
+	case string:
+		number, err := strconv.ParseFloat(value, 32)
+		return float32(number), err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,91 @@
+package reader
+
+import (
+	"hash/fnv"
+	"rtkcsm/component/structure"
+	"testing"
+	"time"
+)
+
+func TestECSDecode(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	hash := fnv.New32a()
+	hash.Write([]byte("2f8a1226-5720-437d-9c20-e0029deb6194"))
+
+	tests := []struct {
+		name     string
+		line     string
+		expected []structure.Alert
+	}{
+		{
+			"nested fields of the filebeat suricata module",
+			`{"@timestamp":"2024-05-01T10:00:00.000Z","event":{"kind":"alert","severity":1},"source":{"ip":"94.141.120.36"},"destination":{"ip":"172.16.42.42"},"rule":{"id":"2019401","name":"ET SCAN Suspicious inbound"}}`,
+			[]structure.Alert{{
+				Timestamp:     start,
+				SourceIP:      structure.ParseIPAddress("94.141.120.36"),
+				DestinationIP: structure.ParseIPAddress("172.16.42.42"),
+				Severity:      1,
+				Confidence:    1,
+				SignatureId:   2019401,
+				Cause:         "ET SCAN Suspicious inbound",
+			}},
+		},
+		{
+			"dotted keys and an array of host addresses",
+			`{"@timestamp":"2024-05-01T10:00:05.000Z","event.kind":"alert","event.severity":"3","host.ip":["172.16.42.42","fe80::1"],"rule.id":2100498,"rule.name":"GPL ATTACK_RESPONSE id check returned root","label":"malicious"}`,
+			[]structure.Alert{{
+				Timestamp:     start.Add(5 * time.Second),
+				SourceIP:      structure.ParseIPAddress("172.16.42.42"),
+				DestinationIP: structure.ParseIPAddress("172.16.42.42"),
+				Severity:      float32(1) / 3,
+				Confidence:    1,
+				SignatureId:   2100498,
+				Cause:         "GPL ATTACK_RESPONSE id check returned root",
+				Label:         "malicious",
+			}},
+		},
+		{
+			"signal of a detection rule with a source reported by the host",
+			`{"@timestamp":"2024-05-01T12:00:10+02:00","event":{"kind":"signal"},"source":{"ip":"172.16.42.42"},"host":{"ip":["172.16.42.1"]},"rule":{"id":"2f8a1226-5720-437d-9c20-e0029deb6194"},"message":"Potential Lateral Movement"}`,
+			[]structure.Alert{{
+				Timestamp:     start.Add(10 * time.Second),
+				SourceIP:      structure.ParseIPAddress("172.16.42.42"),
+				DestinationIP: structure.ParseIPAddress("172.16.42.1"),
+				Severity:      1,
+				Confidence:    1,
+				SignatureId:   hash.Sum32(),
+				Cause:         "Potential Lateral Movement",
+			}},
+		},
+		{"flow", `{"@timestamp":"2024-05-01T10:00:01.000Z","event":{"kind":"event"},"source":{"ip":"94.141.120.36"},"destination":{"ip":"172.16.42.42"}}`, nil},
+		{"missing addresses", `{"@timestamp":"2024-05-01T10:00:15.000Z","event":{"kind":"alert","severity":1},"rule":{"id":"1"}}`, nil},
+		{"invalid timestamp", `{"@timestamp":"May  1 10:00:00","event":{"kind":"alert"},"host":{"ip":"172.16.42.42"}}`, nil},
+		{"invalid json", `not json`, nil},
+	}
+
+	normalization, err := ParseECSSeverityNormalization("4:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alertReader := ECSAlertReader[structure.SimplifiedUKCStage, structure.UKCStage]{Severity: normalization}
+	for _, test := range tests {
+		expectAlerts(t, test.name, alertReader.decode([]byte(test.line)), test.expected)
+	}
+}
+
+func TestECSSeverityNormalization(t *testing.T) {
+	severities := map[float32]float32{-10: 0, 0: 0, 21: 0.21, 99: 0.99, 150: 1}
+	for severity, expected := range severities {
+		if normalized := DefaultECSSeverityNormalization.Normalize(severity); normalized != expected {
+			t.Errorf("expected severity %.2f to be normalized to %.2f instead of %.2f", severity, expected, normalized)
+		}
+	}
+
+	for _, invalid := range []string{"", "100", "1:1", "low:high", "0:"} {
+		if _, err := ParseECSSeverityNormalization(invalid); err == nil {
+			t.Errorf("severity normalization %q was accepted", invalid)
+		}
+	}
+}
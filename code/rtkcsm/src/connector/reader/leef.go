@@ -0,0 +1,160 @@
+package reader
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LEEFAlertReader reads IBM QRadar Log Event Extended Format lines of version 1.0 and 2.0, optionally
+// preceded by a syslog header:
+//
+//	LEEF:1.0|Vendor|Product|Version|Event ID|Attributes
+//	LEEF:2.0|Vendor|Product|Version|Event ID|Delimiter|Attributes
+//
+// The attributes are separated by tabs or the delimiter of version 2.0 (a character or its hex code,
+// e.g. ^ or x5E). The fields are mapped as follows:
+//
+//	Event ID                    SignatureId (numeric ids are used, others are hashed) and Cause
+//	cat                         Cause, prefixed to the event id
+//	src                         SourceIP
+//	dst                         DestinationIP
+//	sev                         Severity, 0-10 divided by 10 (1 if not set)
+//	devTime and devTimeFormat   Timestamp in milliseconds since the epoch, with the Java date format of
+//	                            devTimeFormat or as MMM dd yyyy HH:mm:ss[.SSS][ zzz], the time of reading if not set
+type LEEFAlertReader[T structure.Stage, K structure.Stage] struct{}
+
+type leefEvent struct {
+	Version        string
+	Vendor         string
+	Product        string
+	ProductVersion string
+	EventId        string
+	Attributes     map[string]string
+}
+
+func (leefAlertReader *LEEFAlertReader[T, K]) ChannelAlerts(rtkcsm behaviour.RTKCSM[T, K], reader io.ReadCloser) error {
+	defer reader.Close()
+	return channelAlerts(rtkcsm, reader, leefAlertReader.decode, decodedAlerts, logAlertError)
+}
+
+func (leefAlertReader *LEEFAlertReader[T, K]) decode(line []byte) []structure.Alert {
+	event, err := parseLEEF(string(line))
+	if err != nil {
+		log.Printf("error decoding: %s (%s)\n", err, line)
+		return nil
+	}
+
+	source := event.Attributes["src"]
+	destination := event.Attributes["dst"]
+	if source == "" || destination == "" {
+		log.Printf("error parsing an ip address of a leef alert: src: %s, dst: %s\n", source, destination)
+		return nil
+	}
+
+	timestamp := time.Now()
+	if value := event.Attributes["devTime"]; value != "" {
+		timestamp, err = parseDeviceTime(value, event.Attributes["devTimeFormat"])
+		if err != nil {
+			log.Printf("error parsing time: %s", err)
+			return nil
+		}
+	}
+
+	severity := float32(1)
+	if value, ok := event.Attributes["sev"]; ok {
+		severity = parseSeverity(value, 10)
+	}
+
+	cause := event.EventId
+	if category := event.Attributes["cat"]; category != "" {
+		cause = fmt.Sprintf("%s: %s", category, event.EventId)
+	}
+
+	return []structure.Alert{
+		{
+			Timestamp:     timestamp,
+			SourceIP:      structure.ParseIPAddress(source),
+			DestinationIP: structure.ParseIPAddress(destination),
+			Severity:      severity,
+			Confidence:    1,
+			SignatureId:   parseSignatureId(event.EventId),
+			Cause:         cause,
+		},
+	}
+}
+
+// parseLEEF ignores everything before the LEEF header, e.g. a syslog header
+func parseLEEF(line string) (leefEvent, error) {
+	start := strings.Index(line, "LEEF:")
+	if start < 0 {
+		return leefEvent{}, fmt.Errorf("missing LEEF header")
+	}
+
+	fields, attributes, err := splitHeader(line[start+len("LEEF:"):], 5)
+	if err != nil {
+		return leefEvent{}, err
+	}
+
+	delimiter := "\t"
+	if strings.HasPrefix(fields[0], "2") {
+		delimiterField, rest, found := strings.Cut(attributes, "|")
+		if !found {
+			return leefEvent{}, fmt.Errorf("missing delimiter of LEEF %s", fields[0])
+		}
+
+		attributes = rest
+		if delimiterField != "" {
+			delimiter, err = parseLEEFDelimiter(delimiterField)
+			if err != nil {
+				return leefEvent{}, err
+			}
+		}
+	}
+
+	attributeFields := map[string]string{}
+	for _, attribute := range strings.Split(strings.TrimRight(attributes, "\r\n"), delimiter) {
+		if attribute == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(attribute, "=")
+		if !found {
+			return leefEvent{}, fmt.Errorf("invalid attribute: %q", attribute)
+		}
+		attributeFields[strings.TrimSpace(key)] = value
+	}
+
+	return leefEvent{
+		Version:        fields[0],
+		Vendor:         fields[1],
+		Product:        fields[2],
+		ProductVersion: fields[3],
+		EventId:        fields[4],
+		Attributes:     attributeFields,
+	}, nil
+}
+
+// parseLEEFDelimiter accepts a character or its hex code, e.g. x09 or 0x09 for tabs
+func parseLEEFDelimiter(value string) (string, error) {
+	if len(value) == 1 {
+		return value, nil
+	}
+
+	hexCode, found := strings.CutPrefix(strings.ToLower(value), "0x")
+	if !found {
+		hexCode, found = strings.CutPrefix(strings.ToLower(value), "x")
+	}
+
+	code, err := strconv.ParseUint(hexCode, 16, 8)
+	if !found || err != nil {
+		return "", fmt.Errorf("invalid delimiter: %s", value)
+	}
+
+	return string(rune(code)), nil
+}
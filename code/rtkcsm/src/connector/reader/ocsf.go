@@ -1,7 +1,6 @@
 package reader
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -51,82 +50,79 @@ type OCSFAlertReader[T structure.Stage, K structure.Stage] struct{}
 
 func (ocsfAlertReader *OCSFAlertReader[T, K]) ChannelAlerts(rtkcsm behaviour.RTKCSM[T, K], reader io.ReadCloser) error {
 	defer reader.Close()
+	return channelAlerts(rtkcsm, reader, ocsfAlertReader.decode, decodedAlerts, logAlertError)
+}
 
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		var detectionFinding OCSFDetectionFinding
-		line := scanner.Bytes()
+func (ocsfAlertReader *OCSFAlertReader[T, K]) decode(line []byte) []structure.Alert {
+	var detectionFinding OCSFDetectionFinding
 
-		if err := json.Unmarshal(line, &detectionFinding); err != nil {
-			log.Printf("error decoding: %s (%s)\n", err, line)
-		}
+	if err := json.Unmarshal(line, &detectionFinding); err != nil {
+		log.Printf("error decoding: %s (%s)\n", err, line)
+	}
 
-		// Check if detection finding is created
-		if detectionFinding.TypeUID == DetectionFindingCreateTypeUID {
-			pairs := []OCSFDevicePair{}
-			timestamp := time.UnixMilli(int64(detectionFinding.EventTime))
+	// Check if detection finding is created
+	if detectionFinding.TypeUID != DetectionFindingCreateTypeUID {
+		return nil
+	}
 
-			if detectionFinding.Device.IP != "" { // We assume it is a host event when a device is given
+	pairs := []OCSFDevicePair{}
+	timestamp := time.UnixMilli(int64(detectionFinding.EventTime))
+
+	if detectionFinding.Device.IP != "" { // We assume it is a host event when a device is given
+		pairs = append(pairs, OCSFDevicePair{
+			Source:      detectionFinding.Device,
+			Destination: detectionFinding.Device,
+		})
+	} else {
+		for _, evidence := range detectionFinding.EvidenceArtifacts {
+			if evidence.SourceEndpoint.IP != "" && evidence.DestinationEndpoint.IP != "" {
 				pairs = append(pairs, OCSFDevicePair{
-					Source:      detectionFinding.Device,
-					Destination: detectionFinding.Device,
+					Source: OCSFDevice{
+						IP: evidence.SourceEndpoint.IP,
+					},
+					Destination: OCSFDevice{
+						evidence.DestinationEndpoint.IP,
+					},
 				})
 			} else {
-				for _, evidence := range detectionFinding.EvidenceArtifacts {
-					if evidence.SourceEndpoint.IP != "" && evidence.DestinationEndpoint.IP != "" {
-						pairs = append(pairs, OCSFDevicePair{
-							Source: OCSFDevice{
-								IP: evidence.SourceEndpoint.IP,
-							},
-							Destination: OCSFDevice{
-								evidence.DestinationEndpoint.IP,
-							},
-						})
-					} else {
-						log.Printf("error parsing an ip address of an network alert: src: %s, dst: %s\n", evidence.SourceEndpoint.IP, evidence.DestinationEndpoint.IP)
-					}
-				}
+				log.Printf("error parsing an ip address of an network alert: src: %s, dst: %s\n", evidence.SourceEndpoint.IP, evidence.DestinationEndpoint.IP)
 			}
+		}
+	}
 
-			for _, pair := range pairs {
-				sourceIP := structure.ParseIPAddress(pair.Source.IP)
-				destinationIP := structure.ParseIPAddress(pair.Destination.IP)
-
-				confidence := float32(0)
-				switch detectionFinding.ConfidenceID {
-				case 1: // Low
-					confidence = 0.33
-				case 2: // Medium
-					confidence = 0.66
-				default: // High
-					confidence = 1
-				}
-
-				severity := float32(detectionFinding.SeverityId)
-				if severity >= 1 && severity <= 6 { // Severity ids are from 1 to 6
-					severity = severity / 6
-				} else { // If unknown or other we map it to 1
-					severity = 1
-				}
-
-				alert := structure.Alert{
-					Timestamp:     timestamp,
-					SourceIP:      sourceIP,
-					DestinationIP: destinationIP,
-					Severity:      severity,
-					Confidence:    confidence,
-					SignatureId:   detectionFinding.Unmapped.Alert.SignatureId,
-					Cause:         fmt.Sprintf("%s: %s", detectionFinding.FindingInformation.Title, detectionFinding.FindingInformation.Description),
-					Label:         "", // Not applicable for production as it is used for testing
-				}
-
-				err := rtkcsm.AddAlert(alert)
-				if err != nil {
-					log.Println(err)
-				}
-			}
+	alerts := []structure.Alert{}
+	for _, pair := range pairs {
+		sourceIP := structure.ParseIPAddress(pair.Source.IP)
+		destinationIP := structure.ParseIPAddress(pair.Destination.IP)
+
+		confidence := float32(0)
+		switch detectionFinding.ConfidenceID {
+		case 1: // Low
+			confidence = 0.33
+		case 2: // Medium
+			confidence = 0.66
+		default: // High
+			confidence = 1
 		}
+
+		severity := float32(detectionFinding.SeverityId)
+		if severity >= 1 && severity <= 6 { // Severity ids are from 1 to 6
+			severity = severity / 6
+		} else { // If unknown or other we map it to 1
+			severity = 1
+		}
+
+		alerts = append(alerts, structure.Alert{
+			Timestamp:     timestamp,
+			SourceIP:      sourceIP,
+			DestinationIP: destinationIP,
+			Severity:      severity,
+			Confidence:    confidence,
+			SignatureId:   detectionFinding.Unmapped.Alert.SignatureId,
+			Cause:         fmt.Sprintf("%s: %s", detectionFinding.FindingInformation.Title, detectionFinding.FindingInformation.Description),
+			Label:         "", // Not applicable for production as it is used for testing
+		})
 	}
 
-	return nil
+	return alerts
 }
@@ -1,11 +1,85 @@
 package reader
 
 import (
+	"bufio"
+	"bytes"
+	"hash/fnv"
 	"io"
+	"log"
 	"rtkcsm/component/behaviour"
 	"rtkcsm/component/structure"
+	"strconv"
 )
 
 type AlertReader[T structure.Stage, K structure.Stage] interface {
 	ChannelAlerts(rtkcsm behaviour.RTKCSM[T, K], reader io.ReadCloser) error
 }
+
+// LineObserver can be implemented by the RTKCSM passed to a reader to count the alerts of every line,
+// e.g. to report lines that were not decoded into an alert
+type LineObserver interface {
+	ObserveLine(alerts int)
+}
+
+// channelAlerts decodes the lines of a reader with the workers of the RTKCSM. The decoded entries are
+// converted into alerts in the order of the lines, so convert can keep state across lines.
+func channelAlerts[T structure.Stage, K structure.Stage, E any](rtkcsm behaviour.RTKCSM[T, K], reader io.Reader, decode func(line []byte) E, convert func(entry E) []structure.Alert, handleError func(alert structure.Alert, err error)) error {
+	workerCount := rtkcsm.WorkerCount()
+
+	lines := make(chan []byte, workerCount)
+	entries := structure.OrderedMap(workerCount, lines, decode)
+	alerts := make(chan structure.Alert, workerCount)
+
+	observer, observed := rtkcsm.(LineObserver)
+
+	go func() {
+		for entry := range entries {
+			converted := convert(entry)
+			if observed {
+				observer.ObserveLine(len(converted))
+			}
+
+			for _, alert := range converted {
+				alerts <- alert
+			}
+		}
+		close(alerts)
+	}()
+
+	scanner := bufio.NewScanner(reader)
+	go func() {
+		for scanner.Scan() {
+			// the scanner reuses its buffer
+			lines <- bytes.Clone(scanner.Bytes())
+		}
+		close(lines)
+	}()
+
+	rtkcsm.AddAlerts(alerts, handleError)
+
+	return scanner.Err()
+}
+
+func decodedAlerts(alerts []structure.Alert) []structure.Alert {
+	return alerts
+}
+
+func logAlertError(alert structure.Alert, err error) {
+	log.Println(err)
+}
+
+// parseSignatureId uses numeric ids (e.g. of Suricata rules) and hashes others (e.g. uuids of Elastic
+// rules or event names)
+func parseSignatureId(id string) uint32 {
+	if id == "" {
+		return 0
+	}
+
+	if signatureId, err := strconv.ParseUint(id, 10, 32); err == nil {
+		return uint32(signatureId)
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(id))
+	return hash.Sum32()
+}
@@ -0,0 +1,102 @@
+package reader
+
+import (
+	"fmt"
+	"io"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"strings"
+	"testing"
+	"time"
+)
+
+var profilerOptions = structure.NewProfilerOptions()
+
+// newTestRTKCSM creates an RTKCSM with a single worker, so alerts are correlated in the order they are added
+func newTestRTKCSM() *behaviour.RTKCSMImplementation[structure.SimplifiedUKCStage, structure.UKCStage] {
+	return behaviour.NewIncrementalRTKCSM(1, structure.NewSimplifiedUKCStageMapper(), structure.NewUKCStateMachine[structure.SimplifiedUKCStage](), &profilerOptions)
+}
+
+// suricataLines creates lines of alerts between a few hosts, one second apart
+func suricataLines(count int) string {
+	start := time.Now().Add(-time.Hour)
+	lines := strings.Builder{}
+	for i := range count {
+		source := fmt.Sprintf("94.141.%d.%d", i%7, i%13)
+		destination := fmt.Sprintf("172.16.%d.%d", i%5, i%11)
+		if i%3 == 0 {
+			source, destination = destination, fmt.Sprintf("172.16.%d.%d", i%4, i%9)
+		}
+
+		fmt.Fprintf(&lines, `{"timestamp":"%s","src_ip":"%s","dest_ip":"%s","alert":{"severity":%d,"signature":"test","signature_id":%d}}`+"\n",
+			start.Add(time.Duration(i)*time.Second).Format("2006-01-02T15:04:05.000000-0700"), source, destination, 1+i%3, i%17)
+	}
+
+	return lines.String()
+}
+
+func TestConcurrentIngestion(t *testing.T) {
+	lines := suricataLines(2000)
+
+	relations := map[string]int{}
+	for _, workerCount := range []int{1, 8} {
+		rtkcsm := behaviour.NewIncrementalRTKCSM(workerCount, structure.NewSimplifiedUKCStageMapper(), structure.NewUKCStateMachine[structure.SimplifiedUKCStage](), &profilerOptions)
+
+		alertReader := SuricataAlertReader[structure.SimplifiedUKCStage, structure.UKCStage]{}
+		if err := alertReader.ChannelAlerts(rtkcsm, io.NopCloser(strings.NewReader(lines))); err != nil {
+			t.Fatal(err)
+		}
+
+		// alerts are correlated in order, so both runs create graphs with the same content
+		sign := 1
+		if workerCount > 1 {
+			sign = -1
+		}
+
+		graphList := rtkcsm.GetGraphList(-1)
+		if graphList.Count == 0 {
+			t.Fatalf("no graphs with %d workers", workerCount)
+		}
+
+		for _, graph := range graphList.Graphs {
+			relations[fmt.Sprintf("%.4f", graph.Relevance)] += sign * rtkcsm.GetGraph(graph.ID).Len()
+		}
+	}
+
+	for relevance, difference := range relations {
+		if difference != 0 {
+			t.Errorf("graphs with relevance %s differ by %d relations", relevance, difference)
+		}
+	}
+}
+
+// discardingRTKCSM drops the alerts of a reader, so that benchmarks measure the decoding of the lines
+type discardingRTKCSM struct {
+	behaviour.RTKCSM[structure.SimplifiedUKCStage, structure.UKCStage]
+}
+
+func (c discardingRTKCSM) AddAlerts(alerts <-chan structure.Alert, handleError func(alert structure.Alert, err error)) {
+	for range alerts {
+	}
+}
+
+// BenchmarkChannelAlerts compares the sequential decoding of lines with the ordered decoding by multiple workers
+func BenchmarkChannelAlerts(b *testing.B) {
+	lines := suricataLines(10000)
+
+	for _, workerCount := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workerCount), func(b *testing.B) {
+			rtkcsm := discardingRTKCSM{
+				RTKCSM: behaviour.NewIncrementalRTKCSM(workerCount, structure.NewSimplifiedUKCStageMapper(), structure.NewUKCStateMachine[structure.SimplifiedUKCStage](), &profilerOptions),
+			}
+			alertReader := SuricataAlertReader[structure.SimplifiedUKCStage, structure.UKCStage]{}
+
+			b.SetBytes(int64(len(lines)))
+			for b.Loop() {
+				if err := alertReader.ChannelAlerts(rtkcsm, io.NopCloser(strings.NewReader(lines))); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
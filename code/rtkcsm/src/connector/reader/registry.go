@@ -0,0 +1,77 @@
+package reader
+
+import (
+	"fmt"
+	"mime"
+	"rtkcsm/component/structure"
+)
+
+// ReaderOptions configure readers that are created by name
+type ReaderOptions struct {
+	ECSSeverity ECSSeverityNormalization
+}
+
+// readerContentTypes select the reader of a body by its content type
+var readerContentTypes = map[string]string{
+	"application/vnd.suricata.eve+json": "suricata",
+	"application/vnd.zeek+json":         "zeek",
+	"application/vnd.ocsf+json":         "ocsf",
+	"application/vnd.tenzir+json":       "suricata-tenzir",
+	"application/vnd.wazuh+json":        "wazuh",
+	"application/vnd.elastic.ecs+json":  "ecs",
+	"text/x-cef":                        "cef",
+	"text/x-leef":                       "leef",
+}
+
+// genericContentTypes are read by the default reader
+var genericContentTypes = map[string]bool{
+	"application/json":     true,
+	"application/x-ndjson": true,
+	"application/jsonl":    true,
+	"text/plain":           true,
+}
+
+func NewAlertReader[T structure.Stage, K structure.Stage](name string, options ReaderOptions) (AlertReader[T, K], error) {
+	switch name {
+	case "", "suricata":
+		return &SuricataAlertReader[T, K]{}, nil
+	case "zeek":
+		return &ZeekAlertReader[T, K]{}, nil
+	case "suricata-tenzir":
+		return &SuricataTenzirAlertReader[T, K]{}, nil
+	case "ocsf":
+		return &OCSFAlertReader[T, K]{}, nil
+	case "wazuh":
+		return &WazuhAlertReader[T, K]{}, nil
+	case "ecs":
+		return &ECSAlertReader[T, K]{Severity: options.ECSSeverity}, nil
+	case "cef":
+		return &CEFAlertReader[T, K]{}, nil
+	case "leef":
+		return &LEEFAlertReader[T, K]{}, nil
+	default:
+		return nil, fmt.Errorf("reader type is not known: %s", name)
+	}
+}
+
+// ReaderNameOfContentType returns the reader of a content type, generic JSON and text content types
+// (or none) are read by the default reader
+func ReaderNameOfContentType(contentType string, defaultName string) (string, error) {
+	if contentType == "" {
+		return defaultName, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+
+	if name, ok := readerContentTypes[mediaType]; ok {
+		return name, nil
+	}
+	if genericContentTypes[mediaType] {
+		return defaultName, nil
+	}
+
+	return "", fmt.Errorf("unsupported content type: %s", mediaType)
+}
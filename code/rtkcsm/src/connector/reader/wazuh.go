@@ -0,0 +1,99 @@
+package reader
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"time"
+)
+
+// WazuhAlertReader reads the alerts.json of a Wazuh (or OSSEC) manager. Alerts with a source address
+// in their decoded data are network alerts from the source to the agent, all other alerts are host
+// alerts of the agent.
+type WazuhAlertReader[T structure.Stage, K structure.Stage] struct{}
+
+type wazuhAlert struct {
+	Timestamp string     `json:"timestamp"`
+	Rule      wazuhRule  `json:"rule"`
+	Agent     wazuhAgent `json:"agent"`
+	Data      wazuhData  `json:"data"`
+	Label     string     `json:"label"`
+}
+
+type wazuhRule struct {
+	Level       int    `json:"level"`
+	Description string `json:"description"`
+	ID          string `json:"id"`
+}
+
+type wazuhAgent struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+}
+
+type wazuhData struct {
+	SourceIP      string `json:"srcip"`
+	DestinationIP string `json:"dstip"`
+}
+
+// rule levels range from 0 (ignored) to 15 (severe attack)
+const maxWazuhRuleLevel = 15
+
+func (wazuhAlertReader *WazuhAlertReader[T, K]) ChannelAlerts(rtkcsm behaviour.RTKCSM[T, K], reader io.ReadCloser) error {
+	defer reader.Close()
+	return channelAlerts(rtkcsm, reader, wazuhAlertReader.decode, decodedAlerts, logAlertError)
+}
+
+func (wazuhAlertReader *WazuhAlertReader[T, K]) decode(line []byte) []structure.Alert {
+	var alert wazuhAlert
+
+	if err := json.Unmarshal(line, &alert); err != nil {
+		log.Printf("error decoding: %s (%s)\n", err, line)
+		return nil
+	}
+
+	if alert.Rule.ID == "" || alert.Rule.Level <= 0 {
+		return nil
+	}
+
+	timestamp, err := time.Parse("2006-01-02T15:04:05.000-0700", alert.Timestamp)
+	if err != nil {
+		timestamp, err = time.Parse(time.RFC3339Nano, alert.Timestamp)
+		if err != nil {
+			log.Printf("error parsing time: %s", err)
+			return nil
+		}
+	}
+
+	// the destination of a network alert is the agent that observed it unless the decoder found one
+	source := alert.Agent.IP
+	destination := alert.Agent.IP
+	if alert.Data.SourceIP != "" {
+		source = alert.Data.SourceIP
+		if alert.Data.DestinationIP != "" {
+			destination = alert.Data.DestinationIP
+		}
+	}
+
+	// alerts of the manager itself (agent 000) do not have an address
+	if source == "" || destination == "" {
+		log.Printf("error parsing an ip address of a wazuh alert: rule: %s, agent: %s\n", alert.Rule.ID, alert.Agent.Name)
+		return nil
+	}
+
+	return []structure.Alert{
+		{
+			Timestamp:     timestamp,
+			SourceIP:      structure.ParseIPAddress(source),
+			DestinationIP: structure.ParseIPAddress(destination),
+			Severity:      float32(min(alert.Rule.Level, maxWazuhRuleLevel)) / maxWazuhRuleLevel,
+			Confidence:    1,
+			SignatureId:   parseSignatureId(alert.Rule.ID),
+			Cause:         alert.Rule.Description,
+			Label:         alert.Label,
+		},
+	}
+}
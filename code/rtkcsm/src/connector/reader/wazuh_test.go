@@ -0,0 +1,103 @@
+package reader
+
+import (
+	"reflect"
+	"rtkcsm/component/structure"
+	"testing"
+	"time"
+)
+
+// expectAlerts compares decoded alerts with the expected ones, timestamps are compared as instants
+func expectAlerts(t *testing.T, name string, alerts []structure.Alert, expected []structure.Alert) {
+	t.Helper()
+
+	if len(alerts) != len(expected) {
+		t.Errorf("%s: expected alerts %v instead of %v", name, expected, alerts)
+		return
+	}
+
+	for i, alert := range alerts {
+		if !alert.Timestamp.Equal(expected[i].Timestamp) {
+			t.Errorf("%s: expected timestamp %s instead of %s", name, expected[i].Timestamp, alert.Timestamp)
+		}
+		alert.Timestamp = expected[i].Timestamp
+
+		if !reflect.DeepEqual(alert, expected[i]) {
+			t.Errorf("%s: expected alert %v instead of %v", name, expected[i], alert)
+		}
+	}
+}
+
+func TestWazuhDecode(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		line     string
+		expected []structure.Alert
+	}{
+		{
+			"network alert against an agent",
+			`{"timestamp":"2024-05-01T10:00:00.000+0000","rule":{"level":10,"description":"sshd: brute force trying to get access to the system.","id":"5712"},"agent":{"id":"001","name":"web","ip":"172.16.42.42"},"data":{"srcip":"94.141.120.36"},"id":"1714557600.1"}`,
+			[]structure.Alert{{
+				Timestamp:     start,
+				SourceIP:      structure.ParseIPAddress("94.141.120.36"),
+				DestinationIP: structure.ParseIPAddress("172.16.42.42"),
+				Severity:      float32(10) / 15,
+				Confidence:    1,
+				SignatureId:   5712,
+				Cause:         "sshd: brute force trying to get access to the system.",
+			}},
+		},
+		{
+			"host alert of an agent",
+			`{"timestamp":"2024-05-01T12:00:05.000+0200","rule":{"level":7,"description":"Integrity checksum changed.","id":"550"},"agent":{"id":"001","name":"web","ip":"172.16.42.42"},"syscheck":{"path":"/etc/passwd"},"label":"malicious","id":"1714557605.2"}`,
+			[]structure.Alert{{
+				Timestamp:     start.Add(5 * time.Second),
+				SourceIP:      structure.ParseIPAddress("172.16.42.42"),
+				DestinationIP: structure.ParseIPAddress("172.16.42.42"),
+				Severity:      float32(7) / 15,
+				Confidence:    1,
+				SignatureId:   550,
+				Cause:         "Integrity checksum changed.",
+				Label:         "malicious",
+			}},
+		},
+		{
+			"destination found by the decoder with a level above the maximum",
+			`{"timestamp":"2024-05-01T10:00:10Z","rule":{"level":20,"description":"Multiple authentication failures.","id":"40111"},"agent":{"id":"002","name":"db","ip":"172.16.42.1"},"data":{"srcip":"172.16.42.42","dstip":"172.16.42.2"},"id":"1714557610.3"}`,
+			[]structure.Alert{{
+				Timestamp:     start.Add(10 * time.Second),
+				SourceIP:      structure.ParseIPAddress("172.16.42.42"),
+				DestinationIP: structure.ParseIPAddress("172.16.42.2"),
+				Severity:      1,
+				Confidence:    1,
+				SignatureId:   40111,
+				Cause:         "Multiple authentication failures.",
+			}},
+		},
+		{
+			"rule id that is not a number",
+			`{"timestamp":"2024-05-01T10:00:15.000+0000","rule":{"level":15,"description":"Custom rule.","id":"custom-1"},"agent":{"id":"001","name":"web","ip":"172.16.42.42"}}`,
+			[]structure.Alert{{
+				Timestamp:     start.Add(15 * time.Second),
+				SourceIP:      structure.ParseIPAddress("172.16.42.42"),
+				DestinationIP: structure.ParseIPAddress("172.16.42.42"),
+				Severity:      1,
+				Confidence:    1,
+				SignatureId:   parseSignatureId("custom-1"),
+				Cause:         "Custom rule.",
+			}},
+		},
+		{"manager alert without an address", `{"timestamp":"2024-05-01T10:00:15.000+0000","rule":{"level":3,"description":"Wazuh server started.","id":"502"},"agent":{"id":"000","name":"manager"},"id":"1714557615.4"}`, nil},
+		{"ignored rule", `{"timestamp":"2024-05-01T10:00:20.000+0000","rule":{"level":0,"description":"Ignored.","id":"1"},"agent":{"id":"001","name":"web","ip":"172.16.42.42"},"id":"1714557620.5"}`, nil},
+		{"missing rule id", `{"timestamp":"2024-05-01T10:00:20.000+0000","rule":{"level":5,"description":"Unknown."},"agent":{"id":"001","name":"web","ip":"172.16.42.42"}}`, nil},
+		{"invalid timestamp", `{"timestamp":"May  1 10:00:00","rule":{"level":5,"description":"Unknown.","id":"1"},"agent":{"id":"001","name":"web","ip":"172.16.42.42"}}`, nil},
+		{"invalid json", `not json`, nil},
+	}
+
+	alertReader := WazuhAlertReader[structure.SimplifiedUKCStage, structure.UKCStage]{}
+	for _, test := range tests {
+		expectAlerts(t, test.name, alertReader.decode([]byte(test.line)), test.expected)
+	}
+}
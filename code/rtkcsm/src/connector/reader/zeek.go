@@ -1,9 +1,7 @@
 package reader
 
 import (
-	"bufio"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log"
 	"math"
@@ -24,56 +22,61 @@ type zeekAlert struct {
 	Signature   string  `json:"note"`
 }
 
+type zeekEntry struct {
+	alert     structure.Alert
+	signature string
+}
+
 func (zeekAlertReader *ZeekAlertReader[T, K]) ChannelAlerts(rtkcsm behaviour.RTKCSM[T, K], reader io.ReadCloser) error {
 	defer reader.Close()
 
+	// signature ids are assigned in the order of the lines
 	zeekSignatureIdMap := map[string]uint32{}
 
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		var zeekAlert zeekAlert
-		line := scanner.Bytes()
-
-		if err := json.Unmarshal(line, &zeekAlert); err != nil {
-			log.Printf("error decoding: %s (%s)\n", err, line)
-		}
-
-		seconds := int64(zeekAlert.Timestamp)
-		nanoseconds := int64((zeekAlert.Timestamp - float64(seconds)) * 1_000_000_000)
-
-		timestamp := time.Unix(seconds, nanoseconds)
-
-		sourceIP := structure.ParseIPAddress(zeekAlert.Source)
-		destinationIP := structure.ParseIPAddress(zeekAlert.Destination)
-
-		signatureId, ok := zeekSignatureIdMap[zeekAlert.Signature]
+	return channelAlerts(rtkcsm, reader, zeekAlertReader.decode, func(entry zeekEntry) []structure.Alert {
+		signatureId, ok := zeekSignatureIdMap[entry.signature]
 
 		if !ok {
 			length := len(zeekSignatureIdMap)
 			if length <= math.MaxUint32 {
 				signatureId = uint32(length)
-				zeekSignatureIdMap[zeekAlert.Signature] = signatureId
+				zeekSignatureIdMap[entry.signature] = signatureId
 			} else {
-				return fmt.Errorf("exceeding signature id limit")
+				log.Printf("exceeding signature id limit: %s\n", entry.signature)
+				return nil
 			}
 		}
 
-		alert := structure.Alert{
+		entry.alert.SignatureId = signatureId
+		return []structure.Alert{entry.alert}
+	}, nil)
+}
+
+func (zeekAlertReader *ZeekAlertReader[T, K]) decode(line []byte) zeekEntry {
+	var zeekAlert zeekAlert
+
+	if err := json.Unmarshal(line, &zeekAlert); err != nil {
+		log.Printf("error decoding: %s (%s)\n", err, line)
+	}
+
+	seconds := int64(zeekAlert.Timestamp)
+	nanoseconds := int64((zeekAlert.Timestamp - float64(seconds)) * 1_000_000_000)
+
+	timestamp := time.Unix(seconds, nanoseconds)
+
+	sourceIP := structure.ParseIPAddress(zeekAlert.Source)
+	destinationIP := structure.ParseIPAddress(zeekAlert.Destination)
+
+	return zeekEntry{
+		alert: structure.Alert{
 			Timestamp:     timestamp,
 			SourceIP:      sourceIP,
 			DestinationIP: destinationIP,
 			Severity:      1,
 			Confidence:    1,
 			Cause:         zeekAlert.Message,
-			SignatureId:   signatureId,
 			Label:         zeekAlert.Label,
-		}
-
-		err := rtkcsm.AddAlert(alert)
-		if err != nil {
-			//log.Println(err)
-		}
+		},
+		signature: zeekAlert.Signature,
 	}
-
-	return nil
 }
@@ -0,0 +1,38 @@
+package sink
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"rtkcsm/connector/visualization"
+)
+
+// FileSink appends incidents as JSON lines
+type FileSink struct {
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(filepath.Clean(path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{
+		file: file,
+	}, nil
+}
+
+func (sink *FileSink) Send(finding visualization.OCSFIncidentFinding) error {
+	line, err := json.Marshal(finding)
+	if err != nil {
+		return err
+	}
+
+	_, err = sink.file.Write(append(line, '\n'))
+	return err
+}
+
+func (sink *FileSink) Close() error {
+	return sink.file.Close()
+}
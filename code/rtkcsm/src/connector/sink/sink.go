@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"fmt"
+	"net/url"
+	"rtkcsm/connector/visualization"
+	"strings"
+)
+
+// Sink delivers incidents to a downstream system. Errors are retried by the caller, so a sink
+// has to be usable again after a failed Send.
+type Sink interface {
+	Send(finding visualization.OCSFIncidentFinding) error
+	Close() error
+}
+
+// New creates a sink from a target: a file path or file:// URL for JSON lines, tcp://host:port for
+// JSON lines over TCP, syslog://host:port for RFC 5424 messages over TCP or an http(s):// webhook URL
+func New(target string) (Sink, error) {
+	if !strings.Contains(target, "://") {
+		return NewFileSink(target)
+	}
+
+	targetUrl, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid incident sink: %s", target)
+	}
+
+	switch targetUrl.Scheme {
+	case "file":
+		return NewFileSink(targetUrl.Path)
+	case "tcp":
+		return NewTcpSink(targetUrl.Host, false), nil
+	case "syslog":
+		return NewTcpSink(targetUrl.Host, true), nil
+	case "http", "https":
+		return NewWebhookSink(target), nil
+	default:
+		return nil, fmt.Errorf("unknown incident sink: %s", target)
+	}
+}
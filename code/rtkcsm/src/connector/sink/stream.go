@@ -0,0 +1,371 @@
+package sink
+
+import (
+	"log"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"rtkcsm/connector/visualization"
+	"slices"
+	"sync"
+	"time"
+)
+
+const DEFAULT_INCIDENT_FLUSH_INTERVAL = time.Second
+const DEFAULT_INCIDENT_RETRIES = 5
+const DEFAULT_INCIDENT_RETRY_BACKOFF = time.Second
+const MAX_INCIDENT_RETRY_BACKOFF = time.Minute
+
+// IncidentStreamOptions select the graphs that are reported. A graph is reported once it crosses the
+// relevance threshold or enters the top graphs and afterwards whenever it changes.
+type IncidentStreamOptions struct {
+	MinRelevance  float32       // report graphs with at least this relevance (0 = disabled)
+	TopGraphs     int           // report the graphs with the highest relevance (0 = disabled)
+	MaxRetries    int           // retries of a failed delivery before the incident is dropped
+	RetryBackoff  time.Duration // wait before the first retry, doubled for every further retry
+	FlushInterval time.Duration // graph events are collected for this interval, so a burst of alerts results in one incident per graph
+}
+
+func NewIncidentStreamOptions() IncidentStreamOptions {
+	return IncidentStreamOptions{
+		MaxRetries:    DEFAULT_INCIDENT_RETRIES,
+		RetryBackoff:  DEFAULT_INCIDENT_RETRY_BACKOFF,
+		FlushInterval: DEFAULT_INCIDENT_FLUSH_INTERVAL,
+	}
+}
+
+func (o *IncidentStreamOptions) IsEnabled() bool {
+	return o.MinRelevance > 0 || o.TopGraphs > 0
+}
+
+// incidentState is the state of a graph when it was reported the last time
+type incidentState struct {
+	relations int
+	relevance float32
+	status    structure.IncidentStatus
+}
+
+// IncidentStream pushes OCSF incident findings of graphs to a sink. The first finding of a graph is
+// created and later findings update or close it. Graphs that were merged into another graph are
+// closed with a reference to the graph they were merged into, which continues the incident.
+type IncidentStream[T structure.Stage, K structure.Stage] struct {
+	rtkcsm       behaviour.RTKCSM[T, K]
+	sink         Sink
+	options      IncidentStreamOptions
+	subscription *structure.GraphEventSubscription
+
+	mutex    sync.Mutex
+	reported map[structure.GraphID]incidentState
+	// findings waiting for delivery, only the latest finding of a graph is delivered
+	queue   []structure.GraphID
+	pending map[structure.GraphID]visualization.OCSFIncidentFinding
+	queued  chan struct{}
+
+	stop      chan struct{}
+	collected chan struct{}
+	delivered chan struct{}
+}
+
+// NewIncidentStream starts to report graphs until the stream is closed
+func NewIncidentStream[T structure.Stage, K structure.Stage](rtkcsm behaviour.RTKCSM[T, K], sink Sink, options IncidentStreamOptions) *IncidentStream[T, K] {
+	stream := &IncidentStream[T, K]{
+		rtkcsm:       rtkcsm,
+		sink:         sink,
+		options:      options,
+		subscription: rtkcsm.SubscribeGraphEvents(),
+		reported:     map[structure.GraphID]incidentState{},
+		queue:        []structure.GraphID{},
+		pending:      map[structure.GraphID]visualization.OCSFIncidentFinding{},
+		queued:       make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		collected:    make(chan struct{}),
+		delivered:    make(chan struct{}),
+	}
+
+	go stream.collect()
+	go stream.deliver()
+
+	return stream
+}
+
+// Close reports the current state of all graphs, waits until the findings are delivered or dropped
+// and closes the sink
+func (s *IncidentStream[T, K]) Close() error {
+	close(s.stop)
+	<-s.collected
+	<-s.delivered
+
+	s.subscription.Close()
+	return s.sink.Close()
+}
+
+func (s *IncidentStream[T, K]) collect() {
+	defer close(s.collected)
+
+	ticker := time.NewTicker(s.options.FlushInterval)
+	defer ticker.Stop()
+
+	changed := []structure.GraphID{}
+	reload := false
+
+	for {
+		select {
+		case event := <-s.subscription.Events:
+			switch event.Type {
+			case structure.GraphEventReload:
+				reload = true
+			case structure.GraphEventMerged:
+				changed = append(changed, event.ID, event.MergedInto)
+			default:
+				changed = append(changed, event.ID)
+			}
+		case <-ticker.C:
+			// events were dropped, so every graph could have changed
+			if s.subscription.Overflowed() {
+				reload = true
+			}
+
+			if reload {
+				s.evaluate(s.candidates())
+			} else if len(changed) > 0 {
+				s.evaluate(append(changed, s.topGraphs()...))
+			}
+
+			changed = []structure.GraphID{}
+			reload = false
+		case <-s.stop:
+			s.evaluate(s.candidates())
+			return
+		}
+	}
+}
+
+// candidates returns all graphs that are reported or could be reported
+func (s *IncidentStream[T, K]) candidates() []structure.GraphID {
+	ids := s.topGraphs()
+
+	s.mutex.Lock()
+	for id := range s.reported {
+		ids = append(ids, id)
+	}
+	s.mutex.Unlock()
+
+	if s.options.MinRelevance > 0 {
+		query := structure.NewGraphQuery(0)
+		query.MinRelevance = s.options.MinRelevance
+		query.PageSize = structure.MAX_GRAPH_PAGE_SIZE
+
+		for {
+			list := s.rtkcsm.SearchGraphs(query)
+			for _, information := range list.Graphs {
+				ids = append(ids, information.ID)
+			}
+
+			if len(list.Graphs) < query.PageSize {
+				break
+			}
+			query.Page += 1
+		}
+	}
+
+	return ids
+}
+
+func (s *IncidentStream[T, K]) topGraphs() []structure.GraphID {
+	ids := []structure.GraphID{}
+	if s.options.TopGraphs <= 0 {
+		return ids
+	}
+
+	query := structure.NewGraphQuery(0)
+	query.PageSize = s.options.TopGraphs
+
+	for _, information := range s.rtkcsm.SearchGraphs(query).Graphs {
+		ids = append(ids, information.ID)
+	}
+
+	return ids
+}
+
+// evaluate queues findings of the graphs that have to be reported and changed since they were reported
+func (s *IncidentStream[T, K]) evaluate(ids []structure.GraphID) {
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	top := s.topGraphs()
+
+	for _, id := range ids {
+		graph := s.rtkcsm.GetGraph(id)
+		incident := s.rtkcsm.GetIncident(id)
+		if graph == nil || incident == nil {
+			s.closeMerged(id)
+			continue
+		}
+
+		preComputedGraph := graph.GetPreComputed()
+		state := incidentState{
+			relations: len(preComputedGraph.PreComputedDirectedRelations),
+			relevance: preComputedGraph.ComputedRelevance,
+			status:    incident.Status,
+		}
+
+		s.mutex.Lock()
+		previous, reported := s.reported[id]
+
+		triggered := (s.options.MinRelevance > 0 && state.relevance >= s.options.MinRelevance) || slices.Contains(top, id)
+		if (!reported && !triggered) || (reported && previous == state) {
+			s.mutex.Unlock()
+			continue
+		}
+
+		finding := visualization.FromGraphToOCSFIncidentFinding(id, &preComputedGraph, *incident)
+		switch {
+		case !reported:
+			finding.SetActivity(visualization.OCSFActivityCreate)
+		case state.status == structure.IncidentStatusClosed:
+			finding.SetActivity(visualization.OCSFActivityClose)
+		default:
+			finding.SetActivity(visualization.OCSFActivityUpdate)
+		}
+
+		s.reported[id] = state
+		s.enqueue(id, finding)
+		s.mutex.Unlock()
+	}
+}
+
+// closeMerged closes the incident of a reported graph that was merged into another graph. Removed
+// graphs (e.g. by the retention policy) are only forgotten.
+func (s *IncidentStream[T, K]) closeMerged(id structure.GraphID) {
+	into := s.rtkcsm.ResolveGraphID(id)
+	graph := s.rtkcsm.GetGraph(into)
+	incident := s.rtkcsm.GetIncident(into)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, reported := s.reported[id]
+	delete(s.reported, id)
+
+	if !reported || into == id || graph == nil || incident == nil {
+		return
+	}
+
+	// the graph was not created downstream yet
+	if waiting, ok := s.pending[id]; ok && waiting.Activity == visualization.OCSFActivityCreate {
+		s.dequeue(id)
+		return
+	}
+
+	preComputedGraph := graph.GetPreComputed()
+	s.enqueue(id, visualization.FromMergedGraphToOCSFIncidentFinding(id, into, &preComputedGraph, *incident))
+}
+
+// enqueue replaces a finding of the graph that was not delivered yet, expects the mutex to be locked
+func (s *IncidentStream[T, K]) enqueue(id structure.GraphID, finding visualization.OCSFIncidentFinding) {
+	if waiting, ok := s.pending[id]; ok {
+		// the graph was not created downstream yet
+		if waiting.Activity == visualization.OCSFActivityCreate {
+			finding.SetActivity(visualization.OCSFActivityCreate)
+		}
+	} else {
+		s.queue = append(s.queue, id)
+	}
+	s.pending[id] = finding
+
+	select {
+	case s.queued <- struct{}{}:
+	default:
+	}
+}
+
+// dequeue removes the finding of a graph from the queue, expects the mutex to be locked
+func (s *IncidentStream[T, K]) dequeue(id structure.GraphID) (visualization.OCSFIncidentFinding, bool) {
+	finding, ok := s.pending[id]
+	if ok {
+		delete(s.pending, id)
+		s.queue = slices.DeleteFunc(s.queue, func(queuedId structure.GraphID) bool {
+			return queuedId == id
+		})
+	}
+	return finding, ok
+}
+
+func (s *IncidentStream[T, K]) next() (structure.GraphID, visualization.OCSFIncidentFinding, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.queue) == 0 {
+		return 0, visualization.OCSFIncidentFinding{}, false
+	}
+
+	id := s.queue[0]
+	finding, _ := s.dequeue(id)
+	return id, finding, true
+}
+
+func (s *IncidentStream[T, K]) deliver() {
+	defer close(s.delivered)
+
+	for {
+		if id, finding, ok := s.next(); ok {
+			s.send(id, finding)
+			continue
+		}
+
+		select {
+		case <-s.queued:
+		case <-s.collected:
+			// no findings are queued after the last evaluation
+			s.mutex.Lock()
+			empty := len(s.queue) == 0
+			s.mutex.Unlock()
+
+			if empty {
+				return
+			}
+		}
+	}
+}
+
+// send retries a failed delivery with exponential back-off. A newer finding of the graph replaces
+// the failed finding before a retry.
+func (s *IncidentStream[T, K]) send(id structure.GraphID, finding visualization.OCSFIncidentFinding) {
+	backoff := s.options.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := s.sink.Send(finding)
+		if err == nil {
+			return
+		}
+
+		if attempt >= s.options.MaxRetries {
+			log.Printf("dropping incident of graph %d after %d attempts: %s", id, attempt+1, err)
+
+			// the graph is created by its next finding
+			if finding.Activity == visualization.OCSFActivityCreate {
+				s.mutex.Lock()
+				if waiting, ok := s.pending[id]; ok {
+					waiting.SetActivity(visualization.OCSFActivityCreate)
+					s.pending[id] = waiting
+				} else {
+					delete(s.reported, id)
+				}
+				s.mutex.Unlock()
+			}
+			return
+		}
+
+		log.Printf("error sending incident of graph %d, retrying in %s: %s", id, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, MAX_INCIDENT_RETRY_BACKOFF)
+
+		s.mutex.Lock()
+		if newer, ok := s.dequeue(id); ok {
+			if finding.Activity == visualization.OCSFActivityCreate {
+				newer.SetActivity(visualization.OCSFActivityCreate)
+			}
+			finding = newer
+		}
+		s.mutex.Unlock()
+	}
+}
@@ -0,0 +1,232 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"rtkcsm/connector/visualization"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var profilerOptions = structure.NewProfilerOptions()
+
+// newTestRTKCSM creates an RTKCSM with a single worker, so alerts are correlated in the order they are added
+func newTestRTKCSM() *behaviour.RTKCSMImplementation[structure.SimplifiedUKCStage, structure.UKCStage] {
+	return behaviour.NewIncrementalRTKCSM(1, structure.NewSimplifiedUKCStageMapper(), structure.NewUKCStateMachine[structure.SimplifiedUKCStage](), &profilerOptions)
+}
+
+// newTestAlert creates an alert with full confidence between two hosts
+func newTestAlert(timestamp time.Time, source string, destination string, severity float32) structure.Alert {
+	return structure.Alert{
+		Timestamp:     timestamp,
+		SourceIP:      structure.ParseIPAddress(source),
+		DestinationIP: structure.ParseIPAddress(destination),
+		Severity:      severity,
+		Confidence:    1,
+	}
+}
+
+// recordingSink fails the first deliveries and records the delivered findings
+type recordingSink struct {
+	mutex    sync.Mutex
+	failures int
+	attempts int
+	findings []visualization.OCSFIncidentFinding
+	closed   bool
+}
+
+func (s *recordingSink) Send(finding visualization.OCSFIncidentFinding) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.attempts += 1
+	if s.attempts <= s.failures {
+		return fmt.Errorf("delivery %d failed", s.attempts)
+	}
+
+	s.findings = append(s.findings, finding)
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+func (s *recordingSink) activities() []visualization.OCSFActivity {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	activities := []visualization.OCSFActivity{}
+	for _, finding := range s.findings {
+		activities = append(activities, finding.Activity)
+	}
+	return activities
+}
+
+func waitFor(t *testing.T, description string, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestIncidentStream(t *testing.T) {
+	rtkcsm := newTestRTKCSM()
+
+	options := NewIncidentStreamOptions()
+	options.MinRelevance = 0.3
+	options.MaxRetries = 3
+	options.RetryBackoff = time.Millisecond
+	options.FlushInterval = 10 * time.Millisecond
+
+	recorder := &recordingSink{
+		failures: 2,
+	}
+	stream := NewIncidentStream(rtkcsm, recorder, options)
+
+	seconds := time.Now().Unix()
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-10, 0), "94.141.120.36", "172.16.42.42", 1))
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-9, 0), "94.141.120.37", "172.16.42.43", 1))
+
+	// graphs below the threshold are not reported
+	time.Sleep(5 * options.FlushInterval)
+	if activities := recorder.activities(); len(activities) != 0 {
+		t.Fatalf("reported graphs below the threshold: %v", activities)
+	}
+
+	// the graph crosses the threshold and is created after two failed deliveries
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-8, 0), "172.16.42.42", "218.92.0.27", 1))
+	waitFor(t, "the created incident", func() bool { return len(recorder.activities()) == 1 })
+
+	// unchanged graphs are not reported again
+	time.Sleep(5 * options.FlushInterval)
+	if activities := recorder.activities(); len(activities) != 1 {
+		t.Fatalf("reported an unchanged graph again: %v", activities)
+	}
+
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-7, 0), "172.16.42.42", "218.92.0.28", 1))
+	waitFor(t, "the updated incident", func() bool { return len(recorder.activities()) == 2 })
+
+	id := rtkcsm.GetGraphList(0).Graphs[0].ID
+	status := string(structure.IncidentStatusClosed)
+	if _, err := rtkcsm.UpdateIncident(id, structure.IncidentUpdate{Status: &status}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the closed incident", func() bool { return len(recorder.activities()) == 3 })
+
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []visualization.OCSFActivity{visualization.OCSFActivityCreate, visualization.OCSFActivityUpdate, visualization.OCSFActivityClose}
+	if activities := recorder.activities(); !reflect.DeepEqual(activities, expected) {
+		t.Errorf("expected activities %v instead of %v", expected, activities)
+	}
+
+	for _, finding := range recorder.findings {
+		if finding.Metadata.CorrelationUID != strconv.Itoa(int(id)) || finding.Type != visualization.OCSFType(int(finding.Class)*100+int(finding.Activity)) {
+			t.Errorf("unexpected correlation %s or type %d of graph %d", finding.Metadata.CorrelationUID, finding.Type, id)
+		}
+	}
+
+	if recorder.attempts != 5 || !recorder.closed {
+		t.Errorf("expected 5 delivery attempts and a closed sink: attempts=%d closed=%t", recorder.attempts, recorder.closed)
+	}
+
+	// the most relevant graph is reported to a webhook
+	requests := make(chan visualization.OCSFIncidentFinding, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var finding visualization.OCSFIncidentFinding
+		if err := json.NewDecoder(request.Body).Decode(&finding); err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests <- finding
+	}))
+	defer server.Close()
+
+	webhook, err := New(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options = NewIncidentStreamOptions()
+	options.TopGraphs = 1
+	if err := NewIncidentStream(rtkcsm, webhook, options).Close(); err != nil {
+		t.Fatal(err)
+	}
+	close(requests)
+
+	findings := []visualization.OCSFIncidentFinding{}
+	for finding := range requests {
+		findings = append(findings, finding)
+	}
+	if len(findings) != 1 || findings[0].Metadata.CorrelationUID != strconv.Itoa(int(id)) || findings[0].Activity != visualization.OCSFActivityCreate {
+		t.Errorf("expected the top graph %d to be created: %+v", id, findings)
+	}
+}
+
+func TestIncidentStreamMerge(t *testing.T) {
+	rtkcsm := newTestRTKCSM()
+
+	options := NewIncidentStreamOptions()
+	options.MinRelevance = 0.01
+	options.FlushInterval = 10 * time.Millisecond
+
+	recorder := &recordingSink{}
+	stream := NewIncidentStream(rtkcsm, recorder, options)
+
+	seconds := time.Now().Unix()
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-3, 0), "94.141.120.36", "172.16.42.42", 1))
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-2, 0), "94.141.120.37", "172.16.42.42", 1))
+	waitFor(t, "the created incidents", func() bool { return len(recorder.activities()) == 2 })
+
+	graphs := rtkcsm.GetGraphList(-1).Graphs
+	survivingId := min(graphs[0].ID, graphs[1].ID)
+	mergedId := max(graphs[0].ID, graphs[1].ID)
+
+	// links both graphs
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-1, 0), "172.16.42.42", "172.16.42.1", 1))
+	waitFor(t, "the merged incidents", func() bool { return len(recorder.activities()) == 4 })
+
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	findings := map[string]visualization.OCSFIncidentFinding{}
+	for _, finding := range recorder.findings[2:] {
+		findings[finding.Metadata.CorrelationUID] = finding
+	}
+
+	updated := findings[strconv.Itoa(int(survivingId))]
+	if updated.Activity != visualization.OCSFActivityUpdate {
+		t.Errorf("surviving graph %d was not updated: %v", survivingId, recorder.activities())
+	}
+
+	closed := findings[strconv.Itoa(int(mergedId))]
+	if closed.Activity != visualization.OCSFActivityClose || closed.Status != visualization.OCSFStatusClosed {
+		t.Fatalf("merged graph %d was not closed: %v", mergedId, recorder.activities())
+	}
+
+	references := []string{}
+	for _, information := range closed.FindingInformations {
+		references = append(references, information.ID)
+	}
+	if expected := []string{strconv.Itoa(int(mergedId)), strconv.Itoa(int(survivingId))}; !reflect.DeepEqual(references, expected) {
+		t.Errorf("closed finding references %v instead of %v", references, expected)
+	}
+}
@@ -0,0 +1,107 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"rtkcsm/connector/visualization"
+	"time"
+)
+
+const TCP_SINK_TIMEOUT = 10 * time.Second
+
+// facility local0 of syslog
+const SYSLOG_FACILITY = 16
+
+// syslog severities of the OCSF severities, unknown severities are notices
+var ocsfSeveritiesToSyslogSeverities = map[visualization.OCSFSeverity]int{
+	visualization.OCSFSeverityInformational: 6,
+	visualization.OCSFSeverityLow:           5,
+	visualization.OCSFSeverityMedium:        4,
+	visualization.OCSFSeverityHigh:          3,
+	visualization.OCSFSeverityCritical:      2,
+	visualization.OCSFSeverityFatal:         0,
+}
+
+// TcpSink writes incidents as JSON lines or as RFC 5424 syslog messages with octet counting
+// (RFC 6587) to a TCP endpoint. The connection is established again after an error.
+type TcpSink struct {
+	Address    string
+	Syslog     bool
+	connection net.Conn
+	hostname   string
+}
+
+func NewTcpSink(address string, syslog bool) *TcpSink {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &TcpSink{
+		Address:  address,
+		Syslog:   syslog,
+		hostname: hostname,
+	}
+}
+
+func (sink *TcpSink) Send(finding visualization.OCSFIncidentFinding) error {
+	message, err := json.Marshal(finding)
+	if err != nil {
+		return err
+	}
+
+	if sink.Syslog {
+		message = sink.syslogFrame(finding, message)
+	} else {
+		message = append(message, '\n')
+	}
+
+	if sink.connection == nil {
+		sink.connection, err = net.DialTimeout("tcp", sink.Address, TCP_SINK_TIMEOUT)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := sink.connection.SetWriteDeadline(time.Now().Add(TCP_SINK_TIMEOUT)); err != nil {
+		sink.reset()
+		return err
+	}
+
+	if _, err := sink.connection.Write(message); err != nil {
+		sink.reset()
+		return err
+	}
+
+	return nil
+}
+
+// syslogFrame formats an RFC 5424 message and prefixes it with its length
+func (sink *TcpSink) syslogFrame(finding visualization.OCSFIncidentFinding, message []byte) []byte {
+	severity, ok := ocsfSeveritiesToSyslogSeverities[finding.Severity]
+	if !ok {
+		severity = 5
+	}
+
+	header := fmt.Sprintf("<%d>1 %s %s rtkcsm %d incident - ", SYSLOG_FACILITY*8+severity, time.Now().UTC().Format("2006-01-02T15:04:05.000Z"), sink.hostname, os.Getpid())
+	message = append([]byte(header), message...)
+
+	return append([]byte(fmt.Sprintf("%d ", len(message))), message...)
+}
+
+func (sink *TcpSink) reset() {
+	sink.connection.Close()
+	sink.connection = nil
+}
+
+func (sink *TcpSink) Close() error {
+	if sink.connection == nil {
+		return nil
+	}
+
+	err := sink.connection.Close()
+	sink.connection = nil
+	return err
+}
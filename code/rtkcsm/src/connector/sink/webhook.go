@@ -0,0 +1,64 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"rtkcsm/component/structure"
+	"rtkcsm/connector/visualization"
+	"time"
+)
+
+const WEBHOOK_TIMEOUT = 10 * time.Second
+
+// WebhookSink posts every incident or notification of an alert rule as a JSON document to a URL
+type WebhookSink struct {
+	URL    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		URL: url,
+		client: &http.Client{
+			Timeout: WEBHOOK_TIMEOUT,
+		},
+	}
+}
+
+func (sink *WebhookSink) Send(finding visualization.OCSFIncidentFinding) error {
+	return sink.post(finding)
+}
+
+func (sink *WebhookSink) Notify(notification structure.AlertNotification) error {
+	return sink.post(notification)
+}
+
+func (sink *WebhookSink) post(document any) error {
+	body, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	response, err := sink.client.Post(sink.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// the connection can only be reused if the body was read
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+func (sink *WebhookSink) Close() error {
+	sink.client.CloseIdleConnections()
+	return nil
+}
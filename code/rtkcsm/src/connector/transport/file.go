@@ -1,21 +1,48 @@
 package transport
 
 import (
+	"context"
 	"os"
 	"rtkcsm/component/behaviour"
 	"rtkcsm/component/structure"
 	"rtkcsm/connector/reader"
+	"time"
 )
 
 type FileTransport[T structure.Stage, K structure.Stage] struct {
-	FilePath string
+	FilePath       string
+	Follow         bool          // wait for appended lines and handle log rotation
+	OffsetFilePath string        // persists the offset of correlated lines in follow mode, lines are delivered at least once
+	PollInterval   time.Duration // interval of checking for new lines in follow mode
 }
 
-func (transport *FileTransport[T, K]) Start(rtkcsm behaviour.RTKCSM[T, K], reader reader.AlertReader[T, K]) error {
+func (transport *FileTransport[T, K]) Start(ctx context.Context, rtkcsm behaviour.RTKCSM[T, K], reader reader.AlertReader[T, K]) error {
+	if transport.Follow {
+		follower, err := newFileFollower(transport.FilePath, transport.OffsetFilePath, transport.PollInterval)
+		if err != nil {
+			return err
+		}
+
+		defer follower.Close()
+
+		// a stopped follower ends like a file after the lines that were read are correlated
+		defer closeOnCancel(ctx, func() error {
+			follower.Stop()
+			return nil
+		})()
+
+		return reader.ChannelAlerts(&followedRTKCSM[T, K]{RTKCSM: rtkcsm, follower: follower}, follower)
+	}
+
 	file, err := os.Open(transport.FilePath)
 	if err != nil {
 		return err
 	}
+	defer closeOnCancel(ctx, file.Close)()
 
-	return reader.ChannelAlerts(rtkcsm, file)
+	err = reader.ChannelAlerts(rtkcsm, file)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
 }
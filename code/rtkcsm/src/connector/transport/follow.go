@@ -0,0 +1,446 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"sync"
+	"time"
+)
+
+const DEFAULT_FOLLOW_POLL_INTERVAL = time.Second
+
+// Number of bytes at the beginning of a file used to recognize it after a restart
+const FINGERPRINT_LENGTH = 1024
+
+const followReadSize = 64 * 1024
+
+// offset, fingerprint length, fingerprint
+const offsetStateFormat = "%020d %04d %064s\n"
+
+type offsetState struct {
+	file              *os.File
+	offset            int64
+	fingerprintLength int
+	fingerprint       string
+}
+
+func openOffsetState(path string) (*offsetState, error) {
+	file, err := os.OpenFile(filepath.Clean(path), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &offsetState{
+		file: file,
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) > 0 {
+		_, err = fmt.Sscanf(string(content), offsetStateFormat, &state.offset, &state.fingerprintLength, &state.fingerprint)
+		if err != nil {
+			log.Printf("ignoring invalid offset file %s: %s", path, err)
+			state.offset = 0
+			state.fingerprintLength = 0
+			state.fingerprint = ""
+		}
+	}
+
+	return state, nil
+}
+
+func (s *offsetState) write() error {
+	_, err := s.file.WriteAt([]byte(fmt.Sprintf(offsetStateFormat, s.offset, s.fingerprintLength, s.fingerprint)), 0)
+	return err
+}
+
+// lineEnd is the offset after a line handed out, lines of previous files have an older generation
+type lineEnd struct {
+	offset     int64
+	generation int
+}
+
+// fileFollower is a reader that waits for appended lines instead of returning io.EOF and
+// reopens the file after rotation. Only complete lines are handed out and their end offsets
+// are persisted by commitLines once the alerts of the lines are correlated, so lines are
+// delivered at least once: lines that are not committed when the process stops are read
+// again after a restart.
+type fileFollower struct {
+	path         string
+	file         *os.File
+	pollInterval time.Duration
+	state        *offsetState
+
+	pending         []byte
+	readOffset      int64 // bytes read from the file
+	deliveredOffset int64 // bytes handed out to the reader
+
+	// guards the file, the state and the line ends, which are committed by the correlation
+	mutex      sync.Mutex
+	lineEnds   []lineEnd // ends of the lines handed out that are not committed yet
+	generation int       // incremented when a rotated file is read from the beginning
+
+	stop      chan struct{}
+	stopOnce  sync.Once
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newFileFollower(path string, offsetFilePath string, pollInterval time.Duration) (*fileFollower, error) {
+	if pollInterval <= 0 {
+		pollInterval = DEFAULT_FOLLOW_POLL_INTERVAL
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	follower := &fileFollower{
+		path:         path,
+		file:         file,
+		pollInterval: pollInterval,
+		pending:      []byte{},
+		stop:         make(chan struct{}),
+	}
+
+	if offsetFilePath != "" {
+		follower.state, err = openOffsetState(offsetFilePath)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		if err := follower.resume(); err != nil {
+			follower.Close()
+			return nil, err
+		}
+	}
+
+	return follower, nil
+}
+
+func (f *fileFollower) fingerprint(length int) (string, error) {
+	buffer := make([]byte, length)
+	_, err := f.file.ReadAt(buffer, 0)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(buffer)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// resume continues at the persisted offset if the file was not rotated in the meantime
+func (f *fileFollower) resume() error {
+	if f.state.offset == 0 {
+		return nil
+	}
+
+	info, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() >= f.state.offset && int64(f.state.fingerprintLength) <= info.Size() {
+		fingerprint, err := f.fingerprint(f.state.fingerprintLength)
+		if err != nil {
+			return err
+		}
+
+		if fingerprint == f.state.fingerprint {
+			if _, err := f.file.Seek(f.state.offset, io.SeekStart); err != nil {
+				return err
+			}
+
+			f.readOffset = f.state.offset
+			f.deliveredOffset = f.state.offset
+			log.Printf("resuming %s at offset %d", f.path, f.state.offset)
+
+			return nil
+		}
+	}
+
+	log.Printf("%s changed since offset %d was stored, reading from the beginning", f.path, f.state.offset)
+	f.state.offset = 0
+	f.state.fingerprintLength = 0
+	f.state.fingerprint = ""
+
+	return f.state.write()
+}
+
+// commitLines persists the end of the next lines handed out, after the alerts of the lines are correlated
+func (f *fileFollower) commitLines(lines int) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	lines = min(lines, len(f.lineEnds))
+	if lines == 0 {
+		return nil
+	}
+
+	end := f.lineEnds[lines-1]
+	f.lineEnds = f.lineEnds[lines:]
+
+	// the offset of a rotated file was already reset
+	if f.state == nil || end.generation != f.generation {
+		return nil
+	}
+
+	fingerprintLength := int(min(end.offset, FINGERPRINT_LENGTH))
+	if fingerprintLength != f.state.fingerprintLength {
+		fingerprint, err := f.fingerprint(fingerprintLength)
+		if err != nil {
+			return err
+		}
+
+		f.state.fingerprintLength = fingerprintLength
+		f.state.fingerprint = fingerprint
+	}
+
+	f.state.offset = end.offset
+
+	return f.state.write()
+}
+
+func (f *fileFollower) deliver(p []byte) int {
+	if len(f.pending) == 0 {
+		return 0
+	}
+
+	limit := min(len(f.pending), len(p))
+	length := bytes.LastIndexByte(f.pending[:limit], '\n') + 1
+
+	if length == 0 {
+		if limit == len(f.pending) {
+			return 0
+		}
+
+		// line is longer than p, the scanner requests the remainder
+		length = limit
+	}
+
+	copy(p, f.pending[:length])
+
+	f.mutex.Lock()
+	for i, character := range f.pending[:length] {
+		if character == '\n' {
+			f.lineEnds = append(f.lineEnds, lineEnd{
+				offset:     f.deliveredOffset + int64(i) + 1,
+				generation: f.generation,
+			})
+		}
+	}
+	f.mutex.Unlock()
+
+	f.pending = f.pending[length:]
+	f.deliveredOffset += int64(length)
+
+	return length
+}
+
+func (f *fileFollower) fill() (int, error) {
+	buffer := make([]byte, followReadSize)
+	n, err := f.file.Read(buffer)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	f.pending = append(f.pending, buffer[:n]...)
+	f.readOffset += int64(n)
+
+	return n, nil
+}
+
+func (f *fileFollower) reset(file *os.File) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if file != f.file {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = file
+	} else if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	f.pending = []byte{}
+	f.readOffset = 0
+	f.deliveredOffset = 0
+	f.generation += 1
+
+	if f.state != nil {
+		f.state.offset = 0
+		f.state.fingerprintLength = 0
+		f.state.fingerprint = ""
+		return f.state.write()
+	}
+
+	return nil
+}
+
+// rotate detects truncation (copytruncate) and replaced files (create) after the end of the file was reached
+func (f *fileFollower) rotate() (bool, error) {
+	info, err := f.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if info.Size() < f.readOffset {
+		log.Printf("%s was truncated, reading from the beginning", f.path)
+		return true, f.reset(f.file)
+	}
+
+	pathInfo, err := os.Stat(f.path)
+	if err != nil {
+		// the new file might not be created yet
+		return false, nil
+	}
+
+	if !os.SameFile(info, pathInfo) {
+		if len(f.pending) > 0 {
+			// hand out the last line of the old file first
+			f.pending = append(f.pending, '\n')
+			return true, nil
+		}
+
+		file, err := os.Open(filepath.Clean(f.path))
+		if err != nil {
+			return false, nil
+		}
+
+		log.Printf("%s was rotated, reading the new file", f.path)
+		return true, f.reset(file)
+	}
+
+	return false, nil
+}
+
+func (f *fileFollower) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for {
+		select {
+		case <-f.stop:
+			return 0, io.EOF
+		default:
+		}
+
+		if n := f.deliver(p); n > 0 {
+			return n, nil
+		}
+
+		n, err := f.fill()
+		if err != nil {
+			return 0, err
+		}
+
+		if n == 0 {
+			rotated, err := f.rotate()
+			if err != nil {
+				return 0, err
+			}
+
+			if !rotated {
+				select {
+				case <-f.stop:
+				case <-time.After(f.pollInterval):
+				}
+			}
+		}
+	}
+}
+
+// Stop lets a blocked Read return io.EOF
+func (f *fileFollower) Stop() {
+	f.stopOnce.Do(func() {
+		close(f.stop)
+	})
+}
+
+// Close can be called by the reader and the transport, lines that are not committed are read again
+// after a restart
+func (f *fileFollower) Close() error {
+	f.Stop()
+
+	f.closeOnce.Do(func() {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		if f.state != nil {
+			f.closeErr = f.state.file.Close()
+		}
+
+		if err := f.file.Close(); f.closeErr == nil {
+			f.closeErr = err
+		}
+	})
+
+	return f.closeErr
+}
+
+// followedRTKCSM correlates the alerts of a followed file one after another and commits the lines
+// whose alerts are correlated, so that a restart continues after the last correlated line. With a
+// write-ahead log the alerts are persisted when they are correlated.
+type followedRTKCSM[T structure.Stage, K structure.Stage] struct {
+	behaviour.RTKCSM[T, K]
+
+	follower *fileFollower
+	mutex    sync.Mutex
+	pending  []int // alerts of the observed lines that are not correlated yet
+}
+
+// ObserveLine is called before the alerts of the line are passed to AddAlerts
+func (c *followedRTKCSM[T, K]) ObserveLine(alerts int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.pending = append(c.pending, alerts)
+	c.commit()
+}
+
+func (c *followedRTKCSM[T, K]) AddAlerts(alerts <-chan structure.Alert, handleError func(alert structure.Alert, err error)) {
+	for alert := range alerts {
+		if err := c.RTKCSM.AddAlert(alert); err != nil && handleError != nil {
+			handleError(alert, err)
+		}
+
+		c.mutex.Lock()
+		if len(c.pending) > 0 {
+			c.pending[0] -= 1
+		}
+		c.commit()
+		c.mutex.Unlock()
+	}
+}
+
+// commit expects the mutex to be locked and commits the lines at the beginning without pending alerts
+func (c *followedRTKCSM[T, K]) commit() {
+	lines := 0
+	for lines < len(c.pending) && c.pending[lines] == 0 {
+		lines += 1
+	}
+
+	if lines == 0 {
+		return
+	}
+
+	c.pending = c.pending[lines:]
+	if err := c.follower.commitLines(lines); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,157 @@
+package transport
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"testing"
+	"time"
+)
+
+func appendLines(t *testing.T, path string, lines ...string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for _, line := range lines {
+		if _, err := file.WriteString(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func startFollower(t *testing.T, path string, offsetPath string) (*fileFollower, chan string) {
+	follower, err := newFileFollower(path, offsetPath, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make(chan string, 100)
+	go func() {
+		scanner := bufio.NewScanner(follower)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+			if err := follower.commitLines(1); err != nil {
+				t.Error(err)
+			}
+		}
+		follower.Close()
+		close(lines)
+	}()
+
+	return follower, lines
+}
+
+func expectLines(t *testing.T, lines chan string, expected ...string) {
+	for _, expectedLine := range expected {
+		select {
+		case line := <-lines:
+			if line != expectedLine {
+				t.Fatalf("got line %q, expected %q", line, expectedLine)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for line %q", expectedLine)
+		}
+	}
+}
+
+func stopFollower(t *testing.T, follower *fileFollower, lines chan string) {
+	follower.Stop()
+	for line := range lines {
+		t.Fatalf("unexpected line %q", line)
+	}
+}
+
+func TestFileFollower(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "eve.json")
+	offsetPath := filepath.Join(directory, "eve.offset")
+
+	appendLines(t, path, "first\n", "second\n")
+	follower, lines := startFollower(t, path, offsetPath)
+	expectLines(t, lines, "first", "second")
+
+	// incomplete lines are not handed out before they are terminated
+	appendLines(t, path, "thi")
+	appendLines(t, path, "rd\n")
+	expectLines(t, lines, "third")
+	stopFollower(t, follower, lines)
+
+	// a restart resumes at the persisted offset
+	appendLines(t, path, "fourth\n")
+	follower, lines = startFollower(t, path, offsetPath)
+	expectLines(t, lines, "fourth")
+
+	// copytruncate rotation
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	appendLines(t, path, "fifth\n")
+	expectLines(t, lines, "fifth")
+
+	// create rotation
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, path+".1", "sixth\n")
+	appendLines(t, path, "seventh\n")
+	expectLines(t, lines, "sixth", "seventh")
+	stopFollower(t, follower, lines)
+
+	// a restart after rotation does not ingest lines twice
+	follower, lines = startFollower(t, path, offsetPath)
+	appendLines(t, path, "eighth\n")
+	expectLines(t, lines, "eighth")
+	stopFollower(t, follower, lines)
+}
+
+// correlatedRTKCSM counts the alerts passed to AddAlert
+type correlatedRTKCSM struct {
+	behaviour.RTKCSM[structure.SimplifiedUKCStage, structure.UKCStage]
+	alerts int
+}
+
+func (c *correlatedRTKCSM) AddAlert(alert structure.Alert) error {
+	c.alerts += 1
+	return nil
+}
+
+func TestFollowedRTKCSMCommit(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "eve.json")
+	appendLines(t, path, "alert\n", "empty\n")
+
+	follower, err := newFileFollower(path, filepath.Join(directory, "eve.offset"), 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.Close()
+
+	scanner := bufio.NewScanner(follower)
+	for range 2 {
+		scanner.Scan()
+	}
+
+	rtkcsm := &followedRTKCSM[structure.SimplifiedUKCStage, structure.UKCStage]{RTKCSM: &correlatedRTKCSM{}, follower: follower}
+	rtkcsm.ObserveLine(1)
+	rtkcsm.ObserveLine(0)
+
+	// lines are read but the alert of the first line is not correlated yet
+	if follower.state.offset != 0 {
+		t.Errorf("committed offset %d before the alert was correlated", follower.state.offset)
+	}
+
+	alerts := make(chan structure.Alert, 1)
+	alerts <- structure.Alert{}
+	close(alerts)
+	rtkcsm.AddAlerts(alerts, nil)
+
+	if follower.state.offset != 12 {
+		t.Errorf("committed offset %d instead of 12 after the alert was correlated", follower.state.offset)
+	}
+}
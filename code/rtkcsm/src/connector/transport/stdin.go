@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"context"
 	"os"
 	"rtkcsm/component/behaviour"
 	"rtkcsm/component/structure"
@@ -9,6 +10,12 @@ import (
 
 type StdinTransport[T structure.Stage, K structure.Stage] struct{}
 
-func (transport *StdinTransport[T, K]) Start(rtkcsm behaviour.RTKCSM[T, K], reader reader.AlertReader[T, K]) error {
-	return reader.ChannelAlerts(rtkcsm, os.Stdin)
+func (transport *StdinTransport[T, K]) Start(ctx context.Context, rtkcsm behaviour.RTKCSM[T, K], reader reader.AlertReader[T, K]) error {
+	defer closeOnCancel(ctx, os.Stdin.Close)()
+
+	err := reader.ChannelAlerts(rtkcsm, os.Stdin)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
 }
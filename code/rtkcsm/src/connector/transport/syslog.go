@@ -0,0 +1,315 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"regexp"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"rtkcsm/connector/reader"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MAX_SYSLOG_MESSAGE_SIZE is the largest line that alert readers accept
+const MAX_SYSLOG_MESSAGE_SIZE = bufio.MaxScanTokenSize - 1
+
+// SyslogTransport receives RFC 5424 and RFC 3164 messages over UDP and TCP and hands their payload
+// to the alert reader, e.g. of rsyslog or syslog-ng forwarding eve.json. TCP connections can use
+// octet-counting or non-transparent (newline) framing (RFC 6587). Messages without a syslog header
+// are handed over unchanged.
+type SyslogTransport[T structure.Stage, K structure.Stage] struct {
+	ListenAddress string
+	Network       string // "udp", "tcp" or both if not set
+}
+
+func (transport *SyslogTransport[T, K]) Start(ctx context.Context, rtkcsm behaviour.RTKCSM[T, K], reader reader.AlertReader[T, K]) error {
+	var packetConnection net.PacketConn
+	var listener net.Listener
+	var err error
+
+	switch transport.Network {
+	case "", "udp", "tcp":
+	default:
+		return fmt.Errorf("unknown syslog network: %s", transport.Network)
+	}
+
+	if transport.Network != "tcp" {
+		packetConnection, err = net.ListenPacket("udp", transport.ListenAddress)
+		if err != nil {
+			return err
+		}
+		defer packetConnection.Close()
+		defer closeOnCancel(ctx, packetConnection.Close)()
+	}
+
+	if transport.Network != "udp" {
+		listener, err = net.Listen("tcp", transport.ListenAddress)
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+		defer closeOnCancel(ctx, listener.Close)()
+	}
+
+	err = serveSyslog(rtkcsm, reader, packetConnection, listener)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// serveSyslog reads messages until a connection fails, both connections are optional. It returns
+// after the reader has passed the messages that were received to the RTKCSM.
+func serveSyslog[T structure.Stage, K structure.Stage](rtkcsm behaviour.RTKCSM[T, K], reader reader.AlertReader[T, K], packetConnection net.PacketConn, listener net.Listener) error {
+	pipeReader, pipeWriter := io.Pipe()
+	payloads := &syslogPayloadWriter{writer: pipeWriter}
+	errs := make(chan error, 2)
+
+	if packetConnection != nil {
+		go func() {
+			errs <- payloads.readDatagrams(packetConnection)
+		}()
+	}
+
+	if listener != nil {
+		go func() {
+			for {
+				connection, err := listener.Accept()
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				go payloads.readConnection(connection)
+			}
+		}()
+	}
+
+	read := make(chan error, 1)
+	go func() {
+		read <- reader.ChannelAlerts(rtkcsm, pipeReader)
+	}()
+
+	select {
+	case err := <-errs:
+		pipeWriter.CloseWithError(err)
+		<-read
+		return err
+	case err := <-read:
+		pipeReader.CloseWithError(err)
+		return err
+	}
+}
+
+// syslogPayloadWriter writes the payloads of all connections as lines
+type syslogPayloadWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func (w *syslogPayloadWriter) write(message []byte) error {
+	payload := bytes.TrimRight(syslogPayload(message), "\r\n")
+	if len(bytes.TrimSpace(payload)) == 0 {
+		return nil
+	}
+
+	// alert readers read lines, so a payload has to be a single line
+	payload = bytes.ReplaceAll(payload, []byte("\r\n"), []byte(" "))
+	payload = bytes.ReplaceAll(payload, []byte("\n"), []byte(" "))
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	_, err := w.writer.Write(append(payload, '\n'))
+	return err
+}
+
+func (w *syslogPayloadWriter) readDatagrams(connection net.PacketConn) error {
+	buffer := make([]byte, 65536)
+
+	for {
+		length, _, err := connection.ReadFrom(buffer)
+		if err != nil {
+			return err
+		}
+
+		if err := w.write(buffer[:length]); err != nil {
+			return err
+		}
+	}
+}
+
+func (w *syslogPayloadWriter) readConnection(connection net.Conn) {
+	defer connection.Close()
+
+	frames := bufio.NewReaderSize(connection, MAX_SYSLOG_MESSAGE_SIZE+16)
+	for {
+		frame, err := readSyslogFrame(frames)
+		if len(frame) > 0 {
+			if err := w.write(frame); err != nil {
+				return
+			}
+		}
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("error reading syslog from %s: %s", connection.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+// readSyslogFrame reads a message with octet-counting ("LENGTH MESSAGE") or non-transparent framing
+// (newline terminated), the framing can change between messages
+func readSyslogFrame(reader *bufio.Reader) ([]byte, error) {
+	// skip empty lines between frames
+	for {
+		character, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		if character != '\n' && character != '\r' && character != ' ' {
+			reader.UnreadByte()
+			break
+		}
+	}
+
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		lengthField, err := reader.ReadString(' ')
+		if err != nil {
+			return nil, fmt.Errorf("incomplete octet count: %w", err)
+		}
+
+		length, err := strconv.Atoi(lengthField[:len(lengthField)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid octet count: %q", lengthField)
+		}
+		if length > MAX_SYSLOG_MESSAGE_SIZE {
+			return nil, fmt.Errorf("message of %d bytes exceeds %d bytes", length, MAX_SYSLOG_MESSAGE_SIZE)
+		}
+
+		message := make([]byte, length)
+		if _, err := io.ReadFull(reader, message); err != nil {
+			return nil, fmt.Errorf("incomplete message: %w", err)
+		}
+		return message, nil
+	}
+
+	message, err := reader.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("message exceeds %d bytes", MAX_SYSLOG_MESSAGE_SIZE)
+	}
+	// the last message of a connection does not need to be terminated
+	return bytes.Clone(bytes.TrimRight(message, "\r\n")), err
+}
+
+// syslogTag matches the tag of RFC 3164 messages, e.g. "suricata[1234]: "
+var syslogTag = regexp.MustCompile(`^[A-Za-z0-9_\-./]{1,48}(\[[0-9]+\])?: `)
+
+var utf8ByteOrderMark = []byte("\xEF\xBB\xBF")
+
+// syslogPayload strips the header of a RFC 5424 or RFC 3164 message
+func syslogPayload(message []byte) []byte {
+	if len(message) == 0 || message[0] != '<' {
+		return message
+	}
+
+	end := bytes.IndexByte(message, '>')
+	if end < 2 || end > 4 {
+		return message
+	}
+	if _, err := strconv.ParseUint(string(message[1:end]), 10, 8); err != nil {
+		return message
+	}
+
+	rest := message[end+1:]
+	if len(rest) >= 2 && rest[0] >= '1' && rest[0] <= '9' && rest[1] == ' ' {
+		return rfc5424Payload(rest[2:])
+	}
+	return rfc3164Payload(rest)
+}
+
+// rfc5424Payload skips TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA
+func rfc5424Payload(message []byte) []byte {
+	for range 5 {
+		separator := bytes.IndexByte(message, ' ')
+		if separator < 0 {
+			return nil
+		}
+		message = message[separator+1:]
+	}
+
+	if len(message) > 0 && message[0] == '-' {
+		message = message[1:]
+	} else {
+		// structured data elements, in which quoted values escape '"', '\' and ']'
+		for len(message) > 0 && message[0] == '[' {
+			quoted := false
+			end := -1
+			for i := 1; i < len(message) && end < 0; i++ {
+				switch {
+				case quoted && message[i] == '\\':
+					i++
+				case message[i] == '"':
+					quoted = !quoted
+				case !quoted && message[i] == ']':
+					end = i
+				}
+			}
+
+			if end < 0 {
+				return nil
+			}
+			message = message[end+1:]
+		}
+	}
+
+	message = bytes.TrimPrefix(message, []byte(" "))
+	return bytes.TrimPrefix(message, utf8ByteOrderMark)
+}
+
+// rfc3164Payload skips TIMESTAMP HOSTNAME TAG, messages without a timestamp are payloads (RFC 3164 4.3.3)
+func rfc3164Payload(message []byte) []byte {
+	if len(message) > len(time.Stamp) && message[len(time.Stamp)] == ' ' {
+		if _, err := time.Parse(time.Stamp, string(message[:len(time.Stamp)])); err != nil {
+			return message
+		}
+		message = message[len(time.Stamp)+1:]
+	} else {
+		// RFC 3339 timestamps of rsyslog's forward format
+		separator := bytes.IndexByte(message, ' ')
+		if separator < 0 {
+			return message
+		}
+		if _, err := time.Parse(time.RFC3339Nano, string(message[:separator])); err != nil {
+			return message
+		}
+		message = message[separator+1:]
+	}
+
+	// the hostname can be missing, e.g. if it is the tag or the payload
+	if separator := bytes.IndexByte(message, ' '); separator > 0 && message[0] != '{' && !bytes.HasPrefix(message, []byte("CEF:")) && !bytes.HasPrefix(message, []byte("LEEF:")) {
+		message = message[separator+1:]
+	}
+
+	if tag := syslogTag.Find(message); tag != nil {
+		message = message[len(tag):]
+	}
+
+	return message
+}
@@ -0,0 +1,165 @@
+package transport
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogPayload(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{"without header", `{"src_ip":"10.0.0.1"}`, `{"src_ip":"10.0.0.1"}`},
+		{"rfc 5424", `<165>1 2024-05-01T10:00:00.003Z ids01 suricata 1234 alert - {"src_ip":"10.0.0.1"}`, `{"src_ip":"10.0.0.1"}`},
+		{"rfc 5424 with nil values", `<13>1 - - - - - - CEF:0|Vendor|IDS|1.0|100|name|5|src=10.0.0.1`, `CEF:0|Vendor|IDS|1.0|100|name|5|src=10.0.0.1`},
+		{"rfc 5424 with structured data", `<165>1 2024-05-01T10:00:00Z ids01 suricata - - [exampleSDID@32473 iut="3" eventSource="Application"][origin ip="10.0.0.5"] {"a":1}`, `{"a":1}`},
+		{"rfc 5424 with escaped structured data", `<165>1 2024-05-01T10:00:00Z ids01 suricata - - [meta note="a \"quoted\" \] bracket"] {"a":1}`, `{"a":1}`},
+		{"rfc 5424 with byte order mark", "<165>1 2024-05-01T10:00:00Z ids01 suricata - - - \xEF\xBB\xBF{\"a\":1}", `{"a":1}`},
+		{"rfc 5424 without message", `<165>1 2024-05-01T10:00:00Z ids01 suricata - - -`, ``},
+		{"rfc 5424 with incomplete structured data", `<165>1 2024-05-01T10:00:00Z ids01 suricata - - [meta note="]`, ``},
+		{"rfc 3164", `<13>May  1 10:00:00 ids01 suricata[1234]: {"a":1}`, `{"a":1}`},
+		{"rfc 3164 with a two-digit day", `<13>May 11 10:00:00 ids01 suricata: {"a":1}`, `{"a":1}`},
+		{"rfc 3164 without tag", `<13>May  1 10:00:00 ids01 {"a":1}`, `{"a":1}`},
+		{"rfc 3164 without hostname", `<13>May  1 10:00:00 suricata[1234]: {"a":1}`, `{"a":1}`},
+		{"rfc 3164 with cef", `<134>May  1 10:00:00 fw01 CEF:0|Vendor|Firewall|1.0|100|name|5|src=10.0.0.1`, `CEF:0|Vendor|Firewall|1.0|100|name|5|src=10.0.0.1`},
+		{"rfc 3164 with cef without hostname", `<134>May  1 10:00:00 CEF:0|Vendor|Firewall|1.0|100|name|5|src=10.0.0.1`, `CEF:0|Vendor|Firewall|1.0|100|name|5|src=10.0.0.1`},
+		{"rfc 3164 with rfc 3339 timestamp", `<13>2024-05-01T10:00:00.123+02:00 ids01 suricata[1234]: {"a":1}`, `{"a":1}`},
+		{"rfc 3164 without timestamp", `<13>suricata: {"a":1}`, `suricata: {"a":1}`},
+		{"invalid priority", `<1000>1 - - - - - - {"a":1}`, `<1000>1 - - - - - - {"a":1}`},
+	}
+
+	for _, test := range tests {
+		if payload := string(syslogPayload([]byte(test.message))); payload != test.expected {
+			t.Errorf("%s: expected %q instead of %q", test.name, test.expected, payload)
+		}
+	}
+}
+
+func TestSyslogFraming(t *testing.T) {
+	tests := []struct {
+		name     string
+		stream   string
+		expected []string
+		invalid  bool
+	}{
+		{"non-transparent", "<13>first\n<13>second\r\n\n<13>third", []string{"<13>first", "<13>second", "<13>third"}, false},
+		{"octet-counting", "9 <13>first10 <13>second", []string{"<13>first", "<13>second"}, false},
+		{"octet-counting with newlines", "14 <13>first\nline\n", []string{"<13>first\nline"}, false},
+		{"mixed", "9 <13>first\n<13>second\n10 <13>third\n", []string{"<13>first", "<13>second", "<13>third\n"}, false},
+		{"incomplete message", "20 <13>first", []string{}, true},
+		{"invalid octet count", "9x <13>first", []string{}, true},
+		{"too large message", fmt.Sprintf("%d <13>first", MAX_SYSLOG_MESSAGE_SIZE+1), []string{}, true},
+	}
+
+	for _, test := range tests {
+		reader := bufio.NewReader(strings.NewReader(test.stream))
+
+		frames := []string{}
+		var err error
+		for {
+			var frame []byte
+			frame, err = readSyslogFrame(reader)
+			if len(frame) > 0 {
+				frames = append(frames, string(frame))
+			}
+			if err != nil {
+				break
+			}
+		}
+
+		if test.invalid != !errors.Is(err, io.EOF) {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if strings.Join(frames, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("%s: expected frames %q instead of %q", test.name, test.expected, frames)
+		}
+	}
+}
+
+// lineReader passes the lines of the transport to a channel instead of reading alerts
+type lineReader struct {
+	lines chan string
+}
+
+func (r *lineReader) ChannelAlerts(rtkcsm behaviour.RTKCSM[structure.SimplifiedUKCStage, structure.UKCStage], reader io.ReadCloser) error {
+	defer reader.Close()
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		r.lines <- scanner.Text()
+	}
+	return scanner.Err()
+}
+
+func TestSyslogTransport(t *testing.T) {
+	packetConnection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := &lineReader{lines: make(chan string, 100)}
+	served := make(chan error)
+	go func() {
+		served <- serveSyslog[structure.SimplifiedUKCStage, structure.UKCStage](nil, reader, packetConnection, listener)
+	}()
+
+	expectLine := func(expected string) {
+		select {
+		case line := <-reader.lines:
+			if line != expected {
+				t.Errorf("expected line %q instead of %q", expected, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("missing line %q", expected)
+		}
+	}
+
+	udp, err := net.Dial("udp", packetConnection.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+
+	if _, err := udp.Write([]byte("<13>May  1 10:00:00 ids01 suricata[1234]: {\"a\":1}\n")); err != nil {
+		t.Fatal(err)
+	}
+	expectLine(`{"a":1}`)
+
+	tcp, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// payloads with newlines are passed as a single line
+	message := "<165>1 2024-05-01T10:00:00Z ids01 suricata - - - {\"b\":\n2}"
+	fmt.Fprintf(tcp, "%d %s<13>May  1 10:00:00 ids01 {\"c\":3}\n", len(message), message)
+	tcp.Close()
+	expectLine(`{"b": 2}`)
+	expectLine(`{"c":3}`)
+
+	listener.Close()
+	packetConnection.Close()
+
+	select {
+	case err := <-served:
+		if err == nil {
+			t.Errorf("closed connections did not stop the transport")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("transport did not stop")
+	}
+}
@@ -1,30 +1,46 @@
 package transport
 
 import (
+	"context"
 	"net"
 	"rtkcsm/component/behaviour"
 	"rtkcsm/component/structure"
 	"rtkcsm/connector/reader"
+	"sync"
 )
 
 type TcpTransport[T structure.Stage, K structure.Stage] struct {
 	ListenAddress string
 }
 
-func (transport *TcpTransport[T, K]) Start(rtkcsm behaviour.RTKCSM[T, K], reader reader.AlertReader[T, K]) error {
+// Start closes the listener and all connections when the context is cancelled and returns after
+// the alerts of all connections are read
+func (transport *TcpTransport[T, K]) Start(ctx context.Context, rtkcsm behaviour.RTKCSM[T, K], reader reader.AlertReader[T, K]) error {
 	listener, err := net.Listen("tcp", transport.ListenAddress)
 	if err != nil {
 		return err
 	}
-	defer listener.Close()
+	defer closeOnCancel(ctx, listener.Close)()
+
+	connections := sync.WaitGroup{}
+	defer connections.Wait()
 
 	for {
 		connection, err := listener.Accept()
 		if err != nil {
+			listener.Close()
+			if ctx.Err() != nil {
+				return nil
+			}
 			return err
 		}
 
-		go transport.handleConnection(connection, rtkcsm, reader)
+		connections.Add(1)
+		go func() {
+			defer connections.Done()
+			defer closeOnCancel(ctx, connection.Close)()
+			transport.handleConnection(connection, rtkcsm, reader)
+		}()
 	}
 }
 
@@ -0,0 +1,30 @@
+package transport
+
+import (
+	"context"
+	"rtkcsm/component/structure"
+	"testing"
+	"time"
+)
+
+func TestTcpTransportStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	transport := &TcpTransport[structure.SimplifiedUKCStage, structure.UKCStage]{ListenAddress: "127.0.0.1:0"}
+	stopped := make(chan error)
+	go func() {
+		stopped <- transport.Start(ctx, nil, &lineReader{lines: make(chan string, 1)})
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("cancelled transport returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("transport did not stop")
+	}
+}
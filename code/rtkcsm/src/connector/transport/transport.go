@@ -1,11 +1,22 @@
 package transport
 
 import (
+	"context"
 	"rtkcsm/component/behaviour"
 	"rtkcsm/component/structure"
 	"rtkcsm/connector/reader"
 )
 
+// Transport hands its input to an alert reader until the input ends or the context is cancelled,
+// e.g. on shutdown. Start returns after the alerts that were read are passed to the RTKCSM.
 type Transport[T structure.Stage, K structure.Stage] interface {
-	Start(rtkcsm behaviour.RTKCSM[T, K], reader reader.AlertReader[T, K]) error
+	Start(ctx context.Context, rtkcsm behaviour.RTKCSM[T, K], reader reader.AlertReader[T, K]) error
+}
+
+// closeOnCancel closes a connection or file when the context is cancelled, the returned function
+// stops waiting for the context
+func closeOnCancel(ctx context.Context, close func() error) func() bool {
+	return context.AfterFunc(ctx, func() {
+		close()
+	})
 }
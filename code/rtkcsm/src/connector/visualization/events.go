@@ -0,0 +1,92 @@
+package visualization
+
+import (
+	"io"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Events are collected for this interval, so that a burst of alerts results in one event per graph
+const EVENT_FLUSH_INTERVAL = 250 * time.Millisecond
+
+type pendingEventKey struct {
+	eventType structure.GraphEventType
+	id        structure.GraphID
+}
+
+// pendingEvents keeps the latest event per type and graph in the order of their first occurrence
+type pendingEvents struct {
+	keys   []pendingEventKey
+	events map[pendingEventKey]structure.GraphEvent
+}
+
+func newPendingEvents() *pendingEvents {
+	return &pendingEvents{
+		keys:   []pendingEventKey{},
+		events: map[pendingEventKey]structure.GraphEvent{},
+	}
+}
+
+func (p *pendingEvents) add(event structure.GraphEvent) {
+	key := pendingEventKey{
+		eventType: event.Type,
+		id:        event.ID,
+	}
+
+	if _, ok := p.events[key]; !ok {
+		p.keys = append(p.keys, key)
+	}
+	p.events[key] = event
+}
+
+func (p *pendingEvents) flush() []structure.GraphEvent {
+	events := []structure.GraphEvent{}
+	for _, key := range p.keys {
+		events = append(events, p.events[key])
+	}
+
+	p.keys = []pendingEventKey{}
+	p.events = map[pendingEventKey]structure.GraphEvent{}
+
+	return events
+}
+
+// streamGraphEvents sends graph events as server-sent events until the client disconnects
+func streamGraphEvents[T structure.Stage, K structure.Stage](ctx *gin.Context, rtkcsm behaviour.RTKCSM[T, K]) {
+	subscription := rtkcsm.SubscribeGraphEvents()
+	defer subscription.Close()
+
+	ticker := time.NewTicker(EVENT_FLUSH_INTERVAL)
+	defer ticker.Stop()
+
+	pending := newPendingEvents()
+
+	ctx.Stream(func(writer io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case event, ok := <-subscription.Events:
+			if ok {
+				pending.add(event)
+			}
+			return ok
+		case <-ticker.C:
+			events := pending.flush()
+
+			// the client missed events and has to reload all graphs
+			if subscription.Overflowed() {
+				events = []structure.GraphEvent{{
+					Type: structure.GraphEventReload,
+				}}
+			}
+
+			for _, event := range events {
+				ctx.SSEvent(string(event.Type), event)
+			}
+			return true
+		}
+	})
+}
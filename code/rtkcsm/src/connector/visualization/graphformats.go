@@ -0,0 +1,429 @@
+package visualization
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportedGraph is a graph that is written in a format of other tools
+type ExportedGraph[T structure.Stage] struct {
+	ID    structure.GraphID
+	Graph structure.PreComputedGraph[T]
+}
+
+func IsGraphFormat(format structure.ExportFormat) bool {
+	switch format {
+	case structure.ExportFormatGraphML, structure.ExportFormatDot, structure.ExportFormatCytoscape, structure.ExportFormatStix:
+		return true
+	default:
+		return false
+	}
+}
+
+func graphFormatContentType(format structure.ExportFormat) string {
+	switch format {
+	case structure.ExportFormatGraphML:
+		return "application/graphml+xml"
+	case structure.ExportFormatDot:
+		return "text/vnd.graphviz"
+	case structure.ExportFormatStix:
+		return "application/stix+json;version=2.1"
+	default:
+		return "application/json"
+	}
+}
+
+// ExportGraphs writes all graphs ordered by relevance in a format of other tools
+func ExportGraphs[T structure.Stage, K structure.Stage](writer io.Writer, rtkcsm behaviour.RTKCSM[T, K], format structure.ExportFormat) (int, error) {
+	graphs := []ExportedGraph[T]{}
+
+	query := structure.NewGraphQuery(0)
+	query.PageSize = structure.MAX_GRAPH_PAGE_SIZE
+
+	for {
+		list := rtkcsm.SearchGraphs(query)
+		for _, information := range list.Graphs {
+			// graphs can be removed between the pages
+			if graph := rtkcsm.GetGraph(information.ID); graph != nil {
+				graphs = append(graphs, ExportedGraph[T]{
+					ID:    information.ID,
+					Graph: graph.GetPreComputed(),
+				})
+			}
+		}
+
+		if len(list.Graphs) < query.PageSize {
+			break
+		}
+		query.Page += 1
+	}
+
+	return WriteGraphs(writer, format, graphs)
+}
+
+// WriteGraphs writes multiple graphs into one file. Cytoscape graphs are written as an array
+// and STIX objects of all graphs as one bundle.
+func WriteGraphs[T structure.Stage](writer io.Writer, format structure.ExportFormat, graphs []ExportedGraph[T]) (int, error) {
+	switch format {
+	case structure.ExportFormatGraphML:
+		return writeGraphML(writer, graphs)
+	case structure.ExportFormatDot:
+		return writeDot(writer, graphs)
+	case structure.ExportFormatCytoscape:
+		documents := []cytoscapeDocument{}
+		for _, graph := range graphs {
+			documents = append(documents, newCytoscapeDocument(graph))
+		}
+		return writeJson(writer, documents)
+	case structure.ExportFormatStix:
+		return writeJson(writer, NewStixBundle(graphs))
+	default:
+		return 0, fmt.Errorf("unknown graph format: %s", format)
+	}
+}
+
+// WriteGraph writes a single graph, which is a single document in all formats
+func WriteGraph[T structure.Stage](writer io.Writer, format structure.ExportFormat, graph ExportedGraph[T]) (int, error) {
+	if format == structure.ExportFormatCytoscape {
+		return writeJson(writer, newCytoscapeDocument(graph))
+	}
+
+	return WriteGraphs(writer, format, []ExportedGraph[T]{graph})
+}
+
+func writeJson(writer io.Writer, value any) (int, error) {
+	text, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return writer.Write(text)
+}
+
+type graphNode struct {
+	address    string
+	isInternal bool
+	zone       string
+	riskLevel  structure.RiskLevel
+}
+
+// graphNodes returns the hosts of a graph in the order of their first relation
+func graphNodes[T structure.Stage](graph structure.PreComputedGraph[T]) []graphNode {
+	nodes := []graphNode{}
+	known := map[string]bool{}
+
+	for _, relation := range graph.PreComputedDirectedRelations {
+		for _, node := range []graphNode{
+			{relation.From, relation.FromIsInternal, relation.FromZone, relation.FromRiskLevel},
+			{relation.To, relation.ToIsInternal, relation.ToZone, relation.ToRiskLevel},
+		} {
+			if !known[node.address] {
+				known[node.address] = true
+				nodes = append(nodes, node)
+			}
+		}
+	}
+
+	return nodes
+}
+
+func ukcStageNames(stages []structure.UKCStage) string {
+	names := []string{}
+	for _, stage := range stages {
+		names = append(names, stage.String())
+	}
+
+	return strings.Join(names, ",")
+}
+
+func formatTimestamp(timestamp int64) string {
+	return time.UnixMilli(timestamp).UTC().Format(time.RFC3339Nano)
+}
+
+// formatAddress converts the notation of graph addresses, which lists every byte of IPv6 addresses, into the
+// notation of RFC 5952 required by STIX and OCSF
+func formatAddress(address string) string {
+	return structure.ParseIPAddress(address).IP().String()
+}
+
+func formatFloat(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
+
+type graphMLKey struct {
+	XMLName xml.Name `xml:"key"`
+	ID      string   `xml:"id,attr"`
+	For     string   `xml:"for,attr"`
+	Name    string   `xml:"attr.name,attr"`
+	Type    string   `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLGraph struct {
+	XMLName     xml.Name      `xml:"graph"`
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Data        []graphMLData `xml:"data"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+var graphMLKeys = []graphMLKey{
+	{ID: "graph_relevance", For: "graph", Name: "relevance", Type: "double"},
+	{ID: "label", For: "node", Name: "label", Type: "string"},
+	{ID: "internal", For: "node", Name: "internal", Type: "boolean"},
+	{ID: "zone", For: "node", Name: "zone", Type: "string"},
+	{ID: "risk_level", For: "node", Name: "risk_level", Type: "double"},
+	{ID: "stage", For: "edge", Name: "stage", Type: "string"},
+	{ID: "ukc_stages", For: "edge", Name: "ukc_stages", Type: "string"},
+	{ID: "count", For: "edge", Name: "count", Type: "int"},
+	{ID: "signature_id", For: "edge", Name: "signature_id", Type: "long"},
+	{ID: "timestamp", For: "edge", Name: "timestamp", Type: "long"},
+	{ID: "time", For: "edge", Name: "time", Type: "string"},
+	{ID: "severity", For: "edge", Name: "severity", Type: "double"},
+	{ID: "confidence", For: "edge", Name: "confidence", Type: "double"},
+	{ID: "relevance", For: "edge", Name: "relevance", Type: "double"},
+	{ID: "cause", For: "edge", Name: "cause", Type: "string"},
+	{ID: "labels", For: "edge", Name: "labels", Type: "string"},
+}
+
+// writeGraphML writes all graphs into one document, so node ids are prefixed with the graph id to be unique
+func writeGraphML[T structure.Stage](writer io.Writer, graphs []ExportedGraph[T]) (int, error) {
+	keys, err := xml.MarshalIndent(graphMLKeys, "  ", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	header := xml.Header + `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n" + string(keys) + "\n"
+	totalSize, err := io.WriteString(writer, header)
+	if err != nil {
+		return totalSize, err
+	}
+
+	for _, graph := range graphs {
+		prefix := fmt.Sprintf("%d:", graph.ID)
+		element := graphMLGraph{
+			ID:          fmt.Sprintf("graph-%d", graph.ID),
+			EdgeDefault: "directed",
+			Data: []graphMLData{
+				{"graph_relevance", formatFloat(graph.Graph.ComputedRelevance)},
+			},
+			Nodes: []graphMLNode{},
+			Edges: []graphMLEdge{},
+		}
+
+		for _, node := range graphNodes(graph.Graph) {
+			data := []graphMLData{
+				{"label", node.address},
+				{"internal", strconv.FormatBool(node.isInternal)},
+				{"risk_level", formatFloat(float32(node.riskLevel))},
+			}
+			if node.zone != "" {
+				data = append(data, graphMLData{"zone", node.zone})
+			}
+
+			element.Nodes = append(element.Nodes, graphMLNode{
+				ID:   prefix + node.address,
+				Data: data,
+			})
+		}
+
+		for _, relation := range graph.Graph.PreComputedDirectedRelations {
+			element.Edges = append(element.Edges, graphMLEdge{
+				ID:     prefix + relation.ID,
+				Source: prefix + relation.From,
+				Target: prefix + relation.To,
+				Data: []graphMLData{
+					{"stage", fmt.Sprint(relation.MetaStage)},
+					{"ukc_stages", ukcStageNames(relation.ConfirmedStages)},
+					{"count", strconv.Itoa(relation.Count)},
+					{"signature_id", strconv.FormatUint(uint64(relation.SignatureId), 10)},
+					{"timestamp", strconv.FormatInt(relation.Timestamp, 10)},
+					{"time", formatTimestamp(relation.Timestamp)},
+					{"severity", formatFloat(relation.Severity)},
+					{"confidence", formatFloat(relation.Confidence)},
+					{"relevance", formatFloat(relation.ComputedHostRelevance)},
+					{"cause", relation.Cause},
+					{"labels", strings.Join(relation.Labels, ",")},
+				},
+			})
+		}
+
+		text, err := xml.MarshalIndent(element, "  ", "  ")
+		if err != nil {
+			return totalSize, err
+		}
+
+		size, err := writer.Write(append(text, '\n'))
+		totalSize += size
+		if err != nil {
+			return totalSize, err
+		}
+	}
+
+	size, err := io.WriteString(writer, "</graphml>\n")
+	return totalSize + size, err
+}
+
+// quoteDot quotes an identifier or attribute value of the DOT language
+func quoteDot(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+	return `"` + replacer.Replace(value) + `"`
+}
+
+func dotAttributes(attributes [][2]string) string {
+	pairs := []string{}
+	for _, attribute := range attributes {
+		pairs = append(pairs, attribute[0]+"="+quoteDot(attribute[1]))
+	}
+
+	return "[" + strings.Join(pairs, ", ") + "]"
+}
+
+// writeDot writes a digraph per graph, Graphviz renders all graphs of a file
+func writeDot[T structure.Stage](writer io.Writer, graphs []ExportedGraph[T]) (int, error) {
+	totalSize := 0
+
+	for _, graph := range graphs {
+		builder := strings.Builder{}
+		fmt.Fprintf(&builder, "digraph %s {\n", quoteDot(fmt.Sprintf("graph-%d", graph.ID)))
+		fmt.Fprintf(&builder, "  graph %s;\n", dotAttributes([][2]string{
+			{"label", fmt.Sprintf("Graph %d", graph.ID)},
+			{"relevance", formatFloat(graph.Graph.ComputedRelevance)},
+		}))
+
+		for _, node := range graphNodes(graph.Graph) {
+			// colors like the web UI
+			color := "red"
+			if node.isInternal {
+				color = "blue"
+			}
+
+			fmt.Fprintf(&builder, "  %s %s;\n", quoteDot(node.address), dotAttributes([][2]string{
+				{"label", node.address},
+				{"color", color},
+				{"internal", strconv.FormatBool(node.isInternal)},
+				{"zone", node.zone},
+				{"risk_level", formatFloat(float32(node.riskLevel))},
+			}))
+		}
+
+		for _, relation := range graph.Graph.PreComputedDirectedRelations {
+			fmt.Fprintf(&builder, "  %s -> %s %s;\n", quoteDot(relation.From), quoteDot(relation.To), dotAttributes([][2]string{
+				{"id", relation.ID},
+				{"label", ukcStageNames(relation.ConfirmedStages)},
+				{"stage", fmt.Sprint(relation.MetaStage)},
+				{"count", strconv.Itoa(relation.Count)},
+				{"signature_id", strconv.FormatUint(uint64(relation.SignatureId), 10)},
+				{"timestamp", strconv.FormatInt(relation.Timestamp, 10)},
+				{"time", formatTimestamp(relation.Timestamp)},
+				{"severity", formatFloat(relation.Severity)},
+				{"confidence", formatFloat(relation.Confidence)},
+				{"relevance", formatFloat(relation.ComputedHostRelevance)},
+				{"cause", relation.Cause},
+				{"labels", strings.Join(relation.Labels, ",")},
+			}))
+		}
+
+		builder.WriteString("}\n")
+
+		size, err := io.WriteString(writer, builder.String())
+		totalSize += size
+		if err != nil {
+			return totalSize, err
+		}
+	}
+
+	return totalSize, nil
+}
+
+type cytoscapeElement struct {
+	Data map[string]any `json:"data"`
+}
+
+type cytoscapeElements struct {
+	Nodes []cytoscapeElement `json:"nodes"`
+	Edges []cytoscapeElement `json:"edges"`
+}
+
+// cytoscapeDocument is the JSON format of Cytoscape and Cytoscape.js
+type cytoscapeDocument struct {
+	FormatVersion string            `json:"format_version"`
+	GeneratedBy   string            `json:"generated_by"`
+	Data          map[string]any    `json:"data"`
+	Elements      cytoscapeElements `json:"elements"`
+}
+
+func newCytoscapeDocument[T structure.Stage](graph ExportedGraph[T]) cytoscapeDocument {
+	document := cytoscapeDocument{
+		FormatVersion: "1.0",
+		GeneratedBy:   "rtkcsm",
+		Data: map[string]any{
+			"id":        strconv.Itoa(int(graph.ID)),
+			"name":      fmt.Sprintf("Graph %d", graph.ID),
+			"relevance": graph.Graph.ComputedRelevance,
+		},
+		Elements: cytoscapeElements{
+			Nodes: []cytoscapeElement{},
+			Edges: []cytoscapeElement{},
+		},
+	}
+
+	for _, node := range graphNodes(graph.Graph) {
+		document.Elements.Nodes = append(document.Elements.Nodes, cytoscapeElement{
+			Data: map[string]any{
+				"id":         node.address,
+				"name":       node.address,
+				"internal":   node.isInternal,
+				"zone":       node.zone,
+				"risk_level": node.riskLevel,
+			},
+		})
+	}
+
+	for _, relation := range graph.Graph.PreComputedDirectedRelations {
+		document.Elements.Edges = append(document.Elements.Edges, cytoscapeElement{
+			Data: map[string]any{
+				"id":           relation.ID,
+				"source":       relation.From,
+				"target":       relation.To,
+				"stage":        fmt.Sprint(relation.MetaStage),
+				"ukc_stages":   relation.ConfirmedStages,
+				"count":        relation.Count,
+				"signature_id": relation.SignatureId,
+				"timestamp":    relation.Timestamp,
+				"time":         formatTimestamp(relation.Timestamp),
+				"severity":     relation.Severity,
+				"confidence":   relation.Confidence,
+				"relevance":    relation.ComputedHostRelevance,
+				"cause":        relation.Cause,
+				"labels":       relation.Labels,
+			},
+		})
+	}
+
+	return document
+}
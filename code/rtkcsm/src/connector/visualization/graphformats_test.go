@@ -0,0 +1,174 @@
+package visualization
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var profilerOptions = structure.NewProfilerOptions()
+
+// newTestRTKCSM creates an RTKCSM with a single worker, so alerts are correlated in the order they are added
+func newTestRTKCSM() *behaviour.RTKCSMImplementation[structure.SimplifiedUKCStage, structure.UKCStage] {
+	return behaviour.NewIncrementalRTKCSM(1, structure.NewSimplifiedUKCStageMapper(), structure.NewUKCStateMachine[structure.SimplifiedUKCStage](), &profilerOptions)
+}
+
+// newTestAlert creates an alert with full confidence between two hosts
+func newTestAlert(timestamp time.Time, source string, destination string, severity float32) structure.Alert {
+	return structure.Alert{
+		Timestamp:     timestamp,
+		SourceIP:      structure.ParseIPAddress(source),
+		DestinationIP: structure.ParseIPAddress(destination),
+		Severity:      severity,
+		Confidence:    1,
+	}
+}
+
+func TestGraphFormats(t *testing.T) {
+	rtkcsm := newTestRTKCSM()
+
+	seconds := time.Now().Unix()
+	alerts := structure.Alerts{
+		structure.Alert{
+			Timestamp:     time.Unix(seconds-3, 0),
+			SourceIP:      structure.ParseIPAddress("94.141.120.36"),
+			DestinationIP: structure.ParseIPAddress("172.16.42.42"),
+			Severity:      1,
+			Confidence:    1,
+			SignatureId:   2010935,
+			Cause:         `ET SCAN "Suspicious" <inbound> \\ scan`,
+		},
+		newTestAlert(time.Unix(seconds-2, 0), "172.16.42.42", "218.92.0.27", 1),
+		newTestAlert(time.Unix(seconds-1, 0), "94.141.120.37", "172.16.42.43", 0.5),
+	}
+
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+
+	export := func(format structure.ExportFormat) []byte {
+		buffer := bytes.Buffer{}
+		size, err := ExportGraphs(&buffer, rtkcsm, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != buffer.Len() {
+			t.Errorf("%s: reported %d bytes instead of %d", format, size, buffer.Len())
+		}
+		return buffer.Bytes()
+	}
+
+	var graphML struct {
+		Keys []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"key"`
+		Graphs []struct {
+			Nodes []struct {
+				ID   string `xml:"id,attr"`
+				Data []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+				Data   []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(export(structure.ExportFormatGraphML), &graphML); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(graphML.Graphs) != 2 || len(graphML.Graphs[0].Edges) != 2 || len(graphML.Graphs[0].Nodes) != 3 || len(graphML.Graphs[1].Edges) != 1 {
+		t.Fatalf("unexpected GraphML graphs %+v", graphML.Graphs)
+	}
+
+	nodeIds := map[string]bool{}
+	for _, graph := range graphML.Graphs {
+		for _, node := range graph.Nodes {
+			if nodeIds[node.ID] {
+				t.Errorf("GraphML node id %s is not unique", node.ID)
+			}
+			nodeIds[node.ID] = true
+		}
+
+		for _, edge := range graph.Edges {
+			if !nodeIds[edge.Source] || !nodeIds[edge.Target] {
+				t.Errorf("GraphML edge %s -> %s references unknown nodes", edge.Source, edge.Target)
+			}
+		}
+	}
+
+	edgeData := map[string]string{}
+	for _, data := range graphML.Graphs[0].Edges[0].Data {
+		edgeData[data.Key] = data.Value
+	}
+	if edgeData["cause"] != alerts[0].Cause || edgeData["signature_id"] != "2010935" || edgeData["stage"] != "incoming" ||
+		edgeData["ukc_stages"] != "Reconnaissance,Delivery Phase 1" || edgeData["count"] != "1" || edgeData["timestamp"] != strconv.FormatInt(alerts[0].Timestamp.UnixMilli(), 10) {
+		t.Errorf("unexpected GraphML edge data %v", edgeData)
+	}
+
+	dot := string(export(structure.ExportFormatDot))
+	if strings.Count(dot, "digraph ") != 2 || strings.Count(dot, " -> ") != 3 {
+		t.Errorf("unexpected DOT graphs:\n%s", dot)
+	}
+	if !strings.Contains(dot, `cause="ET SCAN \"Suspicious\" <inbound> \\\\ scan"`) {
+		t.Errorf("DOT cause is not escaped:\n%s", dot)
+	}
+	if !strings.Contains(dot, `"172.16.42.42" [label="172.16.42.42", color="blue", internal="true"`) {
+		t.Errorf("DOT node is not annotated:\n%s", dot)
+	}
+
+	var cytoscape []struct {
+		Data     map[string]any `json:"data"`
+		Elements struct {
+			Nodes []struct {
+				Data map[string]any `json:"data"`
+			} `json:"nodes"`
+			Edges []struct {
+				Data map[string]any `json:"data"`
+			} `json:"edges"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(export(structure.ExportFormatCytoscape), &cytoscape); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cytoscape) != 2 || len(cytoscape[0].Elements.Nodes) != 3 || len(cytoscape[0].Elements.Edges) != 2 {
+		t.Fatalf("unexpected Cytoscape graphs %+v", cytoscape)
+	}
+
+	node := cytoscape[0].Elements.Nodes[0].Data
+	if node["id"] != "94.141.120.36" || node["internal"] != false || node["risk_level"] != float64(structure.MediumRisk) {
+		t.Errorf("unexpected Cytoscape node %v", node)
+	}
+
+	edge := cytoscape[0].Elements.Edges[1].Data
+	if edge["source"] != "172.16.42.42" || edge["target"] != "218.92.0.27" || edge["stage"] != "outgoing" {
+		t.Errorf("unexpected Cytoscape edge %v", edge)
+	}
+
+	// a single graph is a Cytoscape document instead of an array
+	graphId := rtkcsm.GetGraphList(-1).Graphs[0].ID
+	single := bytes.Buffer{}
+	if _, err := WriteGraph(&single, structure.ExportFormatCytoscape, ExportedGraph[structure.SimplifiedUKCStage]{
+		ID:    graphId,
+		Graph: rtkcsm.GetGraph(graphId).GetPreComputed(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(single.Bytes(), []byte("{")) {
+		t.Errorf("single Cytoscape graph is not an object: %s", single.String())
+	}
+}
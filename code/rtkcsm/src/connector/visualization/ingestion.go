@@ -0,0 +1,320 @@
+package visualization
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"rtkcsm/component/behaviour"
+	"rtkcsm/component/structure"
+	"rtkcsm/connector/reader"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+const DEFAULT_INGESTION_MAX_QUEUED_ALERTS = 65536
+const DEFAULT_INGESTION_MAX_BODY_SIZE = 32 * 1024 * 1024
+
+// IngestionOptions configure POST /api/alerts
+type IngestionOptions struct {
+	DefaultReader   string               // reader of bodies without a reader query parameter or a content type of a reader
+	ReaderOptions   reader.ReaderOptions // options of the readers
+	MaxQueuedAlerts int                  // requests stop reading their body with 429 when this number of alerts waits for the correlation
+	MaxBodySize     int64                // bytes of a decompressed body
+}
+
+func NewIngestionOptions() IngestionOptions {
+	return IngestionOptions{
+		MaxQueuedAlerts: DEFAULT_INGESTION_MAX_QUEUED_ALERTS,
+		MaxBodySize:     DEFAULT_INGESTION_MAX_BODY_SIZE,
+	}
+}
+
+var errCorrelationBacklog = errors.New("too many alerts wait for the correlation")
+
+// IngestionResult counts the alerts of a request. Rejected are lines without an alert, e.g. invalid
+// lines, and alerts that could not be correlated.
+type IngestionResult struct {
+	Reader   string `json:"reader"`
+	Lines    int    `json:"lines"`
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ingestionCounter counts the lines and alerts of a request that are passed to the RTKCSM by a reader
+// and stops the body once maxQueuedAlerts wait for the correlation
+type ingestionCounter[T structure.Stage, K structure.Stage] struct {
+	behaviour.RTKCSM[T, K]
+
+	maxQueuedAlerts int
+	stop            func()
+
+	mutex       sync.Mutex
+	lines       int
+	emptyLines  int
+	alerts      int
+	errorAlerts int
+}
+
+func (c *ingestionCounter[T, K]) ObserveLine(alerts int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.lines += 1
+	if alerts == 0 {
+		c.emptyLines += 1
+	}
+}
+
+func (c *ingestionCounter[T, K]) AddAlerts(alerts <-chan structure.Alert, handleError func(alert structure.Alert, err error)) {
+	counted := make(chan structure.Alert)
+	go func() {
+		for alert := range alerts {
+			c.mutex.Lock()
+			c.alerts += 1
+			c.mutex.Unlock()
+
+			counted <- alert
+
+			// the lines that were already read are still correlated and accepted
+			if c.maxQueuedAlerts > 0 && c.RTKCSM.GetQueuedAlerts() >= c.maxQueuedAlerts {
+				c.stop()
+			}
+		}
+		close(counted)
+	}()
+
+	c.RTKCSM.AddAlerts(counted, func(alert structure.Alert, err error) {
+		c.mutex.Lock()
+		c.errorAlerts += 1
+		c.mutex.Unlock()
+
+		if handleError != nil {
+			handleError(alert, err)
+		}
+	})
+}
+
+func (c *ingestionCounter[T, K]) result(readerName string) IngestionResult {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return IngestionResult{
+		Reader:   readerName,
+		Lines:    c.lines,
+		Accepted: c.alerts - c.errorAlerts,
+		Rejected: c.emptyLines + c.errorAlerts,
+	}
+}
+
+// ingestion passes the bodies of requests to the selected reader
+type ingestion[T structure.Stage, K structure.Stage] struct {
+	rtkcsm  behaviour.RTKCSM[T, K]
+	options IngestionOptions
+}
+
+func newIngestion[T structure.Stage, K structure.Stage](rtkcsm behaviour.RTKCSM[T, K], options IngestionOptions) *ingestion[T, K] {
+	return &ingestion[T, K]{
+		rtkcsm:  rtkcsm,
+		options: options,
+	}
+}
+
+// handle accepts a single JSON alert (or an array of alerts), NDJSON or lines of other formats, each
+// optionally gzip-compressed. The reader is selected by the reader query parameter or the content type.
+func (i *ingestion[T, K]) handle(ctx *gin.Context) {
+	// alerts are correlated one after another, so a backlog of the correlation (e.g. of other requests,
+	// transports or a reorder window) would only grow with further alerts
+	if queued := i.rtkcsm.GetQueuedAlerts(); i.options.MaxQueuedAlerts > 0 && queued >= i.options.MaxQueuedAlerts {
+		ctx.Header("Retry-After", "1")
+		ctx.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("%d alerts wait for the correlation", queued)})
+		return
+	}
+
+	readerName := ctx.Query("reader")
+	if readerName == "" {
+		var err error
+		readerName, err = reader.ReaderNameOfContentType(ctx.GetHeader("Content-Type"), i.options.DefaultReader)
+		if err != nil {
+			ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	alertReader, err := reader.NewAlertReader[T, K](readerName, i.options.ReaderOptions)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	body, err := i.decompressedBody(ctx.Request)
+	if err != nil {
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+	defer body.Close()
+
+	if isSingleJSONDocument(ctx.GetHeader("Content-Type")) {
+		body, err = jsonDocumentLines(body)
+		if err != nil {
+			respondIngestionError(ctx, IngestionResult{Reader: readerName}, err)
+			return
+		}
+	}
+
+	lines := newBlankLineFilter(body)
+	counter := &ingestionCounter[T, K]{
+		RTKCSM:          i.rtkcsm,
+		maxQueuedAlerts: i.options.MaxQueuedAlerts,
+		stop:            lines.Stop,
+	}
+	err = alertReader.ChannelAlerts(counter, lines)
+
+	result := counter.result(readerName)
+	if err != nil {
+		respondIngestionError(ctx, result, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func respondIngestionError(ctx *gin.Context, result IngestionResult, err error) {
+	result.Error = err.Error()
+
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, result)
+	} else if errors.Is(err, errCorrelationBacklog) {
+		// the client resends the lines after the counted ones
+		ctx.Header("Retry-After", "1")
+		ctx.JSON(http.StatusTooManyRequests, result)
+	} else {
+		ctx.JSON(http.StatusBadRequest, result)
+	}
+}
+
+// decompressedBody limits the size of the decompressed body
+func (i *ingestion[T, K]) decompressedBody(request *http.Request) (io.ReadCloser, error) {
+	switch strings.ToLower(request.Header.Get("Content-Encoding")) {
+	case "", "identity":
+		return http.MaxBytesReader(nil, request.Body, i.options.MaxBodySize), nil
+	case "gzip", "x-gzip":
+		decompressed, err := gzip.NewReader(request.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		return http.MaxBytesReader(nil, decompressed, i.options.MaxBodySize), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", request.Header.Get("Content-Encoding"))
+	}
+}
+
+func isSingleJSONDocument(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// jsonDocumentLines converts a JSON document, which can span multiple lines, into a line per alert.
+// Bodies that are not a single document (e.g. NDJSON) are read as lines.
+func jsonDocumentLines(body io.ReadCloser) (io.ReadCloser, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var document json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&document); err != nil || decoder.More() {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	documents := []json.RawMessage{document}
+	if trimmed := bytes.TrimSpace(document); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(document, &documents); err != nil {
+			return nil, err
+		}
+	}
+
+	lines := bytes.Buffer{}
+	for _, document := range documents {
+		if err := json.Compact(&lines, document); err != nil {
+			return nil, err
+		}
+		lines.WriteByte('\n')
+	}
+
+	return io.NopCloser(&lines), nil
+}
+
+// blankLineFilter drops blank lines of a body, e.g. between NDJSON documents, which are not
+// rejected alerts. Stop ends the body after the current line.
+type blankLineFilter struct {
+	reader    *bufio.Reader
+	closer    io.Closer
+	pending   []byte
+	lineStart bool
+	err       error
+	stopped   atomic.Bool
+}
+
+func newBlankLineFilter(body io.ReadCloser) *blankLineFilter {
+	return &blankLineFilter{
+		reader:    bufio.NewReader(body),
+		closer:    body,
+		lineStart: true,
+	}
+}
+
+func (f *blankLineFilter) Read(p []byte) (int, error) {
+	for len(f.pending) == 0 && f.err == nil {
+		if f.stopped.Load() && f.lineStart {
+			// a body that ends anyway was read completely
+			if _, err := f.reader.Peek(1); err != nil {
+				f.err = err
+			} else {
+				f.err = errCorrelationBacklog
+			}
+			break
+		}
+
+		// pending refers to the buffer of the reader until it is handed out
+		line, err := f.reader.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			err = nil
+		} else if err == nil || errors.Is(err, io.EOF) {
+			if f.lineStart && len(bytes.TrimSpace(line)) == 0 {
+				line = nil
+			}
+		}
+
+		f.lineStart = len(line) == 0 || line[len(line)-1] == '\n'
+		f.pending = line
+		f.err = err
+	}
+
+	n := copy(p, f.pending)
+	f.pending = f.pending[n:]
+
+	if len(f.pending) == 0 && f.err != nil {
+		return n, f.err
+	}
+	return n, nil
+}
+
+func (f *blankLineFilter) Stop() {
+	f.stopped.Store(true)
+}
+
+func (f *blankLineFilter) Close() error {
+	return f.closer.Close()
+}
@@ -0,0 +1,150 @@
+package visualization
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestAlertIngestion(t *testing.T) {
+	rtkcsm := newTestRTKCSM()
+
+	options := NewIngestionOptions()
+	options.DefaultReader = "suricata"
+	// more than the alerts of a single test request, so that they are never stopped by their own alerts
+	options.MaxQueuedAlerts = 4
+	options.MaxBodySize = 8192
+	server := NewServer(rtkcsm, fstest.MapFS{}, options)
+
+	suricataLine := func(seconds int, source string, destination string) string {
+		timestamp := time.Date(2024, 5, 1, 10, 0, seconds, 0, time.UTC).Format("2006-01-02T15:04:05.000000-0700")
+		return fmt.Sprintf(`{"timestamp":"%s","src_ip":"%s","dest_ip":"%s","alert":{"severity":1,"signature":"test","signature_id":1}}`, timestamp, source, destination)
+	}
+
+	gzipped := func(body string) string {
+		compressed := bytes.Buffer{}
+		writer := gzip.NewWriter(&compressed)
+		writer.Write([]byte(body))
+		writer.Close()
+		return compressed.String()
+	}
+
+	post := func(query string, contentType string, contentEncoding string, body io.Reader) (int, IngestionResult) {
+		request := httptest.NewRequest(http.MethodPost, "/api/alerts"+query, body)
+		if contentType != "" {
+			request.Header.Set("Content-Type", contentType)
+		}
+		if contentEncoding != "" {
+			request.Header.Set("Content-Encoding", contentEncoding)
+		}
+
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+
+		var result IngestionResult
+		json.Unmarshal(recorder.Body.Bytes(), &result)
+		return recorder.Code, result
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		contentType     string
+		contentEncoding string
+		body            string
+		status          int
+		expected        IngestionResult
+	}{
+		{
+			"single json alert", "", "application/json", "",
+			strings.ReplaceAll(suricataLine(0, "94.141.120.36", "172.16.42.42"), ",", ",\n  "),
+			http.StatusOK, IngestionResult{Reader: "suricata", Lines: 1, Accepted: 1},
+		},
+		{
+			"json array", "", "application/json; charset=utf-8", "",
+			fmt.Sprintf("[%s,\n%s]", suricataLine(1, "172.16.42.42", "172.16.42.1"), suricataLine(2, "172.16.42.1", "10.12.2.93")),
+			http.StatusOK, IngestionResult{Reader: "suricata", Lines: 2, Accepted: 2},
+		},
+		{
+			// an invalid line, an event without alert and an alert without destination are rejected, blank lines are skipped
+			"ndjson batch", "", "application/x-ndjson", "",
+			strings.Join([]string{suricataLine(3, "94.141.120.36", "172.16.42.42"), "not json", `{"event_type":"flow"}`, "", " \r", suricataLine(4, "172.16.42.42", ""), suricataLine(5, "172.16.42.42", "218.92.0.27"), ""}, "\n"),
+			http.StatusOK, IngestionResult{Reader: "suricata", Lines: 5, Accepted: 2, Rejected: 3},
+		},
+		{
+			"gzip with reader query parameter", "?reader=zeek", "application/x-ndjson", "gzip",
+			gzipped(`{"ts":1714557606.5,"src":"94.141.120.36","dst":"172.16.42.42","note":"Scan::Port_Scan","msg":"scan"}` + "\n"),
+			http.StatusOK, IngestionResult{Reader: "zeek", Lines: 1, Accepted: 1},
+		},
+		{
+			"reader content type", "", "text/x-cef", "",
+			"CEF:0|Vendor|Firewall|1.0|100|scan|5|src=94.141.120.36 dst=172.16.42.42 rt=1714557607000",
+			http.StatusOK, IngestionResult{Reader: "cef", Lines: 1, Accepted: 1},
+		},
+		{"unknown reader", "?reader=unknown", "", "", "", http.StatusBadRequest, IngestionResult{}},
+		{"unsupported content type", "", "application/xml", "", "<alert/>", http.StatusUnsupportedMediaType, IngestionResult{}},
+		{"unsupported content encoding", "", "application/x-ndjson", "br", "", http.StatusUnsupportedMediaType, IngestionResult{}},
+		{"invalid gzip", "", "application/x-ndjson", "gzip", "not gzip", http.StatusUnsupportedMediaType, IngestionResult{}},
+		{
+			"too large body", "", "application/x-ndjson", "gzip", gzipped(strings.Repeat("not json\n", 1000)),
+			http.StatusRequestEntityTooLarge, IngestionResult{},
+		},
+	}
+
+	for _, test := range tests {
+		status, result := post(test.query, test.contentType, test.contentEncoding, strings.NewReader(test.body))
+		if status != test.status {
+			t.Errorf("%s: expected status %d instead of %d (%+v)", test.name, test.status, status, result)
+			continue
+		}
+
+		result.Error = ""
+		if status == http.StatusOK && result != test.expected {
+			t.Errorf("%s: expected %+v instead of %+v", test.name, test.expected, result)
+		}
+	}
+
+	if rtkcsm.GetGraphList(-1).Count == 0 {
+		t.Errorf("ingested alerts were not correlated")
+	}
+
+	// alerts waiting in the reorder buffer saturate the correlation
+	rtkcsm.SetReorderWindow(time.Hour)
+	body := ""
+	for i := range 4 {
+		body += suricataLine(9+i, "94.141.120.36", fmt.Sprintf("172.16.42.%d", 42+i)) + "\n"
+	}
+	if status, _ := post("", "application/x-ndjson", "", strings.NewReader(body)); status != http.StatusOK {
+		t.Fatalf("request before the saturation failed with status %d", status)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/api/alerts", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") == "" {
+		t.Errorf("expected status 429 with Retry-After instead of %d", recorder.Code)
+	}
+
+	rtkcsm.FlushReorderBuffer()
+	if status, _ := post("", "application/x-ndjson", "", strings.NewReader("")); status != http.StatusOK {
+		t.Errorf("request after the correlation of the buffered alerts failed with status %d", status)
+	}
+
+	// a request stops reading its body once the correlation is saturated, the lines read so far are accepted
+	body = ""
+	for i := range 40 {
+		body += suricataLine(i, "94.141.120.36", "172.16.42.42") + "\n"
+	}
+	status, result := post("", "application/x-ndjson", "", strings.NewReader(body))
+	if status != http.StatusTooManyRequests || result.Accepted < 4 || result.Accepted >= 40 || result.Accepted != result.Lines {
+		t.Errorf("expected status 429 with the accepted alerts of the read lines instead of %d (%+v)", status, result)
+	}
+}
@@ -1,10 +1,15 @@
 package visualization
 
 import (
+	"fmt"
 	"rtkcsm/component/structure"
+	"slices"
+	"strconv"
 	"time"
 )
 
+const OCSF_VERSION = "1.3.0"
+
 type OCSFSeverity int
 
 const OCSFSeverityUnknown OCSFSeverity = 0
@@ -18,8 +23,8 @@ const OCSFSeverityFatal OCSFSeverity = 6
 
 type OCSFType int
 
-const OCSFTypeIncidentFindingCreate OCSFType = 200501
 const OCSFTypeDetectionFindingCreate OCSFType = 200401
+const OCSFTypeIncidentFindingCreate OCSFType = 200501
 
 type OCSFClass int
 
@@ -28,6 +33,8 @@ const OCSFClassIncidentFinding OCSFClass = 2005
 type OCSFActivity int
 
 const OCSFActivityCreate OCSFActivity = 1
+const OCSFActivityUpdate OCSFActivity = 2
+const OCSFActivityClose OCSFActivity = 3
 
 type OCSFCategory int
 
@@ -36,34 +43,51 @@ const OCSFCategoryFindings OCSFCategory = 2
 type OCSFStatus int
 
 const OCSFStatusNew OCSFStatus = 1
+const OCSFStatusInProgress OCSFStatus = 2
+const OCSFStatusClosed OCSFStatus = 5
 
 type OCSFBase struct {
-	Type      OCSFType     `json:"type_uid"`
-	Class     OCSFClass    `json:"class_uid"`
-	Activity  OCSFActivity `json:"activity_id"`
-	Category  OCSFCategory `json:"category_uid"`
-	Timestamp int          `json:"time"`
-	Metadata  OCSFMetadata `json:"metadata"`
-	Severity  OCSFSeverity `json:"severity_id"`
-	Status    OCSFStatus   `json:"status_id"`
+	Type        OCSFType         `json:"type_uid"`
+	Class       OCSFClass        `json:"class_uid"`
+	Activity    OCSFActivity     `json:"activity_id"`
+	Category    OCSFCategory     `json:"category_uid"`
+	Timestamp   int              `json:"time"`
+	Metadata    OCSFMetadata     `json:"metadata"`
+	Severity    OCSFSeverity     `json:"severity_id"`
+	Status      OCSFStatus       `json:"status_id"`
+	Observables []OCSFObservable `json:"observables,omitempty"`
+	Enrichments []OCSFEnrichment `json:"enrichments,omitempty"`
 }
 
 type OCSFMetadata struct {
-	Version string      `json:"version"`
-	Product OCSFProduct `json:"product"`
+	Version        string      `json:"version"`
+	Product        OCSFProduct `json:"product"`
+	CorrelationUID string      `json:"correlation_uid,omitempty"`
+	Labels         []string    `json:"labels,omitempty"`
 }
 
 type OCSFProduct struct {
-	Name string `json:"name"`
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name,omitempty"`
 }
 
 type OCSFKillChainPhaseType int
 
 const OCSFKillChainPhaseReconnaissance OCSFKillChainPhaseType = 1
 const OCSFKillChainPhaseDelivery OCSFKillChainPhaseType = 3
+const OCSFKillChainPhaseExploitation OCSFKillChainPhaseType = 4
+const OCSFKillChainPhaseInstallation OCSFKillChainPhaseType = 5
 const OCSFKillChainPhaseCommandAndControl OCSFKillChainPhaseType = 6
 const OCSFKillChainPhaseActionsOnObjectives OCSFKillChainPhaseType = 7
-const OCSFKillChainPhaseOther OCSFKillChainPhaseType = 99
+
+var ocsfKillChainPhaseNames = map[OCSFKillChainPhaseType]string{
+	OCSFKillChainPhaseReconnaissance:      "Reconnaissance",
+	OCSFKillChainPhaseDelivery:            "Delivery",
+	OCSFKillChainPhaseExploitation:        "Exploitation",
+	OCSFKillChainPhaseInstallation:        "Installation",
+	OCSFKillChainPhaseCommandAndControl:   "Command & Control",
+	OCSFKillChainPhaseActionsOnObjectives: "Actions on Objectives",
+}
 
 type OCSFKillChainPhase struct {
 	Type OCSFKillChainPhaseType `json:"phase_id"`
@@ -80,97 +104,221 @@ type OCSFObservable struct {
 	Value string             `json:"value"`
 }
 
+// OCSFDeviceEnrichment is the knowledge of RT-KCSM about a host of the graph
+type OCSFDeviceEnrichment struct {
+	IsInternal bool                `json:"is_internal"`
+	Zone       string              `json:"zone,omitempty"`
+	RiskLevel  structure.RiskLevel `json:"risk_level"`
+}
+
+type OCSFEnrichment struct {
+	Name     string               `json:"name"`
+	Value    string               `json:"value"`
+	Type     string               `json:"type"`
+	Provider string               `json:"provider"`
+	Data     OCSFDeviceEnrichment `json:"data"`
+}
+
+type OCSFUser struct {
+	Name string `json:"name"`
+}
+
 type OCSFRelatedEvent struct {
 	ID                 string               `json:"uid"`
 	Type               OCSFType             `json:"type_uid"`
+	Title              string               `json:"title,omitempty"`
 	KillChainPhases    []OCSFKillChainPhase `json:"kill_chain"`
-	FirstSeenTimestamp int                  `json:"fist_seen_time"`
+	FirstSeenTimestamp int                  `json:"first_seen_time"`
 	Count              int                  `json:"count"`
 	Description        string               `json:"desc"`
+	Severity           OCSFSeverity         `json:"severity_id"`
 	Observables        []OCSFObservable     `json:"observables"`
 }
 
 type OCSFFindingInformation struct {
-	ID            string             `json:"uid"`
-	RelatedEvents []OCSFRelatedEvent `json:"related_events"`
+	ID                 string             `json:"uid"`
+	Title              string             `json:"title"`
+	Description        string             `json:"desc"`
+	Types              []string           `json:"types"`
+	FirstSeenTimestamp int                `json:"first_seen_time"`
+	LastSeenTimestamp  int                `json:"last_seen_time"`
+	RelatedEvents      []OCSFRelatedEvent `json:"related_events"`
 }
 
 type OCSFIncidentFinding struct {
 	OCSFBase
+	Description         string                   `json:"desc"`
+	StartTimestamp      int                      `json:"start_time"`
+	EndTimestamp        int                      `json:"end_time"`
+	Assignee            *OCSFUser                `json:"assignee,omitempty"`
 	FindingInformations []OCSFFindingInformation `json:"finding_info_list"`
 }
 
+// the unified kill chain is mapped to the phases of the Lockheed Martin kill chain that OCSF uses,
+// all stages after the initial foothold are actions on objectives
 var ukcStagesToOCSFKillChainPhases = map[structure.UKCStage]OCSFKillChainPhaseType{
-	structure.C2: OCSFKillChainPhaseCommandAndControl,
 	structure.R:  OCSFKillChainPhaseReconnaissance,
+	structure.D1: OCSFKillChainPhaseDelivery,
+	structure.D2: OCSFKillChainPhaseExploitation,
+	structure.X:  OCSFKillChainPhaseInstallation,
+	structure.C2: OCSFKillChainPhaseCommandAndControl,
+	structure.L:  OCSFKillChainPhaseActionsOnObjectives,
+	structure.S:  OCSFKillChainPhaseActionsOnObjectives,
+	structure.P:  OCSFKillChainPhaseActionsOnObjectives,
+	structure.E:  OCSFKillChainPhaseActionsOnObjectives,
 	structure.O:  OCSFKillChainPhaseActionsOnObjectives,
 }
 
-func FromGraphToOCSFIncidentFinding[T structure.Stage](graph *structure.PreComputedGraph[T]) OCSFIncidentFinding {
-	var severity OCSFSeverity
-	severityLevel := int(graph.ComputedRelevance * 6)
-	switch severityLevel {
-	case 1:
-		severity = OCSFSeverityInformational
-	case 2:
-		severity = OCSFSeverityLow
-	case 3:
-		severity = OCSFSeverityMedium
-	case 4:
-		severity = OCSFSeverityHigh
-	case 5:
-		severity = OCSFSeverityCritical
+var incidentStatusesToOCSFStatuses = map[structure.IncidentStatus]OCSFStatus{
+	structure.IncidentStatusOpen:         OCSFStatusNew,
+	structure.IncidentStatusAcknowledged: OCSFStatusInProgress,
+	structure.IncidentStatusClosed:       OCSFStatusClosed,
+}
+
+// ToOCSFSeverity divides relevances and severities between zero and one into five equally sized
+// buckets from informational to critical
+func ToOCSFSeverity(value float32) OCSFSeverity {
+	switch {
+	case value < 0.2:
+		return OCSFSeverityInformational
+	case value < 0.4:
+		return OCSFSeverityLow
+	case value < 0.6:
+		return OCSFSeverityMedium
+	case value < 0.8:
+		return OCSFSeverityHigh
 	default:
-		if severity <= 0 {
-			severity = OCSFSeverityUnknown
-		} else {
-			severity = OCSFSeverityFatal
-		}
+		return OCSFSeverityCritical
 	}
+}
 
-	findings := []OCSFFindingInformation{}
+func ocsfType(class OCSFClass, activity OCSFActivity) OCSFType {
+	return OCSFType(int(class)*100 + int(activity))
+}
 
-	for _, relation := range graph.PreComputedDirectedRelations {
-		killChainPhases := []OCSFKillChainPhase{}
+// SetActivity changes the activity and type of a finding, e.g. for consumers that see a graph for
+// the first time after it grew
+func (f *OCSFIncidentFinding) SetActivity(activity OCSFActivity) {
+	f.Activity = activity
+	f.Type = ocsfType(f.Class, activity)
+}
+
+// ocsfHosts adds an observable and a device enrichment for every host that was not added yet
+type ocsfHosts struct {
+	known       map[string]bool
+	observables []OCSFObservable
+	enrichments []OCSFEnrichment
+}
+
+func (h *ocsfHosts) add(address string, isInternal bool, zone string, riskLevel structure.RiskLevel) {
+	address = formatAddress(address)
+	if h.known[address] {
+		return
+	}
+	h.known[address] = true
+
+	h.observables = append(h.observables, OCSFObservable{
+		Name:  "ip",
+		Type:  OCSFObservableTypeIPAddress,
+		Value: address,
+	})
+	h.enrichments = append(h.enrichments, OCSFEnrichment{
+		Name:     "ip",
+		Value:    address,
+		Type:     "device",
+		Provider: "RT-KCSM",
+		Data: OCSFDeviceEnrichment{
+			IsInternal: isInternal,
+			Zone:       zone,
+			RiskLevel:  riskLevel,
+		},
+	})
+}
+
+// FromGraphToOCSFIncidentFinding reports the current state of a graph with the activity Create, the
+// status of the incident shows whether it was closed. Consumers that report the changes of a graph
+// (e.g. the incident stream) set the activity of a change with SetActivity.
+func FromGraphToOCSFIncidentFinding[T structure.Stage](id structure.GraphID, graph *structure.PreComputedGraph[T], incident structure.Incident) OCSFIncidentFinding {
+	relatedEvents := []OCSFRelatedEvent{}
+	stages := []structure.UKCStage{}
+	hosts := ocsfHosts{
+		known: map[string]bool{},
+	}
 
+	var firstSeen, lastSeen int64
+	for i, relation := range graph.PreComputedDirectedRelations {
+		if i == 0 || relation.Timestamp < firstSeen {
+			firstSeen = relation.Timestamp
+		}
+		lastSeen = max(lastSeen, relation.Timestamp)
+
+		killChainPhases := []OCSFKillChainPhase{}
 		for _, stage := range relation.ConfirmedStages {
-			killChainPhaseType, ok := ukcStagesToOCSFKillChainPhases[stage]
-			if !ok {
-				killChainPhaseType = OCSFKillChainPhaseOther
+			killChainPhaseType := ukcStagesToOCSFKillChainPhases[stage]
+			if !slices.ContainsFunc(killChainPhases, func(phase OCSFKillChainPhase) bool { return phase.Type == killChainPhaseType }) {
+				killChainPhases = append(killChainPhases, OCSFKillChainPhase{
+					Type: killChainPhaseType,
+					Name: ocsfKillChainPhaseNames[killChainPhaseType],
+				})
+			}
+
+			if !slices.Contains(stages, stage) {
+				stages = append(stages, stage)
 			}
-			killChainPhases = append(killChainPhases, OCSFKillChainPhase{
-				Type: killChainPhaseType,
-				Name: stage.String(),
-			})
 		}
 
-		findings = append(findings, OCSFFindingInformation{
-			ID: relation.ID,
-			RelatedEvents: []OCSFRelatedEvent{
+		hosts.add(relation.From, relation.FromIsInternal, relation.FromZone, relation.FromRiskLevel)
+		hosts.add(relation.To, relation.ToIsInternal, relation.ToZone, relation.ToRiskLevel)
+
+		title := relation.Cause
+		if relation.SignatureId != 0 {
+			title = fmt.Sprintf("%s (signature %d)", relation.Cause, relation.SignatureId)
+		}
+
+		relatedEvents = append(relatedEvents, OCSFRelatedEvent{
+			ID:                 relation.ID,
+			Type:               OCSFTypeDetectionFindingCreate,
+			Title:              title,
+			KillChainPhases:    killChainPhases,
+			FirstSeenTimestamp: int(relation.Timestamp),
+			Count:              relation.Count,
+			Description:        relation.Cause,
+			Severity:           ToOCSFSeverity(relation.Severity),
+			Observables: []OCSFObservable{
 				{
-					ID:                 relation.ID,
-					Type:               OCSFTypeDetectionFindingCreate,
-					KillChainPhases:    killChainPhases,
-					FirstSeenTimestamp: int(relation.Timestamp),
-					Count:              relation.Count,
-					Description:        relation.Cause,
-					Observables: []OCSFObservable{
-						{
-							Name:  "source",
-							Type:  OCSFObservableTypeIPAddress,
-							Value: relation.From,
-						},
-						{
-							Name:  "destination",
-							Type:  OCSFObservableTypeIPAddress,
-							Value: relation.To,
-						},
-					},
+					Name:  "source",
+					Type:  OCSFObservableTypeIPAddress,
+					Value: formatAddress(relation.From),
+				},
+				{
+					Name:  "destination",
+					Type:  OCSFObservableTypeIPAddress,
+					Value: formatAddress(relation.To),
 				},
 			},
 		})
 	}
 
+	slices.Sort(stages)
+	types := []string{}
+	for _, stage := range stages {
+		types = append(types, stage.String())
+	}
+
+	description := fmt.Sprintf("Attack graph with %d relations and a relevance of %s", len(relatedEvents), formatFloat(graph.ComputedRelevance))
+
+	var assignee *OCSFUser
+	if incident.Assignee != "" {
+		assignee = &OCSFUser{
+			Name: incident.Assignee,
+		}
+	}
+
+	status, ok := incidentStatusesToOCSFStatuses[incident.Status]
+	if !ok {
+		status = OCSFStatusNew
+	}
+
 	return OCSFIncidentFinding{
 		OCSFBase: OCSFBase{
 			Type:      OCSFTypeIncidentFindingCreate,
@@ -179,14 +327,58 @@ func FromGraphToOCSFIncidentFinding[T structure.Stage](graph *structure.PreCompu
 			Category:  OCSFCategoryFindings,
 			Timestamp: int(time.Now().UnixMilli()),
 			Metadata: OCSFMetadata{
-				Version: "1.0",
+				Version: OCSF_VERSION,
 				Product: OCSFProduct{
-					Name: "RT-KCSM",
+					Name:       "RT-KCSM",
+					VendorName: "RT-KCSM",
 				},
+				// updates of a graph are correlated by the graph id
+				CorrelationUID: strconv.Itoa(int(id)),
+				Labels:         incident.Tags,
 			},
-			Severity: severity,
-			Status:   OCSFStatusNew,
+			Severity:    ToOCSFSeverity(graph.ComputedRelevance),
+			Status:      status,
+			Observables: hosts.observables,
+			Enrichments: hosts.enrichments,
 		},
-		FindingInformations: findings,
+		Description:    description,
+		StartTimestamp: int(firstSeen),
+		EndTimestamp:   int(lastSeen),
+		Assignee:       assignee,
+		FindingInformations: []OCSFFindingInformation{
+			{
+				ID:                 strconv.Itoa(int(id)),
+				Title:              fmt.Sprintf("RT-KCSM graph %d", id),
+				Description:        description,
+				Types:              types,
+				FirstSeenTimestamp: int(firstSeen),
+				LastSeenTimestamp:  int(lastSeen),
+				RelatedEvents:      relatedEvents,
+			},
+		},
+	}
+}
+
+// FromMergedGraphToOCSFIncidentFinding closes the finding of a graph that was merged into another
+// graph. It describes the surviving graph, which continues the incident, and references it with
+// its finding information after the one of the merged graph.
+func FromMergedGraphToOCSFIncidentFinding[T structure.Stage](id structure.GraphID, into structure.GraphID, graph *structure.PreComputedGraph[T], incident structure.Incident) OCSFIncidentFinding {
+	finding := FromGraphToOCSFIncidentFinding(into, graph, incident)
+	finding.SetActivity(OCSFActivityClose)
+	finding.Status = OCSFStatusClosed
+	finding.Metadata.CorrelationUID = strconv.Itoa(int(id))
+	finding.Description = fmt.Sprintf("RT-KCSM graph %d was merged into graph %d", id, into)
+
+	merged := OCSFFindingInformation{
+		ID:                 strconv.Itoa(int(id)),
+		Title:              fmt.Sprintf("RT-KCSM graph %d", id),
+		Description:        finding.Description,
+		Types:              []string{},
+		FirstSeenTimestamp: finding.StartTimestamp,
+		LastSeenTimestamp:  finding.EndTimestamp,
+		RelatedEvents:      []OCSFRelatedEvent{},
 	}
+	finding.FindingInformations = append([]OCSFFindingInformation{merged}, finding.FindingInformations...)
+
+	return finding
 }
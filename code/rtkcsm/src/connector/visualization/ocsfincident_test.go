@@ -0,0 +1,256 @@
+package visualization
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"rtkcsm/component/structure"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ocsfSchemaAttribute struct {
+	Type        string `json:"type"`
+	Requirement string `json:"requirement"`
+	ObjectType  string `json:"object_type"`
+	IsArray     bool   `json:"is_array"`
+	Enum        map[string]struct {
+		Caption string `json:"caption"`
+	} `json:"enum"`
+}
+
+type ocsfSchemaDefinition struct {
+	Attributes map[string]ocsfSchemaAttribute `json:"attributes"`
+}
+
+// ocsfSchema is the format of schema exports of the OCSF server
+type ocsfSchema struct {
+	Version string                          `json:"version"`
+	Classes map[string]ocsfSchemaDefinition `json:"classes"`
+	Objects map[string]ocsfSchemaDefinition `json:"objects"`
+}
+
+// validate checks that all required attributes are set, that no unknown attributes are set and that
+// the values have the type of the attribute and are one of the enum values
+func (s ocsfSchema) validate(t *testing.T, path string, definition ocsfSchemaDefinition, object map[string]any) {
+	for name, attribute := range definition.Attributes {
+		if _, ok := object[name]; !ok && attribute.Requirement == "required" {
+			t.Errorf("%s: missing required attribute %s", path, name)
+		}
+	}
+
+	for name, value := range object {
+		attribute, ok := definition.Attributes[name]
+		if !ok {
+			t.Errorf("%s: unknown attribute %s", path, name)
+			continue
+		}
+
+		if !attribute.IsArray {
+			s.validateValue(t, path+"."+name, attribute, value)
+		} else if values, ok := value.([]any); ok {
+			for i, value := range values {
+				s.validateValue(t, fmt.Sprintf("%s.%s[%d]", path, name, i), attribute, value)
+			}
+		} else {
+			t.Errorf("%s.%s: %v is not an array", path, name, value)
+		}
+
+		// the caption of an enum value is set in the attribute without the _id suffix
+		if caption, ok := object[strings.TrimSuffix(name, "_id")].(string); ok && attribute.Enum != nil && name != strings.TrimSuffix(name, "_id") {
+			if expected := attribute.Enum[fmt.Sprint(value)].Caption; caption != expected {
+				t.Errorf("%s: caption %q of %s %v is not %q", path, caption, name, value, expected)
+			}
+		}
+	}
+}
+
+func (s ocsfSchema) validateValue(t *testing.T, path string, attribute ocsfSchemaAttribute, value any) {
+	switch attribute.Type {
+	case "integer_t", "long_t", "timestamp_t":
+		number, ok := value.(float64)
+		if !ok || number != float64(int64(number)) {
+			t.Errorf("%s: %v is not an integer", path, value)
+			return
+		}
+		if _, ok := attribute.Enum[strconv.FormatInt(int64(number), 10)]; attribute.Enum != nil && !ok {
+			t.Errorf("%s: %v is not an enum value", path, value)
+		}
+	case "string_t":
+		if _, ok := value.(string); !ok {
+			t.Errorf("%s: %v is not a string", path, value)
+		}
+	case "boolean_t":
+		if _, ok := value.(bool); !ok {
+			t.Errorf("%s: %v is not a boolean", path, value)
+		}
+	case "json_t":
+	case "object_t":
+		object, ok := value.(map[string]any)
+		if !ok {
+			t.Errorf("%s: %v is not an object", path, value)
+			return
+		}
+		definition, ok := s.Objects[attribute.ObjectType]
+		if !ok {
+			t.Errorf("%s: object %s is not part of the schema", path, attribute.ObjectType)
+			return
+		}
+		s.validate(t, path, definition, object)
+	default:
+		t.Errorf("%s: unknown type %s", path, attribute.Type)
+	}
+}
+
+func validateOCSFIncidentFinding(t *testing.T, schema ocsfSchema, finding OCSFIncidentFinding) map[string]any {
+	data, err := json.Marshal(finding)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal(data, &object); err != nil {
+		t.Fatal(err)
+	}
+
+	schema.validate(t, "incident_finding", schema.Classes["incident_finding"], object)
+
+	if object["type_uid"] != object["class_uid"].(float64)*100+object["activity_id"].(float64) {
+		t.Errorf("type %v does not match class %v and activity %v", object["type_uid"], object["class_uid"], object["activity_id"])
+	}
+
+	return object
+}
+
+func TestOCSFIncidentFinding(t *testing.T) {
+	data, err := os.ReadFile("testdata/ocsf/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema ocsfSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	rtkcsm := newTestRTKCSM()
+
+	seconds := time.Now().Unix()
+	alerts := structure.Alerts{
+		structure.Alert{
+			Timestamp:     time.Unix(seconds-3, 0),
+			SourceIP:      structure.ParseIPAddress("94.141.120.36"),
+			DestinationIP: structure.ParseIPAddress("172.16.42.42"),
+			Severity:      1,
+			Confidence:    1,
+			SignatureId:   2010935,
+			Cause:         "ET SCAN Suspicious inbound scan",
+		},
+		newTestAlert(time.Unix(seconds-2, 0), "172.16.42.42", "218.92.0.27", 0.7),
+		newTestAlert(time.Unix(seconds-1, 0), "94.141.120.37", "172.16.42.43", 0.5),
+	}
+
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+
+	list := rtkcsm.GetGraphList(-1)
+	if list.Count != 2 {
+		t.Fatalf("expected 2 graphs instead of %d", list.Count)
+	}
+
+	grownId := list.Graphs[0].ID
+	grown := rtkcsm.GetGraph(grownId).GetPreComputed()
+	finding := validateOCSFIncidentFinding(t, schema, FromGraphToOCSFIncidentFinding(grownId, &grown, *rtkcsm.GetIncident(grownId)))
+
+	// a single finding of a graph that grew from its first relation is not an update of a finding that was reported before
+	if finding["activity_id"] != float64(OCSFActivityCreate) || finding["status_id"] != float64(OCSFStatusNew) {
+		t.Errorf("unexpected activity %v or status %v of a grown graph", finding["activity_id"], finding["status_id"])
+	}
+	if finding["severity_id"] != float64(ToOCSFSeverity(grown.ComputedRelevance)) || finding["severity_id"] == float64(OCSFSeverityUnknown) {
+		t.Errorf("unexpected severity %v of relevance %f", finding["severity_id"], grown.ComputedRelevance)
+	}
+	if observables := finding["observables"].([]any); len(observables) != 3 {
+		t.Errorf("expected an observable per host instead of %v", observables)
+	}
+	if enrichments := finding["enrichments"].([]any); len(enrichments) != 3 || enrichments[1].(map[string]any)["data"].(map[string]any)["is_internal"] != true {
+		t.Errorf("unexpected device enrichments %v", enrichments)
+	}
+	if related := finding["finding_info_list"].([]any)[0].(map[string]any)["related_events"].([]any); len(related) != 2 {
+		t.Errorf("expected a related event per relation instead of %v", related)
+	}
+
+	newId := list.Graphs[1].ID
+	status := string(structure.IncidentStatusAcknowledged)
+	assignee := "analyst"
+	incident, err := rtkcsm.UpdateIncident(newId, structure.IncidentUpdate{Status: &status, Assignee: &assignee})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := rtkcsm.GetGraph(newId).GetPreComputed()
+	finding = validateOCSFIncidentFinding(t, schema, FromGraphToOCSFIncidentFinding(newId, &created, *incident))
+	if finding["activity_id"] != float64(OCSFActivityCreate) || finding["status_id"] != float64(OCSFStatusInProgress) {
+		t.Errorf("unexpected activity %v or status %v of an acknowledged graph", finding["activity_id"], finding["status_id"])
+	}
+
+	status = string(structure.IncidentStatusClosed)
+	incident, err = rtkcsm.UpdateIncident(newId, structure.IncidentUpdate{Status: &status})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finding = validateOCSFIncidentFinding(t, schema, FromGraphToOCSFIncidentFinding(newId, &created, *incident))
+	if finding["activity_id"] != float64(OCSFActivityCreate) || finding["status_id"] != float64(OCSFStatusClosed) {
+		t.Errorf("unexpected activity %v or status %v of a closed graph", finding["activity_id"], finding["status_id"])
+	}
+
+	// every stage of the unified kill chain has a phase of the kill chain used by OCSF
+	expectedPhases := map[structure.UKCStage]OCSFKillChainPhaseType{
+		structure.R:  OCSFKillChainPhaseReconnaissance,
+		structure.D1: OCSFKillChainPhaseDelivery,
+		structure.D2: OCSFKillChainPhaseExploitation,
+		structure.C2: OCSFKillChainPhaseCommandAndControl,
+		structure.L:  OCSFKillChainPhaseActionsOnObjectives,
+		structure.S:  OCSFKillChainPhaseActionsOnObjectives,
+		structure.P:  OCSFKillChainPhaseActionsOnObjectives,
+		structure.E:  OCSFKillChainPhaseActionsOnObjectives,
+		structure.O:  OCSFKillChainPhaseActionsOnObjectives,
+		structure.X:  OCSFKillChainPhaseInstallation,
+	}
+
+	allStages := structure.PreComputedGraph[structure.SimplifiedUKCStage]{
+		ComputedRelevance: 1,
+	}
+	for stage := structure.R; stage <= structure.X; stage++ {
+		allStages.PreComputedDirectedRelations = append(allStages.PreComputedDirectedRelations, structure.PreComputedDirectedRelation[structure.SimplifiedUKCStage]{
+			ID: strconv.Itoa(int(stage)),
+			DirectedRelationJson: structure.DirectedRelationJson[structure.SimplifiedUKCStage]{
+				From:      "172.16.42.42",
+				To:        "172.16.42.43",
+				Timestamp: seconds * 1000,
+				Severity:  1,
+				Cause:     stage.String(),
+				Count:     1,
+			},
+			ConfirmedStages: []structure.UKCStage{stage},
+			FromIsInternal:  true,
+			ToIsInternal:    true,
+		})
+	}
+
+	allStagesFinding := FromGraphToOCSFIncidentFinding(1, &allStages, structure.NewIncident(time.Now()))
+	validateOCSFIncidentFinding(t, schema, allStagesFinding)
+
+	if allStagesFinding.Severity != OCSFSeverityCritical {
+		t.Errorf("unexpected severity %d of a graph with all stages", allStagesFinding.Severity)
+	}
+
+	for i, event := range allStagesFinding.FindingInformations[0].RelatedEvents {
+		stage := allStages.PreComputedDirectedRelations[i].ConfirmedStages[0]
+		if len(event.KillChainPhases) != 1 || event.KillChainPhases[0].Type != expectedPhases[stage] {
+			t.Errorf("stage %s is mapped to %+v instead of %d", stage, event.KillChainPhases, expectedPhases[stage])
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package visualization
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"rtkcsm/component/structure"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const STIX_SPEC_VERSION = "2.1"
+const STIX_KILL_CHAIN_NAME = "unified-kill-chain"
+
+// namespace of deterministic identifiers of cyber-observable objects defined by the STIX specification
+const stixObservableNamespace = "00abedb4-aa42-466c-9c01-fed23315a9b7"
+
+// namespace of deterministic identifiers of the objects created by RT-KCSM, so that exports of the same graph
+// reference the same objects
+const stixNamespace = "9e6d4f0a-2c36-4c8e-9a43-5b8e2d1f7c60"
+
+var stixKillChainPhases = map[structure.UKCStage]string{
+	structure.R:  "reconnaissance",
+	structure.D1: "delivery-phase-1",
+	structure.D2: "delivery-phase-2",
+	structure.C2: "command-and-control",
+	structure.L:  "lateral-movement",
+	structure.S:  "discovery",
+	structure.P:  "pivoting",
+	structure.E:  "exfiltration",
+	structure.O:  "objectives",
+	structure.X:  "execution",
+}
+
+type StixKillChainPhase struct {
+	KillChainName string `json:"kill_chain_name"`
+	PhaseName     string `json:"phase_name"`
+}
+
+type StixExternalReference struct {
+	SourceName string `json:"source_name"`
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+// StixObject contains the properties of all object types that are exported, unused properties are omitted
+type StixObject struct {
+	Type               string                  `json:"type"`
+	SpecVersion        string                  `json:"spec_version"`
+	ID                 string                  `json:"id"`
+	CreatedByRef       string                  `json:"created_by_ref,omitempty"`
+	Created            string                  `json:"created,omitempty"`
+	Modified           string                  `json:"modified,omitempty"`
+	Name               string                  `json:"name,omitempty"`
+	Description        string                  `json:"description,omitempty"`
+	Value              string                  `json:"value,omitempty"`
+	IdentityClass      string                  `json:"identity_class,omitempty"`
+	IndicatorTypes     []string                `json:"indicator_types,omitempty"`
+	Pattern            string                  `json:"pattern,omitempty"`
+	PatternType        string                  `json:"pattern_type,omitempty"`
+	ValidFrom          string                  `json:"valid_from,omitempty"`
+	KillChainPhases    []StixKillChainPhase    `json:"kill_chain_phases,omitempty"`
+	ExternalReferences []StixExternalReference `json:"external_references,omitempty"`
+	SightingOfRef      string                  `json:"sighting_of_ref,omitempty"`
+	WhereSightedRefs   []string                `json:"where_sighted_refs,omitempty"`
+	FirstSeen          string                  `json:"first_seen,omitempty"`
+	LastSeen           string                  `json:"last_seen,omitempty"`
+	Count              int                     `json:"count,omitempty"`
+	RelationshipType   string                  `json:"relationship_type,omitempty"`
+	SourceRef          string                  `json:"source_ref,omitempty"`
+	TargetRef          string                  `json:"target_ref,omitempty"`
+	Context            string                  `json:"context,omitempty"`
+	ObjectRefs         []string                `json:"object_refs,omitempty"`
+	Relevance          *float32                `json:"x_rtkcsm_relevance,omitempty"`
+}
+
+type StixBundle struct {
+	Type    string       `json:"type"`
+	ID      string       `json:"id"`
+	Objects []StixObject `json:"objects"`
+}
+
+// stixIdentifier returns a version 5 UUID based identifier
+func stixIdentifier(objectType string, namespace string, name string) string {
+	namespaceBytes, _ := hex.DecodeString(strings.ReplaceAll(namespace, "-", ""))
+
+	hash := sha1.New()
+	hash.Write(namespaceBytes)
+	hash.Write([]byte(name))
+	uuid := hash.Sum(nil)[:16]
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x50
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	encoded := hex.EncodeToString(uuid)
+	return fmt.Sprintf("%s--%s-%s-%s-%s-%s", objectType, encoded[0:8], encoded[8:12], encoded[12:16], encoded[16:20], encoded[20:32])
+}
+
+func formatStixTimestamp(timestamp time.Time) string {
+	return timestamp.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func stixAddressType(address string) string {
+	if strings.Contains(address, ":") {
+		return "ipv6-addr"
+	}
+	return "ipv4-addr"
+}
+
+func stixKillChain(stages []structure.UKCStage) []StixKillChainPhase {
+	phases := []StixKillChainPhase{}
+	for _, stage := range stages {
+		phases = append(phases, StixKillChainPhase{
+			KillChainName: STIX_KILL_CHAIN_NAME,
+			PhaseName:     stixKillChainPhases[stage],
+		})
+	}
+
+	return phases
+}
+
+// stixBundleBuilder collects the objects of one or more graphs, objects shared by graphs are added once
+type stixBundleBuilder struct {
+	objects  []StixObject
+	known    map[string]bool
+	identity string
+}
+
+func newStixBundleBuilder() *stixBundleBuilder {
+	builder := &stixBundleBuilder{
+		known: map[string]bool{},
+	}
+
+	builder.identity = builder.add(StixObject{
+		Type:          "identity",
+		ID:            stixIdentifier("identity", stixNamespace, "rtkcsm"),
+		Created:       formatStixTimestamp(time.Unix(0, 0)),
+		Modified:      formatStixTimestamp(time.Unix(0, 0)),
+		Name:          "RT-KCSM",
+		IdentityClass: "system",
+	})
+
+	return builder
+}
+
+func (b *stixBundleBuilder) add(object StixObject) string {
+	if !b.known[object.ID] {
+		object.SpecVersion = STIX_SPEC_VERSION
+		b.known[object.ID] = true
+		b.objects = append(b.objects, object)
+	}
+
+	return object.ID
+}
+
+type stixSignature struct {
+	name      string
+	id        uint32
+	patterns  []string
+	stages    []structure.UKCStage
+	firstSeen time.Time
+	lastSeen  time.Time
+	count     int
+}
+
+// addStixGraph adds the observed addresses, an indicator and a sighting per signature, attack patterns for the
+// stages of the signatures and a grouping of all objects of the graph
+func addStixGraph[T structure.Stage](b *stixBundleBuilder, graph ExportedGraph[T]) {
+	relations := graph.Graph.PreComputedDirectedRelations
+	graphName := strconv.Itoa(int(graph.ID))
+	refs := []string{}
+
+	if len(relations) == 0 {
+		return
+	}
+
+	first := time.UnixMilli(relations[0].Timestamp)
+	last := first
+	signatures := map[string]*stixSignature{}
+	signatureKeys := []string{}
+
+	for _, relation := range relations {
+		timestamp := time.UnixMilli(relation.Timestamp)
+		if timestamp.Before(first) {
+			first = timestamp
+		}
+		if timestamp.After(last) {
+			last = timestamp
+		}
+
+		from := formatAddress(relation.From)
+		to := formatAddress(relation.To)
+
+		for _, address := range []string{from, to} {
+			addressType := stixAddressType(address)
+			refs = append(refs, b.add(StixObject{
+				Type:  addressType,
+				ID:    stixIdentifier(addressType, stixObservableNamespace, fmt.Sprintf(`{"value":"%s"}`, address)),
+				Value: address,
+			}))
+		}
+
+		// relations without a signature id are grouped by their cause
+		key := strconv.FormatUint(uint64(relation.SignatureId), 10)
+		if relation.SignatureId == 0 {
+			key = "cause:" + relation.Cause
+		}
+
+		signature, ok := signatures[key]
+		if !ok {
+			name := relation.Cause
+			if name == "" {
+				name = fmt.Sprintf("Signature %d", relation.SignatureId)
+			}
+
+			signature = &stixSignature{
+				name:      name,
+				id:        relation.SignatureId,
+				firstSeen: timestamp,
+				lastSeen:  timestamp,
+			}
+			signatures[key] = signature
+			signatureKeys = append(signatureKeys, key)
+		}
+
+		signature.patterns = append(signature.patterns, fmt.Sprintf("[network-traffic:src_ref.type = '%s' AND network-traffic:src_ref.value = '%s' AND network-traffic:dst_ref.type = '%s' AND network-traffic:dst_ref.value = '%s']",
+			stixAddressType(from), from, stixAddressType(to), to))
+		for _, stage := range relation.ConfirmedStages {
+			if !slices.Contains(signature.stages, stage) {
+				signature.stages = append(signature.stages, stage)
+			}
+		}
+		if timestamp.Before(signature.firstSeen) {
+			signature.firstSeen = timestamp
+		}
+		if timestamp.After(signature.lastSeen) {
+			signature.lastSeen = timestamp
+		}
+		signature.count += relation.Count
+	}
+
+	sort.Strings(signatureKeys)
+
+	for _, key := range signatureKeys {
+		signature := signatures[key]
+		slices.Sort(signature.stages)
+
+		indicator := StixObject{
+			Type:            "indicator",
+			ID:              stixIdentifier("indicator", stixNamespace, graphName+"/"+key),
+			CreatedByRef:    b.identity,
+			Created:         formatStixTimestamp(signature.firstSeen),
+			Modified:        formatStixTimestamp(signature.lastSeen),
+			Name:            signature.name,
+			IndicatorTypes:  []string{"malicious-activity"},
+			Pattern:         strings.Join(uniqueStrings(signature.patterns), " OR "),
+			PatternType:     "stix",
+			ValidFrom:       formatStixTimestamp(signature.firstSeen),
+			KillChainPhases: stixKillChain(signature.stages),
+		}
+		if signature.id != 0 {
+			indicator.ExternalReferences = []StixExternalReference{{
+				SourceName: "signature",
+				ExternalID: strconv.FormatUint(uint64(signature.id), 10),
+			}}
+		}
+		refs = append(refs, b.add(indicator))
+
+		refs = append(refs, b.add(StixObject{
+			Type:             "sighting",
+			ID:               stixIdentifier("sighting", stixNamespace, graphName+"/"+key),
+			CreatedByRef:     b.identity,
+			Created:          indicator.Created,
+			Modified:         indicator.Modified,
+			SightingOfRef:    indicator.ID,
+			WhereSightedRefs: []string{b.identity},
+			FirstSeen:        indicator.Created,
+			LastSeen:         indicator.Modified,
+			Count:            min(signature.count, 999999999),
+		}))
+
+		for _, stage := range signature.stages {
+			attackPattern := b.add(StixObject{
+				Type:            "attack-pattern",
+				ID:              stixIdentifier("attack-pattern", stixNamespace, stixKillChainPhases[stage]),
+				CreatedByRef:    b.identity,
+				Created:         formatStixTimestamp(time.Unix(0, 0)),
+				Modified:        formatStixTimestamp(time.Unix(0, 0)),
+				Name:            stage.String(),
+				KillChainPhases: stixKillChain([]structure.UKCStage{stage}),
+			})
+
+			refs = append(refs, attackPattern, b.add(StixObject{
+				Type:             "relationship",
+				ID:               stixIdentifier("relationship", stixNamespace, indicator.ID+"/"+attackPattern),
+				CreatedByRef:     b.identity,
+				Created:          indicator.Created,
+				Modified:         indicator.Modified,
+				RelationshipType: "indicates",
+				SourceRef:        indicator.ID,
+				TargetRef:        attackPattern,
+			}))
+		}
+	}
+
+	relevance := graph.Graph.ComputedRelevance
+	b.add(StixObject{
+		Type:         "grouping",
+		ID:           stixIdentifier("grouping", stixNamespace, graphName),
+		CreatedByRef: b.identity,
+		Created:      formatStixTimestamp(first),
+		Modified:     formatStixTimestamp(last),
+		Name:         fmt.Sprintf("RT-KCSM graph %d", graph.ID),
+		Description:  fmt.Sprintf("Attack graph with %d relations and a relevance of %s", len(relations), formatFloat(relevance)),
+		Context:      "suspicious-activity",
+		ObjectRefs:   uniqueStrings(refs),
+		Relevance:    &relevance,
+	})
+}
+
+func uniqueStrings(values []string) []string {
+	unique := []string{}
+	known := map[string]bool{}
+	for _, value := range values {
+		if !known[value] {
+			known[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// bundle identifiers depend on the contained objects, so that exports of the same graphs are equal
+func (b *stixBundleBuilder) bundle() StixBundle {
+	ids := []string{}
+	for _, object := range b.objects {
+		ids = append(ids, object.ID)
+	}
+
+	return StixBundle{
+		Type:    "bundle",
+		ID:      stixIdentifier("bundle", stixNamespace, strings.Join(ids, ",")),
+		Objects: b.objects,
+	}
+}
+
+// NewStixBundle converts graphs into a STIX 2.1 bundle
+func NewStixBundle[T structure.Stage](graphs []ExportedGraph[T]) StixBundle {
+	builder := newStixBundleBuilder()
+	for _, graph := range graphs {
+		addStixGraph(builder, graph)
+	}
+
+	return builder.bundle()
+}
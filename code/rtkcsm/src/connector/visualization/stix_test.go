@@ -0,0 +1,165 @@
+package visualization
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"rtkcsm/component/structure"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// stixSchemaURL is the location of the official STIX 2.1 JSON schemas, which are read from testdata
+const stixSchemaURL = "http://raw.githubusercontent.com/oasis-open/cti-stix2-json-schemas/stix2.1/schemas/"
+
+var stixSchemaDirectories = []string{"common", "sdos", "sros", "observables"}
+
+func newStixSchemaCompiler() *jsonschema.Compiler {
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		path, ok := strings.CutPrefix(url, stixSchemaURL)
+		if !ok {
+			return nil, fmt.Errorf("schema is not part of STIX 2.1: %s", url)
+		}
+		return os.Open(filepath.Join("testdata/stix/schemas", filepath.FromSlash(path)))
+	}
+	return compiler
+}
+
+// validateStixBundle validates the bundle and each of its objects against the schema of its type
+func validateStixBundle(t *testing.T, name string, data []byte) {
+	compiler := newStixSchemaCompiler()
+
+	bundleSchema, err := compiler.Compile(stixSchemaURL + "common/bundle.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle any
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if err := bundleSchema.Validate(bundle); err != nil {
+		t.Errorf("%s: %v", name, err)
+	}
+
+	// the bundle schema accepts objects of unknown types (e.g. grouping) as custom objects
+	objects, _ := bundle.(map[string]any)["objects"].([]any)
+	for i, object := range objects {
+		objectType, _ := object.(map[string]any)["type"].(string)
+
+		var schema *jsonschema.Schema
+		for _, directory := range stixSchemaDirectories {
+			path := filepath.Join("testdata/stix/schemas", directory, objectType+".json")
+			if _, err := os.Stat(path); err == nil {
+				schema, err = compiler.Compile(stixSchemaURL + directory + "/" + objectType + ".json")
+				if err != nil {
+					t.Fatal(err)
+				}
+				break
+			}
+		}
+		if schema == nil {
+			t.Errorf("%s: object %d has unexpected type %q", name, i, objectType)
+			continue
+		}
+
+		if err := schema.Validate(object); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestStixExport(t *testing.T) {
+	samples, err := filepath.Glob("testdata/stix/sample-*.json")
+	if err != nil || len(samples) == 0 {
+		t.Fatalf("no sample documents: %v", err)
+	}
+	for _, sample := range samples {
+		data, err := os.ReadFile(sample)
+		if err != nil {
+			t.Fatal(err)
+		}
+		validateStixBundle(t, sample, data)
+	}
+
+	rtkcsm := newTestRTKCSM()
+
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	alerts := structure.Alerts{
+		structure.Alert{
+			Timestamp:     start,
+			SourceIP:      structure.ParseIPAddress("2001:db8::1"),
+			DestinationIP: structure.ParseIPAddress("172.16.42.42"),
+			Severity:      1,
+			Confidence:    1,
+			SignatureId:   2010935,
+			Cause:         `ET SCAN "Suspicious" inbound scan`,
+		},
+		structure.Alert{
+			Timestamp:     start.Add(time.Minute),
+			SourceIP:      structure.ParseIPAddress("172.16.42.42"),
+			DestinationIP: structure.ParseIPAddress("218.92.0.27"),
+			Severity:      1,
+			Confidence:    1,
+			Cause:         "beacon",
+		},
+		structure.Alert{
+			Timestamp:     start.Add(2 * time.Minute),
+			SourceIP:      structure.ParseIPAddress("94.141.120.37"),
+			DestinationIP: structure.ParseIPAddress("172.16.42.43"),
+			Severity:      0.5,
+			Confidence:    1,
+			SignatureId:   2024897,
+			Cause:         "ET USER_AGENTS Go HTTP Client User-Agent",
+		},
+	}
+
+	for _, alert := range alerts {
+		rtkcsm.AddAlert(alert)
+	}
+
+	// graph ids are numbered per process, so the expected document uses the position in the graph list
+	graphs := []ExportedGraph[structure.SimplifiedUKCStage]{}
+	for i, information := range rtkcsm.GetGraphList(-1).Graphs {
+		graphs = append(graphs, ExportedGraph[structure.SimplifiedUKCStage]{
+			ID:    structure.GraphID(i + 1),
+			Graph: rtkcsm.GetGraph(information.ID).GetPreComputed(),
+		})
+	}
+
+	output, err := json.Marshal(NewStixBundle(graphs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateStixBundle(t, "export", output)
+
+	expectedOutput, err := os.ReadFile("testdata/stix/expected-bundle.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual, expected any
+	if err := json.Unmarshal(output, &actual); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(expectedOutput, &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("STIX bundle differs from the expected bundle:\n%s", output)
+	}
+
+	buffer := bytes.Buffer{}
+	if _, err := ExportGraphs(&buffer, rtkcsm, structure.ExportFormatStix); err != nil {
+		t.Fatal(err)
+	}
+	validateStixBundle(t, "export endpoint", buffer.Bytes())
+}
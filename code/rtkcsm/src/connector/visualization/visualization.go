@@ -1,20 +1,36 @@
 package visualization
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
+	"net/url"
 	"rtkcsm/component/behaviour"
 	"rtkcsm/component/structure"
 	"strconv"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-func Start[T structure.Stage, K structure.Stage](listenAddress string, rtkcsm behaviour.RTKCSM[T, K], fileSystem fs.FS) error {
+func Start[T structure.Stage, K structure.Stage](listenAddress string, rtkcsm behaviour.RTKCSM[T, K], fileSystem fs.FS, ingestionOptions IngestionOptions) error {
+	server := NewServer(rtkcsm, fileSystem, ingestionOptions)
+
+	log.Printf("Visit the web UI at: http://%s/web/", listenAddress)
+
+	err := server.Run(listenAddress)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewServer registers the web UI and the API
+func NewServer[T structure.Stage, K structure.Stage](rtkcsm behaviour.RTKCSM[T, K], fileSystem fs.FS, ingestionOptions IngestionOptions) *gin.Engine {
 	server := gin.New()
 	server.Use(cors.Default())
 	server.Use(gin.Recovery())
@@ -35,6 +51,17 @@ func Start[T structure.Stage, K structure.Stage](listenAddress string, rtkcsm be
 		ctx.JSON(http.StatusOK, rtkcsm.GetHostRisks())
 	})
 
+	server.GET("/api/hosts/:address/activity", func(ctx *gin.Context) {
+		// graphs list addresses in the notation of IPAddress.String
+		address := structure.ParseIPAddress(ctx.Param("address"))
+		if address.IsUnspecified() {
+			ctx.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, rtkcsm.GetHostActivity(address))
+	})
+
 	server.DELETE("/api/hosts/:address", func(ctx *gin.Context) {
 		address := structure.ParseIPAddress(ctx.Param("address"))
 		rtkcsm.DeleteHostRisk(address)
@@ -58,15 +85,24 @@ func Start[T structure.Stage, K structure.Stage](listenAddress string, rtkcsm be
 		}
 	})
 
-	server.GET("/api/graphs", func(ctx *gin.Context) {
-		pageString := ctx.Request.URL.Query().Get("page")
+	server.POST("/api/alerts", newIngestion(rtkcsm, ingestionOptions).handle)
 
-		page, err := strconv.Atoi(pageString)
+	server.GET("/api/events", func(ctx *gin.Context) {
+		streamGraphEvents(ctx, rtkcsm)
+	})
+
+	server.GET("/api/metrics/reorder", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, rtkcsm.GetReorderMetrics())
+	})
+
+	server.GET("/api/graphs", func(ctx *gin.Context) {
+		query, err := structure.ParseGraphQuery(ctx.Request.URL.Query())
 		if err != nil {
-			page = 0
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 
-		ctx.JSON(http.StatusOK, rtkcsm.GetGraphList(page))
+		ctx.JSON(http.StatusOK, rtkcsm.SearchGraphs(query))
 	})
 
 	server.GET("/api/graphs/:id", func(ctx *gin.Context) {
@@ -74,6 +110,10 @@ func Start[T structure.Stage, K structure.Stage](listenAddress string, rtkcsm be
 		if err != nil {
 			return
 		}
+
+		if redirectMergedGraph(ctx, rtkcsm, structure.GraphID(id), "") {
+			return
+		}
 		graph := rtkcsm.GetGraph(structure.GraphID(id))
 
 		if graph != nil {
@@ -81,11 +121,19 @@ func Start[T structure.Stage, K structure.Stage](listenAddress string, rtkcsm be
 			format := ctx.Request.URL.Query().Get("format")
 			switch format {
 			case "ocsf":
-				finding := FromGraphToOCSFIncidentFinding(&preComputedGraph)
-				ctx.JSON(http.StatusOK, finding)
-				findingBytes, err := json.Marshal(finding)
-				if err == nil {
-					fmt.Println(string(findingBytes))
+				incident := structure.NewIncident(time.Now())
+				if graphIncident := rtkcsm.GetIncident(structure.GraphID(id)); graphIncident != nil {
+					incident = *graphIncident
+				}
+				ctx.JSON(http.StatusOK, FromGraphToOCSFIncidentFinding(structure.GraphID(id), &preComputedGraph, incident))
+			case string(structure.ExportFormatGraphML), string(structure.ExportFormatDot), string(structure.ExportFormatCytoscape), string(structure.ExportFormatStix):
+				ctx.Header("Content-Type", graphFormatContentType(structure.ExportFormat(format)))
+				_, err := WriteGraph(ctx.Writer, structure.ExportFormat(format), ExportedGraph[T]{
+					ID:    structure.GraphID(id),
+					Graph: preComputedGraph,
+				})
+				if err != nil {
+					log.Printf("error writing graph %d as %s: %s", id, format, err)
 				}
 			default:
 				ctx.JSON(http.StatusOK, preComputedGraph)
@@ -95,12 +143,138 @@ func Start[T structure.Stage, K structure.Stage](listenAddress string, rtkcsm be
 		}
 	})
 
-	log.Printf("Visit the web UI at: http://%s/web/", listenAddress)
+	server.GET("/api/graphs/:id/history", func(ctx *gin.Context) {
+		id, err := strconv.Atoi(ctx.Param("id"))
+		if err != nil {
+			ctx.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
 
-	err := server.Run(listenAddress)
-	if err != nil {
-		return err
+		if redirectMergedGraph(ctx, rtkcsm, structure.GraphID(id), "/history") {
+			return
+		}
+
+		history := rtkcsm.GetGraphHistory(structure.GraphID(id))
+		if history != nil {
+			ctx.JSON(http.StatusOK, history)
+		} else {
+			ctx.Writer.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	server.GET("/api/graphs/:id/paths", func(ctx *gin.Context) {
+		id, err := strconv.Atoi(ctx.Param("id"))
+		if err != nil {
+			ctx.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		limit := structure.DEFAULT_ATTACK_PATH_LIMIT
+		if limitString := ctx.Request.URL.Query().Get("limit"); limitString != "" {
+			limit, err = strconv.Atoi(limitString)
+			if err != nil || limit < 1 {
+				ctx.Writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		if redirectMergedGraph(ctx, rtkcsm, structure.GraphID(id), "/paths") {
+			return
+		}
+
+		paths := rtkcsm.GetAttackPaths(structure.GraphID(id), limit)
+		if paths != nil {
+			ctx.JSON(http.StatusOK, paths)
+		} else {
+			ctx.Writer.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	server.GET("/api/graphs/:id/incident", func(ctx *gin.Context) {
+		id, err := strconv.Atoi(ctx.Param("id"))
+		if err != nil {
+			ctx.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if redirectMergedGraph(ctx, rtkcsm, structure.GraphID(id), "/incident") {
+			return
+		}
+
+		incident := rtkcsm.GetIncident(structure.GraphID(id))
+		if incident != nil {
+			ctx.JSON(http.StatusOK, incident)
+		} else {
+			ctx.Writer.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	server.PATCH("/api/graphs/:id/incident", func(ctx *gin.Context) {
+		id, err := strconv.Atoi(ctx.Param("id"))
+		if err != nil {
+			ctx.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if redirectMergedGraph(ctx, rtkcsm, structure.GraphID(id), "/incident") {
+			return
+		}
+
+		var update structure.IncidentUpdate
+		if err := ctx.BindJSON(&update); err != nil {
+			return
+		}
+
+		incident, err := rtkcsm.UpdateIncident(structure.GraphID(id), update)
+		respondIncident(ctx, incident, err)
+	})
+
+	server.POST("/api/graphs/:id/incident/notes", func(ctx *gin.Context) {
+		id, err := strconv.Atoi(ctx.Param("id"))
+		if err != nil {
+			ctx.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if redirectMergedGraph(ctx, rtkcsm, structure.GraphID(id), "/incident/notes") {
+			return
+		}
+
+		var note structure.IncidentNote
+		if err := ctx.BindJSON(&note); err != nil {
+			return
+		}
+
+		incident, err := rtkcsm.AddIncidentNote(structure.GraphID(id), note)
+		respondIncident(ctx, incident, err)
+	})
+
+	return server
+}
+
+func respondIncident(ctx *gin.Context, incident *structure.Incident, err error) {
+	if errors.Is(err, behaviour.ErrGraphNotFound) {
+		ctx.Writer.WriteHeader(http.StatusNotFound)
+	} else if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	} else {
+		ctx.JSON(http.StatusOK, incident)
 	}
+}
 
-	return nil
+// redirectMergedGraph redirects requests of a graph that was merged into another graph. The redirect
+// is temporary, as the surviving graph can be merged or evicted later.
+func redirectMergedGraph[T structure.Stage, K structure.Stage](ctx *gin.Context, rtkcsm behaviour.RTKCSM[T, K], id structure.GraphID, suffix string) bool {
+	resolvedId := rtkcsm.ResolveGraphID(id)
+	if resolvedId == id {
+		return false
+	}
+
+	location := url.URL{
+		Path:     fmt.Sprintf("/api/graphs/%d%s", resolvedId, suffix),
+		RawQuery: ctx.Request.URL.RawQuery,
+	}
+	ctx.Redirect(http.StatusTemporaryRedirect, location.String())
+
+	return true
 }
@@ -0,0 +1,34 @@
+package visualization
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestMergedGraphRedirect(t *testing.T) {
+	rtkcsm := newTestRTKCSM()
+
+	seconds := time.Now().Unix()
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-3, 0), "94.141.120.36", "172.16.42.42", 1))
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-2, 0), "94.141.120.37", "172.16.42.42", 1))
+
+	graphs := rtkcsm.GetGraphList(-1).Graphs
+	survivingId := min(graphs[0].ID, graphs[1].ID)
+	mergedId := max(graphs[0].ID, graphs[1].ID)
+
+	// links both graphs
+	rtkcsm.AddAlert(newTestAlert(time.Unix(seconds-1, 0), "172.16.42.42", "172.16.42.1", 1))
+
+	// the surviving graph can be merged again, so requests of the merged graph are redirected temporarily
+	request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/graphs/%d/incident?user=analyst", mergedId), strings.NewReader("{}"))
+	recorder := httptest.NewRecorder()
+	NewServer(rtkcsm, fstest.MapFS{}, NewIngestionOptions()).ServeHTTP(recorder, request)
+	if location := fmt.Sprintf("/api/graphs/%d/incident?user=analyst", survivingId); recorder.Code != http.StatusTemporaryRedirect || recorder.Header().Get("Location") != location {
+		t.Errorf("merged graph is redirected with %d to %q instead of %q", recorder.Code, recorder.Header().Get("Location"), location)
+	}
+}
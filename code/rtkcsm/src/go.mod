@@ -8,6 +8,7 @@ require (
 	github.com/alexflint/go-arg v1.6.0
 	github.com/gin-contrib/cors v1.7.6
 	github.com/gin-gonic/gin v1.11.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 )
 
 require (
@@ -1,18 +1,23 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"log"
 	"maps"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"rtkcsm/component/behaviour"
 	"rtkcsm/component/structure"
 	"rtkcsm/connector/reader"
+	"rtkcsm/connector/sink"
 	"rtkcsm/connector/transport"
 	"rtkcsm/connector/visualization"
 	"runtime/pprof"
 	"slices"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alexflint/go-arg"
@@ -23,17 +28,43 @@ var assets embed.FS
 
 type configuration struct {
 	TransportFilePath          string             `arg:"--file" help:"filepath of logs from suricata (eve.json) or zeek (JSON format)"`
-	TransportListenAddress     string             `arg:"--listen" help:"TCP port to listen on for alerts"`
-	VisualizationListenAddress string             `arg:"--server" help:"web interface port for visualization"`
+	FollowFile                 bool               `arg:"--follow" help:"keep reading lines appended to --file and reopen it after log rotation"`
+	FollowOffsetFile           string             `arg:"--follow-offset" help:"file storing the read byte offset of --file to resume after a restart in follow mode (at-least-once: lines whose alerts were not correlated at a crash are read again)"`
+	FollowPollInterval         time.Duration      `arg:"--follow-poll-interval" help:"interval of checking --file for new lines in follow mode" default:"1s"`
+	TransportListenAddress     string             `arg:"--listen" help:"address to listen on for alerts with the 'tcp' or 'syslog' transport, e.g. :514"`
+	VisualizationListenAddress string             `arg:"--server" help:"web interface port for visualization and POST /api/alerts"`
+	IngestionMaxQueued         int                `arg:"--ingest-max-queued" help:"alerts waiting for the correlation (including the reorder window) at which POST /api/alerts stops reading the body and answers 429 with the accepted alerts" default:"65536"`
+	IngestionMaxBodySize       int64              `arg:"--ingest-max-body" help:"maximum bytes of a (decompressed) body of POST /api/alerts" default:"33554432"`
 	ImportGraphsFile           string             `arg:"--import" help:"Import existing graphs"`
-	ReaderType                 string             `arg:"--reader" help:"format for reading from transport: 'zeek', 'suricata', 'ocsf', 'suricata-tenzir'" default:"suricata"`
-	TransportType              string             `arg:"--transport" help:"'file', 'stdin', or 'tcp' for ingesting alerts" default:"file"`
+	ReaderType                 string             `arg:"--reader" help:"format for reading from transport and the default of POST /api/alerts: 'zeek', 'suricata', 'ocsf', 'suricata-tenzir', 'wazuh', 'ecs', 'cef', 'leef'" default:"suricata"`
+	ECSSeverity                string             `arg:"--ecs-severity" help:"event.severity values mapped to the lowest and highest severity by the 'ecs' reader, e.g. 4:1 for Suricata" default:"0:100"`
+	TransportType              string             `arg:"--transport" help:"'file', 'stdin', 'tcp' or 'syslog' (RFC 5424/3164 over UDP and TCP) for ingesting alerts" default:"file"`
+	SyslogNetwork              string             `arg:"--syslog-network" help:"receive syslog only over 'udp' or 'tcp' (default: both)"`
 	ExportGraphsFile           string             `arg:"--export" help:"file name of exported graphs from RT-KCSM"`
+	ExportFormat               string             `arg:"--export-format" help:"format of exported graphs: 'json' or 'binary' (imports detect the format), or 'graphml', 'dot', 'cytoscape' or 'stix' for other tools" default:"json"`
 	HostRisk                   map[string]float32 `arg:"--risk" help:"set risk score (low=0.5,default=1.0,high=1.5) of an IP address for a host/asset: --risk 10.0.0.1=1.5"`
 	ProfilerOptions            map[string]string  `arg:"--profile" help:"performance profile options: memory=/path/to/file, cpu=/path/to/file, alerts=/path/to/file, graphs=/path/to/file, graph-ranking=/path/to/file, progress=true"`
 	ProfilerGraphID            structure.GraphID  `arg:"--profile-graph-ranking-id" help:"graph id for profiling ranking"`
 	StageWeights               map[string]float32 `arg:"--stage-weight" help:"set custom stage weights (incoming, same-zone, different-zone, outgoing): --stage-weight incoming=0.1"`
 	ProfilerLogResolution      int                `arg:"--profile-log-resolution" help:"resolution of updating alert count" default:"1000"`
+	ReorderWindow              time.Duration      `arg:"--reorder-window" help:"buffer alerts for this duration of alert time and correlate them in timestamp order, e.g. 5s for multiple sensors"`
+	RelinkLateAlerts           bool               `arg:"--relink-late-alerts" help:"merge graphs that a late alert precedes, e.g. for batch-uploaded logs (uses more memory)"`
+	WorkerCount                int                `arg:"--workers" help:"number of workers decoding alerts and mapping stages, alerts are still correlated in order (default: number of CPUs)"`
+	RetentionIdleTime          time.Duration      `arg:"--retention-idle" help:"evict graphs without a new relation for this duration of alert time, e.g. 24h"`
+	RetentionMaxGraphs         int                `arg:"--retention-max-graphs" help:"maximum number of graphs kept in memory, least recently active graphs are evicted first"`
+	RetentionMaxRelations      int                `arg:"--retention-max-relations" help:"maximum number of relations kept in memory, least recently active graphs are evicted first"`
+	RetentionArchiveFile       string             `arg:"--retention-archive" help:"append evicted graphs to this file in the export format"`
+	StateFolder                string             `arg:"--state" help:"folder for a write-ahead log and snapshots of the graphs, the state is restored on startup"`
+	SnapshotInterval           time.Duration      `arg:"--snapshot-interval" help:"interval of taking snapshots of the graphs into the --state folder" default:"5m"`
+	ZonesFile                  string             `arg:"--zones" help:"JSON file with internal networks and named zones: {\"internal\": [\"10.0.0.0/8\"], \"zones\": {\"dmz\": [\"10.0.1.0/24\"]}}"`
+	InternalNetworks           []string           `arg:"--internal-network" help:"CIDR of an internal network, replaces the RFC1918 default: --internal-network 130.1.0.0/16"`
+	Zones                      map[string]string  `arg:"--zone" help:"comma-separated CIDRs of a named zone, zone networks are internal: --zone dmz=130.1.1.0/24,130.1.2.0/24"`
+	IncidentSink               string             `arg:"--incident-sink" help:"push OCSF incident findings to a JSON lines file, tcp://host:port, syslog://host:port (RFC 5424) or an http(s):// webhook"`
+	IncidentMinRelevance       float32            `arg:"--incident-min-relevance" help:"push graphs to --incident-sink once they reach this relevance, e.g. 0.5"`
+	IncidentTopGraphs          int                `arg:"--incident-top" help:"push the graphs with the highest relevance to --incident-sink, e.g. 10"`
+	IncidentRetries            int                `arg:"--incident-retries" help:"retries with exponential back-off of a failed delivery to --incident-sink" default:"5"`
+	AlertRulesFile             string             `arg:"--rules" help:"JSON file with alert rules on graphs, reloaded when it changes: {\"rules\": [{\"name\": \"exfiltration\", \"sequence\": [\"incoming\", \"outgoing\"], \"min_relevance\": 0.8}]}"`
+	AlertRulesWebhook          string             `arg:"--rules-webhook" help:"URL that notifications of matched alert rules are posted to instead of logging them"`
 }
 
 func startCPUProfile(fileName string) *os.File {
@@ -76,6 +107,12 @@ func main() {
 	var config configuration
 	arg.MustParse(&config)
 
+	switch structure.ExportFormat(config.ExportFormat) {
+	case structure.ExportFormatJson, structure.ExportFormatBinary, structure.ExportFormatGraphML, structure.ExportFormatDot, structure.ExportFormatCytoscape, structure.ExportFormatStix:
+	default:
+		log.Panic("export format is not known")
+	}
+
 	profilerOptions := structure.ParseProfilerOptions(slices.Collect(maps.Keys(config.ProfilerOptions)), config.ProfilerGraphID, config.ProfilerLogResolution)
 
 	if profilerOptions.HasAny() {
@@ -134,6 +171,33 @@ func main() {
 		}
 	}
 
+	// zones have to be known before any IP address is parsed
+	if config.ZonesFile != "" {
+		file, err := os.Open(filepath.Clean(config.ZonesFile))
+		if err != nil {
+			log.Panic(err)
+		}
+
+		err = structure.ZoneManager.Load(file)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		if err := file.Close(); err != nil {
+			log.Panic(err)
+		}
+	}
+
+	if err := structure.ZoneManager.AddInternalNetworks(config.InternalNetworks...); err != nil {
+		log.Panic(err)
+	}
+
+	for zone, networks := range config.Zones {
+		if err := structure.ZoneManager.AddZone(zone, strings.Split(networks, ",")...); err != nil {
+			log.Panic(err)
+		}
+	}
+
 	for ipAdress, risk := range config.HostRisk {
 		structure.HostManager.AddHostRiskLevel(structure.ParseIPAddress(ipAdress), structure.RiskLevel(risk))
 	}
@@ -142,7 +206,69 @@ func main() {
 		structure.SimplifiedUkcStageWeights[structure.NewSimplifiedUKCStageFromString(stage)] = weight
 	}
 
-	rtkcsm := behaviour.NewIncrementalRTKCSM(128, structure.NewSimplifiedUKCStageMapper(), structure.NewUKCStateMachine[structure.SimplifiedUKCStage](), &profilerOptions)
+	rtkcsm := behaviour.NewIncrementalRTKCSM(config.WorkerCount, structure.NewSimplifiedUKCStageMapper(), structure.NewUKCStateMachine[structure.SimplifiedUKCStage](), &profilerOptions)
+
+	retentionPolicy := structure.NewRetentionPolicy()
+	retentionPolicy.MaxIdleTime = config.RetentionIdleTime
+	retentionPolicy.MaxGraphs = config.RetentionMaxGraphs
+	retentionPolicy.MaxRelations = config.RetentionMaxRelations
+
+	if config.RetentionArchiveFile != "" {
+		file, err := os.OpenFile(filepath.Clean(config.RetentionArchiveFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			log.Panic(err)
+		}
+		defer file.Close()
+
+		retentionPolicy.Archive = file
+	}
+
+	rtkcsm.SetRetentionPolicy(retentionPolicy)
+	rtkcsm.SetReorderWindow(config.ReorderWindow)
+	rtkcsm.SetRelinkLateAlerts(config.RelinkLateAlerts)
+
+	if config.StateFolder != "" {
+		err := rtkcsm.EnablePersistence(config.StateFolder, config.SnapshotInterval)
+		if err != nil {
+			log.Panicf("could not restore state: %s", err)
+		}
+	}
+
+	if config.AlertRulesFile != "" {
+		watcher := structure.NewAlertRuleWatcher(config.AlertRulesFile)
+		rules, _, err := watcher.Load()
+		if err != nil {
+			log.Panic(err)
+		}
+
+		var notifier structure.Notifier = structure.LogNotifier{}
+		if config.AlertRulesWebhook != "" {
+			notifier = sink.NewWebhookSink(config.AlertRulesWebhook)
+		}
+
+		rtkcsm.SetNotifier(notifier)
+		rtkcsm.SetAlertRules(rules)
+		go watcher.Watch(rtkcsm.SetAlertRules, make(chan struct{}))
+	}
+
+	var incidentStream *sink.IncidentStream[structure.SimplifiedUKCStage, structure.UKCStage]
+	if config.IncidentSink != "" {
+		options := sink.NewIncidentStreamOptions()
+		options.MinRelevance = config.IncidentMinRelevance
+		options.TopGraphs = config.IncidentTopGraphs
+		options.MaxRetries = config.IncidentRetries
+
+		if !options.IsEnabled() {
+			log.Panic("--incident-sink requires --incident-min-relevance or --incident-top")
+		}
+
+		incidentSink, err := sink.New(config.IncidentSink)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		incidentStream = sink.NewIncidentStream(rtkcsm, incidentSink, options)
+	}
 
 	startTime := time.Now()
 	if config.ImportGraphsFile != "" {
@@ -164,36 +290,51 @@ func main() {
 
 	}
 
-	var alertReader reader.AlertReader[structure.SimplifiedUKCStage, structure.UKCStage]
-	switch config.ReaderType {
-	case "", "suricata":
-		alertReader = &reader.SuricataAlertReader[structure.SimplifiedUKCStage, structure.UKCStage]{}
-	case "zeek":
-		alertReader = &reader.ZeekAlertReader[structure.SimplifiedUKCStage, structure.UKCStage]{}
-	case "suricata-tenzir":
-		alertReader = &reader.SuricataTenzirAlertReader[structure.SimplifiedUKCStage, structure.UKCStage]{}
-	case "ocsf":
-		alertReader = &reader.OCSFAlertReader[structure.SimplifiedUKCStage, structure.UKCStage]{}
-	default:
-		log.Panic("reader type is not known")
+	ecsSeverity, err := reader.ParseECSSeverityNormalization(config.ECSSeverity)
+	if err != nil {
+		log.Panic(err)
+	}
+	readerOptions := reader.ReaderOptions{ECSSeverity: ecsSeverity}
+
+	alertReader, err := reader.NewAlertReader[structure.SimplifiedUKCStage, structure.UKCStage](config.ReaderType, readerOptions)
+	if err != nil {
+		log.Panic(err)
 	}
 
 	if config.VisualizationListenAddress != "" {
-		go visualization.Start(config.VisualizationListenAddress, rtkcsm, assets)
+		ingestionOptions := visualization.NewIngestionOptions()
+		ingestionOptions.DefaultReader = config.ReaderType
+		ingestionOptions.ReaderOptions = readerOptions
+		ingestionOptions.MaxQueuedAlerts = config.IngestionMaxQueued
+		ingestionOptions.MaxBodySize = config.IngestionMaxBodySize
+
+		go visualization.Start(config.VisualizationListenAddress, rtkcsm, assets, ingestionOptions)
 	}
 
+	// transports stop reading on SIGINT and SIGTERM, so that the incident stream and the state are closed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	var selectedTransport transport.Transport[structure.SimplifiedUKCStage, structure.UKCStage]
 	switch config.TransportType {
 	case "", "file":
 		if config.TransportFilePath != "" {
 			selectedTransport = &transport.FileTransport[structure.SimplifiedUKCStage, structure.UKCStage]{
-				FilePath: config.TransportFilePath,
+				FilePath:       config.TransportFilePath,
+				Follow:         config.FollowFile,
+				OffsetFilePath: config.FollowOffsetFile,
+				PollInterval:   config.FollowPollInterval,
 			}
 		}
 	case "tcp":
 		selectedTransport = &transport.TcpTransport[structure.SimplifiedUKCStage, structure.UKCStage]{
 			ListenAddress: config.TransportListenAddress,
 		}
+	case "syslog":
+		selectedTransport = &transport.SyslogTransport[structure.SimplifiedUKCStage, structure.UKCStage]{
+			ListenAddress: config.TransportListenAddress,
+			Network:       config.SyslogNetwork,
+		}
 	case "stdin":
 		selectedTransport = &transport.StdinTransport[structure.SimplifiedUKCStage, structure.UKCStage]{}
 	default:
@@ -201,15 +342,21 @@ func main() {
 	}
 
 	if selectedTransport != nil {
-		err := selectedTransport.Start(rtkcsm, alertReader)
+		err := selectedTransport.Start(ctx, rtkcsm, alertReader)
 		if err != nil {
 			log.Panicf("error channeling alerts: %s", err)
 		}
 	}
 
+	if config.ReorderWindow > 0 {
+		rtkcsm.FlushReorderBuffer()
+		metrics := rtkcsm.GetReorderMetrics()
+		log.Printf("Reordered alerts: %d, late alerts outside of the window: %d (max. %s late)", metrics.Reordered, metrics.LateAlerts, metrics.MaxLateness)
+	}
+
 	endTime := time.Now()
 	graphCount := rtkcsm.GetGraphList(-1).Count
-	err := profilerOptions.TakeMeasurement(graphCount, true)
+	err = profilerOptions.TakeMeasurement(graphCount, true)
 	if err != nil {
 		log.Panic(err)
 	}
@@ -228,7 +375,12 @@ func main() {
 			log.Panic(err)
 		}
 
-		size, err := rtkcsm.ExportGraphs(file)
+		var size int
+		if format := structure.ExportFormat(config.ExportFormat); visualization.IsGraphFormat(format) {
+			size, err = visualization.ExportGraphs(file, rtkcsm, format)
+		} else {
+			size, err = rtkcsm.ExportGraphs(file, format)
+		}
 		if err != nil {
 			log.Panic(err)
 		}
@@ -237,6 +389,23 @@ func main() {
 	}
 
 	if config.VisualizationListenAddress != "" {
-		<-make(chan struct{})
+		// serve until interrupted
+		<-ctx.Done()
+	}
+	if ctx.Err() != nil {
+		log.Println("shutting down")
+	}
+	stop()
+
+	if incidentStream != nil {
+		if err := incidentStream.Close(); err != nil {
+			log.Panic(err)
+		}
+	}
+
+	if config.StateFolder != "" {
+		if err := rtkcsm.ClosePersistence(); err != nil {
+			log.Panic(err)
+		}
 	}
 }
@@ -200,6 +200,6 @@ func TestGraphGenerationComplexAttack(t *testing.T) {
 
 	if sortedGraphList.Count != 1 {
 		t.Errorf("graph list too short or too long: %d graphs", sortedGraphList.Count)
-		visualization.Start(":8080", rtkcsm, assets)
+		visualization.Start(":8080", rtkcsm, assets, visualization.NewIngestionOptions())
 	}
 }
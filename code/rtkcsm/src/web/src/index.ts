@@ -115,6 +115,8 @@ renderer.on("leaveEdge", ({ edge }) => {
 renderer.setSetting("edgeReducer", (_, attributes) => {
     if ("highlight" in attributes) {
         return { ...attributes, color: "#ff0000", zIndex: 10000000, forceLabel: true }
+    } else if ("attackPath" in attributes) {
+        return { ...attributes, color: "#ff8c00", zIndex: 1000000, size: attributes.size + 2 }
     } else {
         return { ...attributes }
     }
@@ -235,13 +237,22 @@ function openGraph(id: number) {
 
 
     graphIdElement.textContent = String(id)
-    alertsListElement.innerHTML = ""
+    loadGraph(id, false)
+}
 
+// loadGraph keeps the positions of existing nodes when the graph is updated incrementally
+function loadGraph(id: number, incremental: boolean) {
     fetch(`/api/graphs/${encodeURIComponent(String(id))}`)
         .then(response => response.json())
         .then(data => {
-            let nodesDict: {[id: string]: any} = {}
-            graph.clear()
+            if (id != currentGraphId) {
+                return
+            }
+
+            if (!incremental) {
+                graph.clear()
+            }
+            alertsListElement.innerHTML = ""
             layoutIterations = 0
             layout.start()
 
@@ -249,7 +260,7 @@ function openGraph(id: number) {
             let alertContainer: HTMLElement | null = null
 
             data.relations.forEach((edge: { [key: string]: any }) => {
-                if (!(edge.from in nodesDict)) {
+                if (!graph.hasNode(edge.from)) {
                     graph.addNode(
                         edge.from,
                         {
@@ -261,10 +272,9 @@ function openGraph(id: number) {
                             y: Math.random(),
                         }
                     )
-                    nodesDict[edge.from] = true
                 }
 
-                if (!(edge.to in nodesDict)) {
+                if (!graph.hasNode(edge.to)) {
                     graph.addNode(
                         edge.to,
                         {
@@ -276,21 +286,24 @@ function openGraph(id: number) {
                             y: Math.random(),
                         }
                     )
-                    nodesDict[edge.to] = true
                 }
                     
-                graph.addEdgeWithKey(
-                    edge.id,
-                    edge.from,
-                    edge.to,
-                    {
-                        label: edge.confirmed_ukc_stages,
-                        message: edge.cause,
-                        size: edge.severity * 3,
-                        showLabel: false,
-                        zIndex: 1,
-                    }
-                )
+                if (!graph.hasEdge(edge.id)) {
+                    graph.addEdgeWithKey(
+                        edge.id,
+                        edge.from,
+                        edge.to,
+                        {
+                            label: edge.confirmed_ukc_stages,
+                            message: edge.cause,
+                            size: edge.severity * 3,
+                            showLabel: false,
+                            zIndex: 1,
+                        }
+                    )
+                } else {
+                    graph.setEdgeAttribute(edge.id, "label", edge.confirmed_ukc_stages)
+                }
 
                 let timestamp = new Date(edge.timestamp)
                 if (lastTimestamp == null || timestamp.toDateString() !== lastTimestamp.toDateString()) {
@@ -303,10 +316,33 @@ function openGraph(id: number) {
             })
 
             renderEdges()
+            loadAttackPath(id)
         })
         .catch(error => console.error("Error fetching graph:", error))
 }
 
+// loadAttackPath highlights the relations of the most relevant attack path
+function loadAttackPath(id: number) {
+    fetch(`/api/graphs/${encodeURIComponent(String(id))}/paths?limit=1`)
+        .then(response => response.json())
+        .then(paths => {
+            if (id != currentGraphId) {
+                return
+            }
+
+            graph.forEachEdge((edge) => graph.removeEdgeAttribute(edge, "attackPath"))
+
+            paths.forEach((path: { steps: { [key: string]: any }[] }) => {
+                path.steps.forEach((step) => {
+                    if (graph.hasEdge(step.id)) {
+                        graph.setEdgeAttribute(step.id, "attackPath", true)
+                    }
+                })
+            })
+        })
+        .catch(error => console.error("Error fetching attack paths:", error))
+}
+
 const list: HTMLDivElement | null = document.querySelector(".sidebar .list")
 
 function refreshGraphs() {
@@ -341,6 +377,10 @@ function loadGraphs(page: number) {
                 edgeCount.className = "relevance"
                 edgeCount.textContent = item.relevance
 
+                if (item.id == currentGraphId) {
+                    graph.classList.add("active")
+                }
+
                 graph.onclick = function () {
                     openGraph(item.id)
                 }
@@ -370,7 +410,7 @@ function loadGraphs(page: number) {
                 list.addEventListener("scroll", scrollListener)
             }
 
-            if ("URLSearchParams" in window && page == 0) {
+            if ("URLSearchParams" in window && page == 0 && currentGraphId == null) {
                 const url = new URL(window.location.href)
                 let graphId = url.searchParams.get("graphId")
                 if (graphId) {
@@ -458,6 +498,14 @@ function addAlertToList(relation: any, parent: HTMLElement) {
         "Relevance": relation.computed_host_relevance.toFixed(2)
     }
 
+    if (relation.from_zone) {
+        attributes["Source zone"] = relation.from_zone
+    }
+
+    if (relation.to_zone) {
+        attributes["Destination zone"] = relation.to_zone
+    }
+
     const attributeListItem = document.createElement("ul")
     attributeListItem.className = "attributes"
 
@@ -613,4 +661,63 @@ document.querySelector(".modal .create-host .button")?.addEventListener("click",
 centerViewButton.addEventListener("click", centerView)
 exportToOCSFButton.addEventListener("click", downloadOCSFExport)
 
-refreshGraphs()
\ No newline at end of file
+refreshGraphs()
+
+const graphEvents = new EventSource("/api/events")
+let graphListRefresh: number | undefined
+let currentGraphUpdate: number | undefined
+
+// events arrive in bursts, so updates are delayed and combined
+function scheduleGraphListRefresh() {
+    if (graphListRefresh === undefined) {
+        graphListRefresh = window.setTimeout(() => {
+            graphListRefresh = undefined
+            refreshGraphs()
+        }, 1000)
+    }
+}
+
+function scheduleCurrentGraphUpdate() {
+    if (currentGraphUpdate === undefined) {
+        currentGraphUpdate = window.setTimeout(() => {
+            currentGraphUpdate = undefined
+            if (currentGraphId != null) {
+                loadGraph(currentGraphId, true)
+            }
+        }, 1000)
+    }
+}
+
+function onGraphEvent(listener: (event: { [key: string]: any }) => void) {
+    return (message: MessageEvent) => listener(JSON.parse(message.data))
+}
+
+graphEvents.addEventListener("created", onGraphEvent(() => {
+    scheduleGraphListRefresh()
+}))
+
+graphEvents.addEventListener("grown", onGraphEvent((event) => {
+    if (event.id == currentGraphId) {
+        scheduleCurrentGraphUpdate()
+    }
+}))
+
+graphEvents.addEventListener("ranking", onGraphEvent(() => {
+    scheduleGraphListRefresh()
+}))
+
+graphEvents.addEventListener("merged", onGraphEvent((event) => {
+    scheduleGraphListRefresh()
+    if (event.id == currentGraphId) {
+        openGraph(event.merged_into)
+    }
+}))
+
+graphEvents.addEventListener("removed", onGraphEvent(() => {
+    scheduleGraphListRefresh()
+}))
+
+graphEvents.addEventListener("reload", onGraphEvent(() => {
+    scheduleGraphListRefresh()
+    scheduleCurrentGraphUpdate()
+}))